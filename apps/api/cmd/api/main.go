@@ -1,16 +1,23 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"skypark/internal/auth"
 	"skypark/internal/booking"
+	"skypark/internal/idempotency"
 	"skypark/internal/models"
 	"skypark/internal/park"
+	"skypark/internal/ticketsign"
+	"skypark/internal/wallet"
 	"skypark/pkg/config"
 )
 
@@ -59,8 +66,110 @@ func main() {
 	parkHandlers := park.NewParkHandlers(db)
 
 	// Initialize booking services
-	bookingService := booking.NewBookingService(db)
-	bookingHandlers := booking.NewBookingHandlers(db)
+	bookingConfig := booking.DefaultConfig()
+	if holdTTL, err := time.ParseDuration(os.Getenv("BOOKING_HOLD_TTL")); err == nil && holdTTL > 0 {
+		bookingConfig.HoldTTL = holdTTL
+	}
+	if slotLength, err := time.ParseDuration(os.Getenv("BOOKING_SLOT_LENGTH")); err == nil && slotLength > 0 {
+		bookingConfig.SlotLength = slotLength
+	}
+	if sweepInterval, err := time.ParseDuration(os.Getenv("BOOKING_SWEEP_INTERVAL")); err == nil && sweepInterval > 0 {
+		bookingConfig.SweepInterval = sweepInterval
+	}
+	if draftTTL, err := time.ParseDuration(os.Getenv("BOOKING_DRAFT_TTL")); err == nil && draftTTL > 0 {
+		bookingConfig.DraftTTL = draftTTL
+	}
+	if pendingTTL, err := time.ParseDuration(os.Getenv("BOOKING_PENDING_PAYMENT_TTL")); err == nil && pendingTTL > 0 {
+		bookingConfig.PendingPaymentTTL = pendingTTL
+	}
+
+	// Ticket QR codes are signed so gates can verify them offline
+	ticketSigner, err := ticketsign.ParseKeyring(
+		os.Getenv("TICKET_SIGNING_KEYS"),
+		os.Getenv("TICKET_SIGNING_KEY_ID"),
+		os.Getenv("TICKET_VERIFY_KEYS"),
+	)
+	if errors.Is(err, ticketsign.ErrNoSigningKey) && os.Getenv("TICKET_SIGNING_KEYS") == "" && os.Getenv("APP_ENV") != "production" {
+		ticketSigner, err = ticketsign.GenerateKeyring("dev")
+		log.Println("⚠️ Using a temporary ticket signing key. Set TICKET_SIGNING_KEYS so issued QR codes survive restarts!")
+	}
+	if err != nil {
+		log.Fatalf("Failed to load ticket signing keys: %v", err)
+	}
+
+	// Mobile wallet passes: each wallet is enabled once its credentials are set
+	walletIssuers := &booking.WalletIssuers{}
+	if passTypeID := os.Getenv("APPLE_PASS_TYPE_ID"); passTypeID != "" {
+		appleConfig := wallet.AppleConfig{
+			PassTypeID:    passTypeID,
+			TeamID:        os.Getenv("APPLE_TEAM_ID"),
+			WebServiceURL: os.Getenv("APPLE_WALLET_WEB_SERVICE_URL"),
+			APNsURL:       os.Getenv("APPLE_APNS_URL"),
+		}
+		for env, dst := range map[string]*[]byte{
+			"APPLE_PASS_CERT_FILE": &appleConfig.Certificate,
+			"APPLE_PASS_KEY_FILE":  &appleConfig.PrivateKey,
+			"APPLE_WWDR_CERT_FILE": &appleConfig.WWDRCertificate,
+		} {
+			data, err := os.ReadFile(os.Getenv(env))
+			if err != nil {
+				log.Fatalf("Failed to read %s: %v", env, err)
+			}
+			*dst = data
+		}
+		walletIssuers.Apple, err = wallet.NewAppleSigner(appleConfig)
+		if err != nil {
+			log.Fatalf("Failed to load Apple Wallet certificates: %v", err)
+		}
+		log.Println("✅ Apple Wallet passes enabled")
+	}
+	if issuerID := os.Getenv("GOOGLE_WALLET_ISSUER_ID"); issuerID != "" {
+		serviceAccountKey, err := os.ReadFile(os.Getenv("GOOGLE_WALLET_SERVICE_ACCOUNT_FILE"))
+		if err != nil {
+			log.Fatalf("Failed to read GOOGLE_WALLET_SERVICE_ACCOUNT_FILE: %v", err)
+		}
+		googleConfig := wallet.GoogleConfig{
+			IssuerID:          issuerID,
+			ServiceAccountKey: serviceAccountKey,
+			APIURL:            os.Getenv("GOOGLE_WALLET_API_URL"),
+		}
+		if origins := os.Getenv("GOOGLE_WALLET_ORIGINS"); origins != "" {
+			googleConfig.Origins = strings.Split(origins, ",")
+		}
+		walletIssuers.Google, err = wallet.NewGoogleIssuer(googleConfig)
+		if err != nil {
+			log.Fatalf("Failed to load Google Wallet service account: %v", err)
+		}
+		log.Println("✅ Google Wallet passes enabled")
+	}
+	walletIssuers.AuthSecret = []byte(os.Getenv("WALLET_AUTH_SECRET"))
+	if walletIssuers.Apple != nil && len(walletIssuers.AuthSecret) == 0 {
+		if os.Getenv("APP_ENV") == "production" {
+			log.Fatal("WALLET_AUTH_SECRET is required for Apple Wallet passes")
+		}
+		walletIssuers.AuthSecret = make([]byte, 32)
+		if _, err := rand.Read(walletIssuers.AuthSecret); err != nil {
+			log.Fatalf("Failed to generate wallet auth secret: %v", err)
+		}
+		log.Println("⚠️ Using a temporary wallet auth secret. Set WALLET_AUTH_SECRET so issued passes keep updating after restarts!")
+	}
+	if pushInterval, err := time.ParseDuration(os.Getenv("WALLET_PUSH_INTERVAL")); err == nil && pushInterval > 0 {
+		bookingConfig.WalletPushInterval = pushInterval
+	}
+
+	bookingService := booking.NewBookingService(db, bookingConfig, smsService, ticketSigner, walletIssuers)
+	bookingHandlers := booking.NewBookingHandlers(db, bookingService)
+
+	// Idempotency keys for retried booking, payment and refund requests
+	idempotencyConfig := idempotency.DefaultConfig()
+	if keyTTL, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_KEY_TTL")); err == nil && keyTTL > 0 {
+		idempotencyConfig.TTL = keyTTL
+	}
+	idempotencyMiddleware := idempotency.NewMiddleware(idempotency.NewPostgresStore(db), idempotencyConfig)
+	idempotent := idempotencyMiddleware.Idempotent()
+
+	// Offer freed spots to the waitlist when an admin raises park capacity
+	parkHandlers.OnCapacityChange(bookingService.CapacityReleased)
 
 	// Seed initial park data
 	if os.Getenv("APP_ENV") == "development" {
@@ -77,6 +186,22 @@ func main() {
 		}
 	}
 
+	// Assign numbers to bookings and tickets created before short numbers existed
+	if assigned, err := bookingService.AssignMissingNumbers(); err != nil {
+		log.Printf("⚠️ Failed to assign booking numbers: %v", err)
+	} else if assigned > 0 {
+		log.Printf("✅ Assigned %d booking and ticket numbers", assigned)
+	}
+
+	// Background jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	go bookingService.StartHoldReleaser(jobsCtx)
+	go bookingService.StartSweeper(jobsCtx)
+	go idempotencyMiddleware.StartCleanup(jobsCtx)
+	go bookingService.StartWalletPusher(jobsCtx)
+
 	// Set up Gin router
 	if os.Getenv("APP_ENV") == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -88,7 +213,8 @@ func main() {
 	router.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key, If-Match")
+		c.Header("Access-Control-Expose-Headers", "ETag, Idempotent-Replayed")
 		
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -117,6 +243,7 @@ func main() {
 		{
 			auth.POST("/send-sms", authHandlers.SendSMSCode)
 			auth.POST("/verify-login", authHandlers.VerifyAndLogin)
+			auth.POST("/guest", authHandlers.GuestCheckout)
 			auth.POST("/refresh", authHandlers.RefreshToken)
 		}
 
@@ -142,33 +269,59 @@ func main() {
 		{
 			// 🔓 Public route for checking time slots
 			bookings.GET("/parks/:parkId/time-slots", bookingHandlers.GetAvailableTimeSlots)
+			bookings.GET("/parks/:parkId/passes", bookingHandlers.GetPassCatalog)
+			bookings.GET("/parks/:parkId/party-packages", bookingHandlers.GetPartyPackages)
+			bookings.GET("/parks/:parkId/party-rooms", bookingHandlers.GetPartyRoomCalendar)
+			bookings.POST("/lookup", bookingHandlers.LookupGuestBooking)
 
 			// 🔒 Protected booking routes
 			bookings.Use(authMiddleware.AuthRequired())
 			{
-				bookings.POST("", bookingHandlers.CreateBooking)
+				bookings.POST("", idempotent, bookingHandlers.CreateBooking)
+				bookings.POST("/group", idempotent, bookingHandlers.CreateGroupBooking)
+				bookings.POST("/party", idempotent, bookingHandlers.CreatePartyBooking)
 				bookings.GET("", bookingHandlers.GetUserBookings)
+				bookings.GET("/number/:number", bookingHandlers.GetBookingByNumber)
+				bookings.POST("/passes", idempotent, bookingHandlers.PurchasePass)
+				bookings.GET("/passes", bookingHandlers.GetUserPasses)
+				bookings.POST("/waitlist", bookingHandlers.JoinWaitlist)
+				bookings.GET("/waitlist", bookingHandlers.GetUserWaitlist)
+				bookings.DELETE("/waitlist/:id", bookingHandlers.LeaveWaitlist)
+				bookings.POST("/waitlist/:id/claim", idempotent, bookingHandlers.ClaimWaitlistOffer)
 				bookings.GET("/:id", bookingHandlers.GetBookingByID)
-				bookings.PUT("/:id", bookingHandlers.UpdateBooking)
-				bookings.DELETE("/:id", bookingHandlers.CancelBooking)
+				bookings.PUT("/:id", idempotent, bookingHandlers.UpdateBooking)
+				bookings.DELETE("/:id", idempotent, bookingHandlers.CancelBooking)
+				bookings.GET("/:id/invoice", bookingHandlers.GetBookingInvoice)
+				bookings.GET("/:id/pdf", bookingHandlers.GetBookingPDF)
 			}
 		}
 
+		// 🔓 Public keys for offline ticket QR verification
+		v1.GET("/tickets/signing-keys", bookingHandlers.GetTicketSigningKeys)
+
+		// 🔓 Apple Wallet web service: the Wallet app authenticates with the pass's own token
+		appleWallet := v1.Group("/wallet/apple/v1")
+		{
+			appleWallet.POST("/devices/:device/registrations/:passType/:serial", bookingHandlers.RegisterWalletDevice)
+			appleWallet.DELETE("/devices/:device/registrations/:passType/:serial", bookingHandlers.UnregisterWalletDevice)
+			appleWallet.GET("/devices/:device/registrations/:passType", bookingHandlers.GetWalletSerials)
+			appleWallet.GET("/passes/:passType/:serial", bookingHandlers.GetWalletPass)
+			appleWallet.POST("/log", bookingHandlers.LogWalletMessages)
+		}
+
 		// 🔒 User dashboard routes
 		protected := v1.Group("")
 		protected.Use(authMiddleware.AuthRequired())
 		{
-			protected.GET("/tickets", func(c *gin.Context) {
-				userID := c.GetString("user_id")
-				var tickets []models.Ticket
-				db.Where("user_id = ? AND deleted_at IS NULL", userID).Find(&tickets)
-				
-				c.JSON(http.StatusOK, gin.H{
-					"success": true,
-					"data":    tickets,
-					"total":   len(tickets),
-				})
-			})
+			protected.GET("/tickets", bookingHandlers.GetUserTickets)
+			protected.GET("/tickets/number/:number", bookingHandlers.GetTicketByNumber)
+			protected.GET("/tickets/:id/qr", bookingHandlers.GetTicketQR)
+			protected.GET("/tickets/:id/wallet/apple", bookingHandlers.GetAppleWalletPass)
+			protected.GET("/tickets/:id/wallet/google", bookingHandlers.GetGoogleWalletLink)
+			protected.POST("/tickets/:id/transfer", idempotent, bookingHandlers.StartTicketTransfer)
+			protected.DELETE("/tickets/:id/transfer", bookingHandlers.CancelTicketTransfer)
+			protected.GET("/tickets/transfers/incoming", bookingHandlers.GetIncomingTransfers)
+			protected.POST("/tickets/transfers/:id/accept", idempotent, bookingHandlers.AcceptTicketTransfer)
 
 			protected.GET("/payments", func(c *gin.Context) {
 				userID := c.GetString("user_id")
@@ -183,6 +336,21 @@ func main() {
 			})
 		}
 
+		// 🔒 Park staff routes
+		staff := v1.Group("/staff")
+		staff.Use(authMiddleware.AuthRequired(), authMiddleware.RequireRole("staff", "manager", "admin", "super_admin"))
+		{
+			staff.GET("/bookings/number/:number", bookingHandlers.LookupBookingByNumber)
+			staff.GET("/tickets/number/:number", bookingHandlers.LookupTicketByNumber)
+			staff.POST("/tickets/validate", idempotent, bookingHandlers.ValidateTicket)
+
+			// Offline gate scanners
+			staff.GET("/scanner/manifest", bookingHandlers.GetScannerManifest)
+			staff.POST("/scanner/scans", bookingHandlers.UploadScans)
+			staff.GET("/scanner/conflicts", bookingHandlers.GetScanConflicts)
+			staff.PUT("/scanner/conflicts/:id/resolve", bookingHandlers.ResolveScanConflict)
+		}
+
 		// 🔒 Admin-only routes
 		admin := v1.Group("/admin")
 		admin.Use(authMiddleware.AuthRequired(), authMiddleware.AdminOnly())
@@ -212,80 +380,21 @@ func main() {
 					})
 				})
 
-				adminBookings.GET("/stats", func(c *gin.Context) {
-					stats, err := bookingService.GetBookingStats()
-					if err != nil {
-						c.JSON(http.StatusInternalServerError, gin.H{
-							"success": false,
-							"error":   err.Error(),
-						})
-						return
-					}
-					
-					c.JSON(http.StatusOK, gin.H{
-						"success": true,
-						"data":    stats,
-					})
-				})
-
-				adminBookings.PUT("/:id/confirm", func(c *gin.Context) {
-					bookingID := c.Param("id")
-					if err := bookingService.ConfirmBooking(bookingID); err != nil {
-						c.JSON(http.StatusInternalServerError, gin.H{
-							"success": false,
-							"error":   err.Error(),
-						})
-						return
-					}
-					
-					c.JSON(http.StatusOK, gin.H{
-						"success": true,
-						"message": "Booking confirmed successfully",
-					})
-				})
-
-				adminBookings.PUT("/:id/complete", func(c *gin.Context) {
-					bookingID := c.Param("id")
-					if err := bookingService.CompleteBooking(bookingID); err != nil {
-						c.JSON(http.StatusInternalServerError, gin.H{
-							"success": false,
-							"error":   err.Error(),
-						})
-						return
-					}
-					
-					c.JSON(http.StatusOK, gin.H{
-						"success": true,
-						"message": "Booking completed successfully",
-					})
-				})
-
-				adminBookings.PUT("/:id/reject", func(c *gin.Context) {
-					bookingID := c.Param("id")
-					var req struct {
-						Reason string `json:"reason" binding:"required"`
-					}
-					if err := c.ShouldBindJSON(&req); err != nil {
-						c.JSON(http.StatusBadRequest, gin.H{
-							"success": false,
-							"error":   "Invalid request",
-						})
-						return
-					}
-
-					if err := bookingService.RejectBooking(bookingID, req.Reason); err != nil {
-						c.JSON(http.StatusInternalServerError, gin.H{
-							"success": false,
-							"error":   err.Error(),
-						})
-						return
-					}
-					
-					c.JSON(http.StatusOK, gin.H{
-						"success": true,
-						"message": "Booking rejected successfully",
-					})
-				})
+				adminBookings.GET("/stats", bookingHandlers.GetBookingStats)
+
+				adminBookings.GET("/sweeper/runs", bookingHandlers.GetSweepRuns)
+				adminBookings.POST("/sweeper/run", bookingHandlers.RunSweep)
+
+				adminBookings.PUT("/:id/confirm", bookingHandlers.ConfirmBooking)
+				adminBookings.PUT("/:id/check-in", bookingHandlers.CheckInBooking)
+				adminBookings.PUT("/:id/complete", bookingHandlers.CompleteBooking)
+				adminBookings.PUT("/:id/no-show", bookingHandlers.MarkNoShow)
+				adminBookings.PUT("/:id/reject", bookingHandlers.RejectBooking)
+				adminBookings.PUT("/:id/approve", bookingHandlers.ApproveGroupBooking)
+				adminBookings.PUT("/:id/invoice/paid", idempotent, bookingHandlers.RecordInvoicePayment)
+				adminBookings.PUT("/:id/surcharge/paid", idempotent, bookingHandlers.RecordSurchargePayment)
+				adminBookings.POST("/:id/party/payments", idempotent, bookingHandlers.RecordPartyPayment)
+				adminBookings.PUT("/:id/cancel", idempotent, bookingHandlers.AdminCancelBooking)
 			}
 
 			// Admin park management
@@ -293,6 +402,15 @@ func main() {
 			{
 				adminParks.POST("", parkHandlers.CreatePark)
 				adminParks.PUT("/:id", parkHandlers.UpdatePark)
+				adminParks.PUT("/:id/capacity", parkHandlers.UpdateCapacity)
+				adminParks.POST("/:id/passes", bookingHandlers.CreatePassProduct)
+				adminParks.PUT("/:id/passes/:passId", bookingHandlers.UpdatePassProduct)
+				adminParks.DELETE("/:id/passes/:passId", bookingHandlers.DeactivatePassProduct)
+				adminParks.POST("/:id/party-rooms", bookingHandlers.CreatePartyRoom)
+				adminParks.PUT("/:id/party-rooms/:roomId", bookingHandlers.UpdatePartyRoom)
+				adminParks.POST("/:id/party-packages", bookingHandlers.CreatePartyPackage)
+				adminParks.PUT("/:id/party-packages/:packageId", bookingHandlers.UpdatePartyPackage)
+				adminParks.DELETE("/:id/party-packages/:packageId", bookingHandlers.DeactivatePartyPackage)
 				adminParks.DELETE("/:id", parkHandlers.DeletePark)
 			}
 		}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"skypark/internal/models"
+)
+
+// Гостевой пользователь — учётная запись в статусе pending, у которой есть
+// только подтверждённый телефон и, возможно, имя из контактов бронирования.
+// Он может бронировать и оплачивать как обычный клиент. Телефон уникален,
+// поэтому при завершении регистрации с тем же номером гостевая запись
+// становится полной и все её бронирования, билеты и платежи сохраняются.
+
+// GuestCheckout проверяет SMS код и открывает гостевую сессию для оформления
+// бронирования без регистрации. Если номер уже принадлежит клиенту, сессия
+// открывается для его учётной записи.
+func (h *AuthHandlers) GuestCheckout(c *gin.Context) {
+	var req struct {
+		Phone     string `json:"phone" binding:"required"`
+		Code      string `json:"code" binding:"required"`
+		FirstName string `json:"first_name,omitempty"`
+		LastName  string `json:"last_name,omitempty"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	valid, err := h.smsService.VerifyCode(req.Phone, req.Code)
+	if err != nil || !valid {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":               "INVALID_SMS_CODE",
+				"message":            "Invalid or expired SMS code",
+				"remaining_attempts": h.smsService.GetRemainingAttempts(req.Phone),
+			},
+		})
+		return
+	}
+
+	var user models.User
+	err = h.db.Where("phone_number = ? AND deleted_at IS NULL", req.Phone).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		user = newGuestUser(req.Phone, req.FirstName, req.LastName)
+		err = h.db.Create(&user).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error": map[string]interface{}{
+					"code":    "USER_CREATION_FAILED",
+					"message": "Failed to create guest account",
+				},
+			})
+			return
+		}
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "DATABASE_ERROR",
+				"message": "Database error occurred",
+			},
+		})
+		return
+	}
+
+	if !canSignIn(&user) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "ACCOUNT_SUSPENDED",
+				"message": "Your account has been suspended",
+			},
+		})
+		return
+	}
+
+	accessToken, err := h.tokenManager.GenerateAccessToken(user.ID, user.PhoneNumber, string(user.Role))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "TOKEN_GENERATION_FAILED",
+				"message": "Failed to generate access token",
+			},
+		})
+		return
+	}
+
+	refreshToken, err := h.tokenManager.GenerateRefreshToken(user.ID, user.PhoneNumber, string(user.Role))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "TOKEN_GENERATION_FAILED",
+				"message": "Failed to generate refresh token",
+			},
+		})
+		return
+	}
+
+	h.db.Model(&user).Update("last_login_at", time.Now())
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": map[string]interface{}{
+			"user": map[string]interface{}{
+				"id":          user.ID,
+				"phone":       user.PhoneNumber,
+				"first_name":  user.FirstName,
+				"last_name":   user.LastName,
+				"role":        user.Role,
+				"is_verified": user.IsPhoneVerified,
+				"is_guest":    isGuest(&user),
+			},
+			"tokens": map[string]interface{}{
+				"access_token":  accessToken,
+				"refresh_token": refreshToken,
+				"token_type":    "Bearer",
+			},
+		},
+		"message": "Guest checkout started",
+	})
+}
+
+// newGuestUser создаёт гостевого пользователя с подтверждённым телефоном
+func newGuestUser(phone, firstName, lastName string) models.User {
+	return models.User{
+		PhoneNumber:     phone,
+		FirstName:       strings.TrimSpace(firstName),
+		LastName:        strings.TrimSpace(lastName),
+		Role:            models.UserRoleCustomer,
+		Status:          models.UserStatusPending,
+		IsPhoneVerified: true,
+		LoyaltyTier:     models.LoyaltyTierBeginner,
+	}
+}
+
+// isGuest сообщает, что пользователь ещё не завершил регистрацию
+func isGuest(user *models.User) bool {
+	return user.Status == models.UserStatusPending
+}
+
+// canSignIn сообщает, может ли пользователь войти: гости входят наравне
+// с активными клиентами
+func canSignIn(user *models.User) bool {
+	return user.Status == models.UserStatusActive || isGuest(user)
+}
+
+// hasFullProfile сообщает, достаточно ли данных для полной учётной записи
+func hasFullProfile(firstName, lastName string) bool {
+	return strings.TrimSpace(firstName) != "" && strings.TrimSpace(lastName) != ""
+}
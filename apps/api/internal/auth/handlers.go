@@ -107,7 +107,7 @@ func (h *AuthHandlers) VerifyAndLogin(c *gin.Context) {
 	result := h.db.Where("phone_number = ? AND deleted_at IS NULL", req.Phone).First(&user)
 	
 	if result.Error == gorm.ErrRecordNotFound {
-		// Регистрируем нового пользователя
+		// Регистрируем нового пользователя; без имени он остаётся гостем
 		var email *string
 		if req.Email != "" {
 			email = &req.Email
@@ -124,6 +124,10 @@ func (h *AuthHandlers) VerifyAndLogin(c *gin.Context) {
 			LoyaltyTier:      models.LoyaltyTierBeginner,
 		}
 
+		if !hasFullProfile(req.FirstName, req.LastName) {
+			user.Status = models.UserStatusPending
+		}
+
 		// Парсим дату рождения если указана
 		if req.DateOfBirth != "" {
 			if dob, err := time.Parse("2006-01-02", req.DateOfBirth); err == nil {
@@ -150,10 +154,38 @@ func (h *AuthHandlers) VerifyAndLogin(c *gin.Context) {
 			},
 		})
 		return
+	} else if isGuest(&user) && hasFullProfile(req.FirstName, req.LastName) {
+		// Гость завершает регистрацию: запись становится полной вместе с
+		// уже оформленными бронированиями
+		updates := map[string]interface{}{
+			"first_name": req.FirstName,
+			"last_name":  req.LastName,
+			"status":     models.UserStatusActive,
+		}
+		if req.Email != "" {
+			updates["email"] = req.Email
+		}
+		if req.DateOfBirth != "" {
+			if dob, err := time.Parse("2006-01-02", req.DateOfBirth); err == nil {
+				updates["date_of_birth"] = dob
+			}
+		}
+
+		if err := h.db.Model(&user).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error": map[string]interface{}{
+					"code":    "UPDATE_FAILED",
+					"message": "Failed to complete registration",
+				},
+			})
+			return
+		}
+		h.db.First(&user, "id = ?", user.ID)
 	}
 
 	// Проверяем статус пользователя
-	if user.Status != models.UserStatusActive {
+	if !canSignIn(&user) {
 		c.JSON(http.StatusForbidden, gin.H{
 			"success": false,
 			"error": map[string]interface{}{
@@ -204,6 +236,7 @@ func (h *AuthHandlers) VerifyAndLogin(c *gin.Context) {
 				"role":           user.Role,
 				"loyalty_tier":   user.LoyaltyTier,
 				"is_verified":    user.IsPhoneVerified,
+				"is_guest":       isGuest(&user),
 				"created_at":     user.CreatedAt,
 			},
 			"tokens": map[string]interface{}{
@@ -295,6 +328,7 @@ func (h *AuthHandlers) GetProfile(c *gin.Context) {
 			"loyalty_tier":  user.LoyaltyTier,
 			"loyalty_points": user.LoyaltyPoints,
 			"is_verified":   user.IsPhoneVerified,
+			"is_guest":      isGuest(&user),
 			"created_at":    user.CreatedAt,
 			"last_login_at": user.LastLoginAt,
 		},
@@ -365,6 +399,18 @@ func (h *AuthHandlers) UpdateProfile(c *gin.Context) {
 		}
 	}
 
+	// Гость, заполнивший имя и фамилию, становится полноценным клиентом
+	firstName, lastName := user.FirstName, user.LastName
+	if req.FirstName != "" {
+		firstName = req.FirstName
+	}
+	if req.LastName != "" {
+		lastName = req.LastName
+	}
+	if isGuest(&user) && hasFullProfile(firstName, lastName) {
+		updates["status"] = models.UserStatusActive
+	}
+
 	if err := h.db.Model(&user).Updates(updates).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -391,6 +437,7 @@ func (h *AuthHandlers) UpdateProfile(c *gin.Context) {
 			"role":          user.Role,
 			"loyalty_tier":  user.LoyaltyTier,
 			"loyalty_points": user.LoyaltyPoints,
+			"is_guest":      isGuest(&user),
 			"updated_at":    user.UpdatedAt,
 		},
 		"message": "Profile updated successfully",
@@ -99,6 +99,18 @@ func (s *SMSService) SendVerificationCode(phone string) (*SMSCode, error) {
 	return smsCode, nil
 }
 
+// SendMessage отправляет произвольное SMS-уведомление
+func (s *SMSService) SendMessage(phone, message string) error {
+	if !s.ValidateKyrgyzstanPhone(phone) {
+		return fmt.Errorf("invalid phone number format for Kyrgyzstan")
+	}
+
+	// В продакшене здесь будет интеграция с SMS-провайдером Кыргызстана
+	fmt.Printf("📱 SMS to %s: %s\n", phone, message)
+
+	return nil
+}
+
 // VerifyCode проверяет введенный код
 func (s *SMSService) VerifyCode(phone, inputCode string) (bool, error) {
 	smsCode, exists := s.codes[phone]
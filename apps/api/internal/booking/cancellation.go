@@ -0,0 +1,282 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"skypark/internal/models"
+)
+
+// CancelRequest описывает отмену бронирования
+type CancelRequest struct {
+	Reason string `json:"reason"`
+}
+
+// AdminCancelRequest описывает отмену администратором в обход политики парка.
+// Если RefundAmount не указан, возвращается вся оплаченная сумма.
+type AdminCancelRequest struct {
+	Reason       string   `json:"reason" binding:"required"`
+	RefundAmount *float64 `json:"refundAmount,omitempty"`
+}
+
+// CancellationResult описывает итог отмены бронирования
+type CancellationResult struct {
+	Booking       *models.Booking        `json:"booking"`
+	RefundPercent float64                `json:"refundPercent"`
+	RefundAmount  float64                `json:"refundAmount"`
+	Refund        *models.RefundDetails  `json:"refund,omitempty"`
+	Refunds       []models.RefundDetails `json:"refunds,omitempty"` // если возврат разбит по нескольким платежам
+}
+
+// CancelBooking отменяет бронирование пользователя userID и рассчитывает
+// возврат по политике отмены парка
+func (s *BookingService) CancelBooking(bookingID, userID uuid.UUID, req CancelRequest) (*CancellationResult, error) {
+	var result *CancellationResult
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		booking, err := loadBookingForUpdate(tx, bookingID)
+		if err != nil {
+			return err
+		}
+		if booking.UserID != userID {
+			return ErrBookingNotFound
+		}
+
+		var park models.Park
+		if err := tx.Where("id = ?", booking.ParkID).First(&park).Error; err != nil {
+			return err
+		}
+
+		percent := 0.0
+		if booking.Status == models.BookingStatusConfirmed {
+			percent = EvaluateRefundPercent(cancellationPolicyFor(&park), visitStart(booking), time.Now())
+		}
+
+		result, err = s.cancel(tx, booking, userID, req.Reason, percent, nil, models.RefundReasonUserRequest)
+		if err != nil {
+			return err
+		}
+		return reloadVersion(tx, booking)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.afterCancellation(result.Booking)
+	return result, nil
+}
+
+// AdminCancelBooking отменяет бронирование от имени администратора.
+// Политика парка не применяется, возврат помечается как admin_action.
+func (s *BookingService) AdminCancelBooking(bookingID, adminID uuid.UUID, req AdminCancelRequest, version *int64) (*CancellationResult, error) {
+	if req.RefundAmount != nil && *req.RefundAmount < 0 {
+		return nil, ErrInvalidRefundAmount
+	}
+
+	var result *CancellationResult
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		booking, err := loadBookingForUpdate(tx, bookingID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(booking, version); err != nil {
+			return err
+		}
+
+		result, err = s.cancel(tx, booking, adminID, req.Reason, 100, req.RefundAmount, models.RefundReasonAdminAction)
+		if err != nil {
+			return err
+		}
+		return reloadVersion(tx, booking)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.afterCancellation(result.Booking)
+	return result, nil
+}
+
+// cancel переводит бронирование в cancelled и оформляет возврат на платеже.
+// Сумма возврата — percent от оплаченного либо явно заданная fixedAmount.
+func (s *BookingService) cancel(tx *gorm.DB, booking *models.Booking, actorID uuid.UUID, reason string, percent float64, fixedAmount *float64, refundReason models.RefundReason) (*CancellationResult, error) {
+	if booking.Status == models.BookingStatusCancelled {
+		// Повторная отмена ничего не меняет
+		result := &CancellationResult{Booking: booking}
+		if booking.RefundAmount != nil {
+			result.RefundAmount = *booking.RefundAmount
+		}
+		return result, nil
+	}
+
+	if _, err := s.transition(tx, booking, models.BookingStatusCancelled, actorID, reason); err != nil {
+		return nil, err
+	}
+	if err := cancelOpenInvoices(tx, booking.ID); err != nil {
+		return nil, err
+	}
+	if err := cancelOpenSurcharges(tx, booking); err != nil {
+		return nil, err
+	}
+
+	// Бронирование могло оплачиваться несколькими платежами (депозит и
+	// остаток, доплата при переносе) — возврат считается от всех
+	payments, err := refundablePayments(tx, booking.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CancellationResult{Booking: booking, RefundPercent: percent}
+	refundable := refundableTotal(payments)
+	if len(payments) == 0 {
+		return result, nil
+	}
+
+	amount := math.Min(roundMoney(refundable*percent/100), refundable)
+	if fixedAmount != nil {
+		amount = roundMoney(*fixedAmount)
+		if amount > refundable {
+			return nil, ErrInvalidRefundAmount
+		}
+		if refundable > 0 {
+			result.RefundPercent = roundMoney(amount / refundable * 100)
+		}
+	}
+	if amount <= 0 {
+		return result, nil
+	}
+
+	result.Refunds, err = spreadRefund(tx, payments, amount, func(part float64) models.RefundDetails {
+		refund := models.RefundDetails{
+			ID:          models.GenerateUUID(),
+			Amount:      part,
+			Reason:      refundReason,
+			RequestedBy: actorID,
+			RequestedAt: time.Now(),
+			Status:      "pending",
+			Metadata: models.JSONB{
+				"refundPercent":  result.RefundPercent,
+				"policyBypassed": refundReason == models.RefundReasonAdminAction,
+			},
+		}
+		if reason != "" {
+			description := reason
+			refund.Description = &description
+		}
+		return refund
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refundReasonText := string(refundReason)
+	booking.RefundAmount = &amount
+	booking.RefundReason = &refundReasonText
+	booking.PaymentStatus = models.PaymentStatusPartiallyRefunded
+	if amount >= refundable {
+		booking.PaymentStatus = models.PaymentStatusRefunded
+	}
+	if err := tx.Model(booking).
+		Select("refund_amount", "refund_reason", "payment_status").
+		Updates(booking).Error; err != nil {
+		return nil, fmt.Errorf("failed to update booking refund: %w", err)
+	}
+
+	result.RefundAmount = amount
+	result.Refund = &result.Refunds[0]
+	return result, nil
+}
+
+// refundablePayments возвращает проведённые платежи бронирования, начиная с последнего
+func refundablePayments(tx *gorm.DB, bookingID uuid.UUID) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("booking_id = ? AND status IN ?", bookingID,
+			[]models.PaymentStatus{models.PaymentStatusCompleted, models.PaymentStatusPartiallyRefunded}).
+		Order("created_at DESC").
+		Find(&payments).Error
+	if err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// refundableTotal возвращает сумму, которую ещё можно вернуть по платежам
+func refundableTotal(payments []models.Payment) float64 {
+	var refundable float64
+	for _, payment := range payments {
+		refundable += payment.Amount - payment.TotalRefunded
+	}
+	return roundMoney(refundable)
+}
+
+// spreadRefund распределяет возврат amount по платежам, начиная с последнего.
+// newRefund описывает возврат очередной части суммы.
+func spreadRefund(tx *gorm.DB, payments []models.Payment, amount float64, newRefund func(part float64) models.RefundDetails) ([]models.RefundDetails, error) {
+	var refunds []models.RefundDetails
+	remaining := amount
+	for i := range payments {
+		if remaining <= 0 {
+			break
+		}
+		payment := &payments[i]
+		part := roundMoney(math.Min(remaining, payment.Amount-payment.TotalRefunded))
+		if part <= 0 {
+			continue
+		}
+
+		refund := newRefund(part)
+		if err := addRefund(tx, payment, refund); err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, refund)
+		remaining = roundMoney(remaining - part)
+	}
+	return refunds, nil
+}
+
+// refundablePayment возвращает последний проведённый платёж бронирования
+func refundablePayment(tx *gorm.DB, bookingID uuid.UUID) (*models.Payment, error) {
+	var payment models.Payment
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("booking_id = ? AND status IN ?", bookingID,
+			[]models.PaymentStatus{models.PaymentStatusCompleted, models.PaymentStatusPartiallyRefunded}).
+		Order("created_at DESC").
+		First(&payment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// addRefund добавляет возврат к платежу и обновляет его статус
+func addRefund(tx *gorm.DB, payment *models.Payment, refund models.RefundDetails) error {
+	payment.Refunds = append(payment.Refunds, refund)
+	payment.TotalRefunded = roundMoney(payment.TotalRefunded + refund.Amount)
+	if payment.TotalRefunded >= payment.Amount {
+		payment.Status = models.PaymentStatusRefunded
+	} else {
+		payment.Status = models.PaymentStatusPartiallyRefunded
+	}
+
+	if err := tx.Model(payment).
+		Select("refunds", "total_refunded", "status").
+		Updates(payment).Error; err != nil {
+		return fmt.Errorf("failed to record refund: %w", err)
+	}
+	return nil
+}
+
+// afterCancellation обновляет снимок вместимости после освобождения мест
+// и предлагает их листу ожидания
+func (s *BookingService) afterCancellation(booking *models.Booking) {
+	s.CapacityReleased(booking.ParkID)
+}
@@ -0,0 +1,176 @@
+package booking
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"skypark/internal/models"
+)
+
+// occupyingStatuses — статусы, которые всегда занимают места в парке.
+// Бронирования в pending_payment занимают места только пока действует удержание.
+var occupyingStatuses = []models.BookingStatus{
+	models.BookingStatusConfirmed,
+	models.BookingStatusCheckedIn,
+}
+
+// lockSlot берёт транзакционную advisory-блокировку на парк/дату/слот,
+// чтобы параллельные проверки вместимости выполнялись последовательно.
+// Блокировка снимается автоматически при завершении транзакции.
+func lockSlot(tx *gorm.DB, parkID uuid.UUID, visitDate time.Time, timeSlot string) error {
+	key := fmt.Sprintf("booking:%s:%s:%s", parkID, visitDate.Format("2006-01-02"), timeSlot)
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", key).Error
+}
+
+// occupying оставляет бронирования, занимающие места: подтверждённые,
+// прошедшие в парк и ожидающие оплаты с действующим удержанием
+func occupying(db *gorm.DB) *gorm.DB {
+	return db.Where("(status IN ? OR (status = ? AND hold_expires_at > ?))",
+		occupyingStatuses, models.BookingStatusPendingPayment, time.Now())
+}
+
+// occupiedSpots считает места, занятые в слоте подтверждёнными бронированиями,
+// действующими удержаниями и предложениями из листа ожидания.
+// Бронирование excludeID не учитывается.
+func occupiedSpots(tx *gorm.DB, parkID uuid.UUID, visitDate time.Time, timeSlot string, excludeID uuid.UUID) (int, error) {
+	var occupied int64
+	err := tx.Model(&models.Booking{}).
+		Select("COALESCE(SUM(total_guests), 0)").
+		Where("park_id = ? AND visit_date = ? AND time_slot = ? AND deleted_at IS NULL", parkID, visitDate.Format("2006-01-02"), timeSlot).
+		Where("id <> ?", excludeID).
+		Scopes(occupying).
+		Scan(&occupied).Error
+	if err != nil {
+		return 0, err
+	}
+
+	offered, err := offeredSpots(tx, parkID, visitDate, timeSlot)
+	if err != nil {
+		return 0, err
+	}
+	return int(occupied) + offered, nil
+}
+
+// reserveCapacity блокирует слот и проверяет, что в нём есть guests свободных мест.
+// Должна вызываться внутри транзакции, в которой затем сохраняется бронирование.
+func reserveCapacity(tx *gorm.DB, park *models.Park, visitDate time.Time, timeSlot string, guests int, excludeID uuid.UUID) error {
+	if err := lockSlot(tx, park.ID, visitDate, timeSlot); err != nil {
+		return fmt.Errorf("failed to lock time slot: %w", err)
+	}
+
+	occupied, err := occupiedSpots(tx, park.ID, visitDate, timeSlot, excludeID)
+	if err != nil {
+		return fmt.Errorf("failed to count occupied spots: %w", err)
+	}
+
+	available := park.Capacity.Total - occupied
+	if available < guests {
+		if available < 0 {
+			available = 0
+		}
+		return fmt.Errorf("%w: %d spots left", ErrInsufficientCapacity, available)
+	}
+	return nil
+}
+
+// RefreshParkCapacity пересчитывает снимок Capacity парка на текущий момент
+// по бронированиям, чьё время визита приходится на сейчас:
+// Reserved — подтверждённые бронирования и действующие удержания,
+// Current — гости, уже прошедшие в парк.
+func (s *BookingService) RefreshParkCapacity(parkID uuid.UUID) error {
+	now := time.Now().In(parkLocation())
+	localNow := now.Format("2006-01-02 15:04:05")
+
+	var counts struct {
+		Reserved int64
+		Current  int64
+	}
+	err := s.db.Model(&models.Booking{}).
+		Select(`COALESCE(SUM(total_guests) FILTER (WHERE status = ? OR (status = ? AND hold_expires_at > ?)), 0) AS reserved,
+			COALESCE(SUM(total_guests) FILTER (WHERE status = ?), 0) AS current`,
+			models.BookingStatusConfirmed, models.BookingStatusPendingPayment, now, models.BookingStatusCheckedIn).
+		Where("park_id = ? AND visit_date = ? AND deleted_at IS NULL", parkID, now.Format("2006-01-02")).
+		Where("visit_date + CAST(time_slot AS time) <= CAST(? AS timestamp)", localNow).
+		Where("visit_date + CAST(time_slot AS time) + duration * INTERVAL '1 minute' > CAST(? AS timestamp)", localNow).
+		Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+
+	// Total не перезаписывается: администратор мог изменить вместимость
+	// после подсчёта, и Available считается от значения в строке на момент записи
+	result := s.db.Exec(`UPDATE parks SET capacity = capacity || jsonb_build_object(
+			'reserved', CAST(? AS integer),
+			'current', CAST(? AS integer),
+			'available', GREATEST(COALESCE(CAST(capacity->>'total' AS integer), 0) - ? - ?, 0),
+			'lastUpdated', CAST(? AS text))
+		WHERE id = ? AND deleted_at IS NULL`,
+		counts.Reserved, counts.Current, counts.Reserved, counts.Current, now.Format(time.RFC3339Nano), parkID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ReleaseExpiredHolds снимает истёкшие удержания и предложения листа
+// ожидания, обновляет снимки вместимости парков и предлагает освободившиеся
+// места листу ожидания. Возвращает количество освобождённых бронирований.
+func (s *BookingService) ReleaseExpiredHolds() (int64, error) {
+	result := s.db.Model(&models.Booking{}).
+		Where("status = ? AND hold_expires_at <= ? AND deleted_at IS NULL", models.BookingStatusPendingPayment, time.Now()).
+		Update("hold_expires_at", nil)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	if expired, err := s.ExpireWaitlistOffers(); err != nil {
+		log.Printf("⚠️ Failed to expire waitlist offers: %v", err)
+	} else if expired > 0 {
+		log.Printf("⌛ Expired %d unclaimed waitlist offers", expired)
+	}
+
+	// Снимок устаревает и без истёкших удержаний (со сменой слотов),
+	// поэтому пересчитываются все активные парки
+	var parkIDs []uuid.UUID
+	if err := s.db.Model(&models.Park{}).
+		Where("status = ? AND deleted_at IS NULL", models.ParkStatusActive).
+		Pluck("id", &parkIDs).Error; err != nil {
+		return result.RowsAffected, err
+	}
+
+	for _, parkID := range parkIDs {
+		s.CapacityReleased(parkID)
+	}
+
+	return result.RowsAffected, nil
+}
+
+// StartHoldReleaser периодически освобождает истёкшие удержания до отмены ctx
+func (s *BookingService) StartHoldReleaser(ctx context.Context) {
+	ticker := time.NewTicker(s.config.HoldReleaseInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			released, err := s.ReleaseExpiredHolds()
+			if err != nil {
+				log.Printf("⚠️ Failed to release expired booking holds: %v", err)
+				continue
+			}
+			if released > 0 {
+				log.Printf("🔓 Released %d expired booking holds", released)
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package booking
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"skypark/internal/etag"
+	"skypark/internal/models"
+)
+
+// testDB возвращает транзакцию в базе TEST_DATABASE_URL с применёнными
+// миграциями; транзакция откатывается после теста. Без переменной тест пропускается.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to connect to the test database: %v", err)
+	}
+	tx := db.Begin()
+	if tx.Error != nil {
+		t.Fatalf("failed to begin a transaction: %v", tx.Error)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	return tx
+}
+
+func createTestPark(t *testing.T, tx *gorm.DB, total int) uuid.UUID {
+	t.Helper()
+	id := models.GenerateUUID()
+	err := tx.Exec(`INSERT INTO parks (id, name, description, address, coordinates, capacity)
+		VALUES (?, 'Sky Park', 'Test park', '{}', '{}', jsonb_build_object('total', CAST(? AS integer)))`, id, total).Error
+	if err != nil {
+		t.Fatalf("failed to create a park: %v", err)
+	}
+	return id
+}
+
+func parkETag(t *testing.T, tx *gorm.DB, parkID uuid.UUID) string {
+	t.Helper()
+	var version int64
+	if err := tx.Model(&models.Park{}).Select("version").Where("id = ?", parkID).Scan(&version).Error; err != nil {
+		t.Fatalf("failed to read the park version: %v", err)
+	}
+	return etag.Format(version)
+}
+
+func TestRefreshParkCapacityKeepsParkETag(t *testing.T) {
+	tx := testDB(t)
+	service := NewBookingService(tx, Config{}, nil, nil, nil)
+	parkID := createTestPark(t, tx, 100)
+	before := parkETag(t, tx, parkID)
+
+	// Фоновый пересчёт снимка не должен делать If-Match клиентов устаревшим
+	for i := 0; i < 2; i++ {
+		if err := service.RefreshParkCapacity(parkID); err != nil {
+			t.Fatalf("RefreshParkCapacity() error = %v", err)
+		}
+	}
+	if got := parkETag(t, tx, parkID); got != before {
+		t.Errorf("ETag after capacity refresh = %s, want %s", got, before)
+	}
+
+	var park models.Park
+	if err := tx.Select("capacity").Where("id = ?", parkID).First(&park).Error; err != nil {
+		t.Fatalf("failed to load the park: %v", err)
+	}
+	if park.Capacity.Total != 100 || park.Capacity.Available != 100 || park.Capacity.LastUpdated.IsZero() {
+		t.Errorf("capacity after refresh = %+v, want total and available 100", park.Capacity)
+	}
+
+	// Изменение общей вместимости администратором — правка парка
+	if err := tx.Exec(`UPDATE parks SET capacity = jsonb_set(capacity, '{total}', '80') WHERE id = ?`, parkID).Error; err != nil {
+		t.Fatalf("failed to update the park capacity: %v", err)
+	}
+	changed := parkETag(t, tx, parkID)
+	if changed == before {
+		t.Errorf("ETag after a capacity total change = %s, want a new version", changed)
+	}
+
+	if err := tx.Model(&models.Park{}).Where("id = ?", parkID).Update("name", "Sky Park Bishkek").Error; err != nil {
+		t.Fatalf("failed to rename the park: %v", err)
+	}
+	if got := parkETag(t, tx, parkID); got == changed {
+		t.Errorf("ETag after a park edit = %s, want a new version", got)
+	}
+}
@@ -0,0 +1,55 @@
+package booking
+
+import "time"
+
+// Config содержит настройки сервиса бронирований
+type Config struct {
+	// HoldTTL — сколько бронирование в статусе pending_payment удерживает места
+	HoldTTL time.Duration
+	// HoldReleaseInterval — как часто освобождаются истёкшие удержания
+	HoldReleaseInterval time.Duration
+	// SlotLength — длительность слота посещения, она же Booking.Duration
+	SlotLength time.Duration
+	// SweepInterval — как часто запускается уборка неявок и устаревших бронирований
+	SweepInterval time.Duration
+	// NoShowGrace — сколько ждать после окончания слота, прежде чем отметить неявку
+	NoShowGrace time.Duration
+	// DraftTTL — через сколько после последнего изменения истекает черновик
+	DraftTTL time.Duration
+	// PendingPaymentTTL — через сколько истекает неоплаченное бронирование без удержания
+	PendingPaymentTTL time.Duration
+	// WaitlistClaimWindow — сколько действует предложение мест из листа ожидания
+	WaitlistClaimWindow time.Duration
+	// PartyDepositWindow — сколько есть на оплату депозита за праздник;
+	// до этого срока за бронированием держится комната
+	PartyDepositWindow time.Duration
+	// EarlyEntryWindow — за сколько до начала слота пускают по билету
+	EarlyEntryWindow time.Duration
+	// WalletPushInterval — как часто кошелькам сообщается об изменившихся билетах
+	WalletPushInterval time.Duration
+	// LookupWindow, LookupsPerIP и LookupsPerPhone ограничивают поиск
+	// бронирования гостем без входа: попыток с одного адреса и на один телефон за окно
+	LookupWindow    time.Duration
+	LookupsPerIP    int
+	LookupsPerPhone int
+}
+
+// DefaultConfig возвращает настройки по умолчанию
+func DefaultConfig() Config {
+	return Config{
+		HoldTTL:             15 * time.Minute,
+		HoldReleaseInterval: time.Minute,
+		SlotLength:          180 * time.Minute,
+		SweepInterval:       5 * time.Minute,
+		NoShowGrace:         30 * time.Minute,
+		DraftTTL:            24 * time.Hour,
+		PendingPaymentTTL:   2 * time.Hour,
+		WaitlistClaimWindow: 30 * time.Minute,
+		PartyDepositWindow:  24 * time.Hour,
+		EarlyEntryWindow:    15 * time.Minute,
+		WalletPushInterval:  time.Minute,
+		LookupWindow:        15 * time.Minute,
+		LookupsPerIP:        20,
+		LookupsPerPhone:     5,
+	}
+}
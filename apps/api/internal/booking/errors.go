@@ -0,0 +1,78 @@
+package booking
+
+import "errors"
+
+var (
+	ErrParkNotFound     = errors.New("park not found")
+	ErrParkUnavailable  = errors.New("park is not accepting bookings")
+	ErrParkClosed       = errors.New("park is closed on the visit date")
+	ErrInvalidVisitDate = errors.New("invalid visit date")
+	ErrInvalidTimeSlot  = errors.New("invalid time slot")
+	ErrNoGuests         = errors.New("at least one guest is required")
+	ErrInvalidGuest     = errors.New("invalid guest information")
+	ErrInvalidContact   = errors.New("invalid contact information")
+
+	ErrInsufficientCapacity = errors.New("not enough free spots in the time slot")
+
+	ErrBookingNotFound      = errors.New("booking not found")
+	ErrTooManyLookups       = errors.New("too many booking lookups, try again later")
+	ErrTicketNotFound       = errors.New("ticket not found")
+	ErrInvalidRefundAmount  = errors.New("refund amount exceeds the refundable amount")
+	ErrBookingNotModifiable = errors.New("booking can no longer be changed")
+	ErrSurchargeDue         = errors.New("surcharge for the previous change has not been paid")
+	ErrSurchargeNotFound    = errors.New("no unpaid surcharge for this booking")
+
+	ErrSweepInProgress = errors.New("booking sweep is already running")
+
+	ErrSlotAvailable         = errors.New("time slot still has enough free spots")
+	ErrAlreadyWaitlisted     = errors.New("already on the waitlist for this time slot")
+	ErrWaitlistEntryNotFound = errors.New("waitlist entry not found")
+	ErrOfferNotActive        = errors.New("waitlist offer is not active")
+
+	ErrGroupTooSmall       = errors.New("group is too small for a group booking")
+	ErrInvalidOrganization = errors.New("invalid organization information")
+	ErrNotAwaitingApproval = errors.New("booking is not awaiting approval")
+	ErrInvoiceNotFound     = errors.New("invoice not found")
+
+	ErrPassProductNotFound = errors.New("pass product not found")
+	ErrInvalidPassProduct  = errors.New("invalid pass product")
+	ErrInvalidPass         = errors.New("pass cannot be used for this visit")
+	ErrPassExhausted       = errors.New("pass has no visits left")
+
+	ErrPartyPackageNotFound = errors.New("party package not found")
+	ErrPartyRoomNotFound    = errors.New("party room not found")
+	ErrInvalidPartyPackage  = errors.New("invalid party package")
+	ErrInvalidPartyAddOn    = errors.New("add-on is not offered by the party package")
+	ErrPartyTooLarge        = errors.New("too many guests for the party room")
+	ErrPartyRoomUnavailable = errors.New("party room is already booked at this time")
+	ErrNoPaymentDue         = errors.New("no scheduled payment is due")
+	ErrInvalidPaymentMethod = errors.New("invalid payment method")
+
+	ErrInvalidStatsRange   = errors.New("invalid statistics date range")
+	ErrInvalidStatsGroupBy = errors.New("statistics can be grouped by day, week or month")
+
+	ErrInvalidManifestCursor = errors.New("manifest cursor does not belong to this park and date")
+	ErrScanBatchTooLarge     = errors.New("too many scans in one upload, the limit is 500")
+	ErrScanNotFound          = errors.New("scan not found")
+	ErrScanNotConflict       = errors.New("scan is not an open conflict")
+
+	ErrTransfersDisabled        = errors.New("ticket transfers are disabled in this park")
+	ErrTicketNotTransferable    = errors.New("ticket can no longer be transferred")
+	ErrTicketAlreadyValidated   = errors.New("ticket has already been validated and cannot be transferred")
+	ErrInvalidTransferRecipient = errors.New("invalid transfer recipient phone number")
+	ErrTransferNotFound         = errors.New("ticket transfer not found")
+	ErrTransferExpired          = errors.New("ticket transfer has expired")
+	ErrInvalidTransferCode      = errors.New("invalid transfer confirmation code")
+	ErrTransferredGuestRemoved  = errors.New("guest holds a ticket transferred to another person and cannot be removed")
+
+	ErrInvalidQRFormat = errors.New("QR code format must be png, svg or jpeg")
+	ErrInvalidQRSize   = errors.New("QR code size must be between 64 and 1024 pixels")
+	ErrInvalidQRLevel  = errors.New("QR code error correction level must be L, M, Q or H")
+
+	ErrInvalidPDFLanguage = errors.New("document language must be ru or ky")
+
+	ErrWalletNotConfigured   = errors.New("wallet passes are not configured")
+	ErrWalletPassUnavailable = errors.New("ticket can no longer be added to a wallet")
+	ErrWalletPassNotFound    = errors.New("wallet pass not found")
+	ErrInvalidWalletToken    = errors.New("invalid wallet pass authentication token")
+)
@@ -0,0 +1,388 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"skypark/internal/models"
+)
+
+// Статусы согласования групповых бронирований в Booking.Metadata["approvalStatus"]
+const (
+	approvalPending  = "pending"
+	approvalApproved = "approved"
+	approvalRejected = "rejected"
+)
+
+// DefaultGroupSettings применяется, если у парка нет своих правил для групп:
+// от 10 гостей, один бесплатный сопровождающий на 10 детей, согласование
+// администратором и 5 дней на оплату счёта.
+func DefaultGroupSettings() models.GroupBookingSettings {
+	return models.GroupBookingSettings{
+		MinGroupSize:             10,
+		ChildrenPerFreeChaperone: 10,
+		RequiresApproval:         true,
+		InvoiceDueDays:           5,
+	}
+}
+
+// groupSettingsFor возвращает правила групповых бронирований парка
+func groupSettingsFor(park *models.Park) models.GroupBookingSettings {
+	if park.Settings.Group != nil {
+		return *park.Settings.Group
+	}
+	return DefaultGroupSettings()
+}
+
+// GroupOrganization — организация (школа, детский сад), на которую выставляется счёт
+type GroupOrganization struct {
+	Name    string  `json:"name" binding:"required"`
+	TaxID   *string `json:"taxId,omitempty"` // ИНН
+	Address *string `json:"address,omitempty"`
+}
+
+// CreateGroupBookingRequest описывает групповое бронирование. Взрослые гости
+// считаются сопровождающими.
+type CreateGroupBookingRequest struct {
+	CreateBookingRequest
+	Organization GroupOrganization `json:"organization" binding:"required"`
+}
+
+// GroupBookingResult описывает групповое бронирование и выставленный счёт
+type GroupBookingResult struct {
+	Booking *models.Booking `json:"booking"`
+	Invoice *models.Payment `json:"invoice,omitempty"`
+}
+
+// RecordInvoicePaymentRequest описывает поступление оплаты по счёту
+type RecordInvoicePaymentRequest struct {
+	Reference string `json:"reference" binding:"required"` // номер платёжного поручения
+}
+
+// CreateGroupBooking создаёт групповое бронирование с групповой скидкой парка
+// и бесплатными билетами сопровождающих. Если парк требует согласования,
+// бронирование остаётся черновиком до решения администратора, иначе сразу
+// выставляется счёт для оплаты банковским переводом.
+func (s *BookingService) CreateGroupBooking(userID uuid.UUID, req CreateGroupBookingRequest) (*GroupBookingResult, error) {
+	req.Organization.Name = strings.TrimSpace(req.Organization.Name)
+	if req.Organization.Name == "" {
+		return nil, ErrInvalidOrganization
+	}
+	req.SaveAsDraft = false
+
+	result := &GroupBookingResult{}
+	booking, err := s.createBooking(userID, req.CreateBookingRequest, createOptions{
+		prepare: func(tx *gorm.DB, park *models.Park, booking *models.Booking) error {
+			settings := groupSettingsFor(park)
+			if len(booking.Items) < settings.MinGroupSize {
+				return fmt.Errorf("%w: at least %d guests", ErrGroupTooSmall, settings.MinGroupSize)
+			}
+
+			booking.BookingType = models.BookingTypeGroup
+			applyGroupPricing(park, settings, booking)
+			applyTotals(booking)
+
+			booking.Metadata["organization"] = req.Organization
+			if settings.RequiresApproval {
+				booking.Status = models.BookingStatusDraft
+				booking.HoldExpiresAt = nil
+				booking.Metadata["approvalStatus"] = approvalPending
+			} else {
+				booking.Status = models.BookingStatusPendingPayment
+				dueAt := invoiceDueDate(park, booking, time.Now())
+				booking.HoldExpiresAt = &dueAt
+			}
+			return nil
+		},
+		created: func(tx *gorm.DB, park *models.Park, booking *models.Booking) error {
+			if booking.Status != models.BookingStatusPendingPayment {
+				return nil
+			}
+			invoice, err := issueInvoice(tx, booking, *booking.HoldExpiresAt)
+			result.Invoice = invoice
+			return err
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Booking = booking
+	return result, nil
+}
+
+// applyGroupPricing применяет к позициям группового бронирования групповую
+// скидку парка и делает бесплатными билеты сопровождающих
+func applyGroupPricing(park *models.Park, settings models.GroupBookingSettings, booking *models.Booking) {
+	children := 0
+	for _, item := range booking.Items {
+		if !isChaperone(item.GuestInfo) {
+			children++
+		}
+	}
+	freeChaperones := 0
+	if settings.ChildrenPerFreeChaperone > 0 {
+		freeChaperones = children / settings.ChildrenPerFreeChaperone
+	}
+
+	var groupDiscount, chaperoneDiscount float64
+	var discounted, free []uuid.UUID
+	for i := range booking.Items {
+		item := &booking.Items[i]
+		if item.GuestInfo.PassTicketID != nil {
+			// Гость проходит по абонементу
+			continue
+		}
+		item.GuestInfo.TicketType = models.TicketTypeGroup
+
+		if freeChaperones > 0 && isChaperone(item.GuestInfo) {
+			freeChaperones--
+			item.DiscountAmount = item.BasePrice
+			chaperoneDiscount += item.DiscountAmount
+			free = append(free, item.ID)
+		} else if park.GroupDiscount > 0 {
+			item.DiscountAmount = roundMoney(item.BasePrice * park.GroupDiscount / 100)
+			groupDiscount += item.DiscountAmount
+			discounted = append(discounted, item.ID)
+		}
+		item.FinalPrice = roundMoney(item.BasePrice - item.DiscountAmount)
+	}
+
+	if groupDiscount > 0 {
+		booking.Discounts = append(booking.Discounts, models.DiscountInfo{
+			Type:        "percentage",
+			Description: fmt.Sprintf("Групповая скидка %g%%", park.GroupDiscount),
+			Amount:      roundMoney(groupDiscount),
+			AppliedTo:   discounted,
+			UsageCount:  1,
+		})
+	}
+	if chaperoneDiscount > 0 {
+		booking.Discounts = append(booking.Discounts, models.DiscountInfo{
+			Type:        "fixed",
+			Description: "Бесплатные билеты сопровождающих",
+			Amount:      roundMoney(chaperoneDiscount),
+			AppliedTo:   free,
+			UsageCount:  1,
+		})
+	}
+}
+
+// isChaperone сообщает, считается ли гость группы сопровождающим
+func isChaperone(guest models.GuestInfo) bool {
+	return guest.AgeCategory == models.AgeCategoryAdult || guest.AgeCategory == models.AgeCategorySenior
+}
+
+// invoiceDueDate возвращает срок оплаты счёта: InvoiceDueDays с момента now,
+// но не позже начала визита
+func invoiceDueDate(park *models.Park, booking *models.Booking, now time.Time) time.Time {
+	dueAt := now.AddDate(0, 0, groupSettingsFor(park).InvoiceDueDays)
+	if start := visitStart(booking); dueAt.After(start) {
+		dueAt = start
+	}
+	return dueAt
+}
+
+// issueInvoice выставляет счёт на оплату бронирования банковским переводом
+func issueInvoice(tx *gorm.DB, booking *models.Booking, dueAt time.Time) (*models.Payment, error) {
+	now := time.Now()
+	invoiceNumber := "INV-" + strings.TrimPrefix(booking.BookingNumber, models.BookingNumberPrefix+"-")
+	description := "Счёт на оплату группового посещения " + booking.BookingNumber
+
+	invoice := models.Payment{
+		BookingID:      booking.ID,
+		UserID:         booking.UserID,
+		Method:         models.PaymentMethodBankTransfer,
+		Status:         models.PaymentStatusPending,
+		Amount:         booking.TotalAmount,
+		OriginalAmount: booking.TotalAmount,
+		NetAmount:      booking.TotalAmount,
+		Currency:       booking.Currency,
+		Details: models.PaymentDetails{
+			Provider:          models.PaymentProviderInternal,
+			ProviderReference: &invoiceNumber,
+			Metadata:          models.JSONB{},
+		},
+		Refunds:     []models.RefundDetails{},
+		InitiatedAt: &now,
+		Description: &description,
+		Metadata: models.JSONB{
+			"type":          "invoice",
+			"invoiceNumber": invoiceNumber,
+			"dueDate":       dueAt,
+			"organization":  booking.Metadata["organization"],
+		},
+	}
+	if err := tx.Create(&invoice).Error; err != nil {
+		return nil, fmt.Errorf("failed to issue invoice: %w", err)
+	}
+	return &invoice, nil
+}
+
+// ApproveGroupBooking согласует групповое бронирование: места удерживаются
+// до срока оплаты и выставляется счёт (администратор)
+func (s *BookingService) ApproveGroupBooking(bookingID, adminID uuid.UUID, version *int64) (*GroupBookingResult, error) {
+	result := &GroupBookingResult{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		booking, err := loadBookingForUpdate(tx, bookingID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(booking, version); err != nil {
+			return err
+		}
+		if booking.BookingType != models.BookingTypeGroup || booking.Status != models.BookingStatusDraft ||
+			booking.Metadata["approvalStatus"] != approvalPending {
+			return ErrNotAwaitingApproval
+		}
+
+		if booking.Metadata == nil {
+			booking.Metadata = models.JSONB{}
+		}
+		booking.Metadata["approvalStatus"] = approvalApproved
+		booking.Metadata["approvedBy"] = adminID
+		booking.Metadata["approvedAt"] = time.Now()
+		if _, err := s.transition(tx, booking, models.BookingStatusPendingPayment, adminID, "group booking approved"); err != nil {
+			return err
+		}
+
+		result.Booking = booking
+		result.Invoice, err = issueInvoice(tx, booking, *booking.HoldExpiresAt)
+		if err != nil {
+			return err
+		}
+		return reloadVersion(tx, booking)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.RefreshParkCapacity(result.Booking.ParkID); err != nil {
+		log.Printf("⚠️ Failed to refresh capacity for park %s: %v", result.Booking.ParkID, err)
+	}
+	return result, nil
+}
+
+// RecordInvoicePayment отмечает счёт оплаченным по банковскому переводу
+// и подтверждает бронирование (администратор)
+func (s *BookingService) RecordInvoicePayment(bookingID, adminID uuid.UUID, req RecordInvoicePaymentRequest, version *int64) (*GroupBookingResult, error) {
+	result := &GroupBookingResult{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		booking, err := loadBookingForUpdate(tx, bookingID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(booking, version); err != nil {
+			return err
+		}
+
+		var invoice models.Payment
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("booking_id = ? AND method = ? AND status = ? AND metadata->>'type' = 'invoice'",
+				booking.ID, models.PaymentMethodBankTransfer, models.PaymentStatusPending).
+			Order("created_at DESC").
+			First(&invoice).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvoiceNotFound
+			}
+			return err
+		}
+
+		if _, err := s.transition(tx, booking, models.BookingStatusConfirmed, adminID, "invoice paid"); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		reference := strings.TrimSpace(req.Reference)
+		invoice.Status = models.PaymentStatusCompleted
+		invoice.CapturedAt = &now
+		invoice.Details.ProviderTransactionID = &reference
+		if invoice.Metadata == nil {
+			invoice.Metadata = models.JSONB{}
+		}
+		invoice.Metadata["paidRecordedBy"] = adminID
+		if err := tx.Model(&invoice).
+			Select("status", "captured_at", "details", "metadata").
+			Updates(&invoice).Error; err != nil {
+			return fmt.Errorf("failed to record invoice payment: %w", err)
+		}
+
+		booking.PaymentStatus = models.PaymentStatusCompleted
+		if err := tx.Model(booking).Update("payment_status", booking.PaymentStatus).Error; err != nil {
+			return fmt.Errorf("failed to update booking payment status: %w", err)
+		}
+
+		result.Booking = booking
+		result.Invoice = &invoice
+		return reloadVersion(tx, booking)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.RefreshParkCapacity(result.Booking.ParkID); err != nil {
+		log.Printf("⚠️ Failed to refresh capacity for park %s: %v", result.Booking.ParkID, err)
+	}
+	return result, nil
+}
+
+// GetBookingInvoice возвращает последний счёт по бронированию пользователя
+func (s *BookingService) GetBookingInvoice(bookingID, userID uuid.UUID) (*models.Payment, error) {
+	var booking models.Booking
+	if err := s.db.Where("id = ? AND user_id = ? AND deleted_at IS NULL", bookingID, userID).
+		Select("id").First(&booking).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookingNotFound
+		}
+		return nil, err
+	}
+
+	var invoice models.Payment
+	err := s.db.Where("booking_id = ? AND method = ? AND metadata->>'type' = 'invoice'",
+		booking.ID, models.PaymentMethodBankTransfer).
+		Order("created_at DESC").
+		First(&invoice).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvoiceNotFound
+		}
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// updateOpenInvoices приводит сумму неоплаченных счетов к новой стоимости бронирования
+func updateOpenInvoices(tx *gorm.DB, booking *models.Booking) error {
+	err := tx.Model(&models.Payment{}).
+		Where("booking_id = ? AND method = ? AND status = ?",
+			booking.ID, models.PaymentMethodBankTransfer, models.PaymentStatusPending).
+		Updates(map[string]interface{}{
+			"amount":          booking.TotalAmount,
+			"original_amount": booking.TotalAmount,
+			"net_amount":      booking.TotalAmount,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update open invoices: %w", err)
+	}
+	return nil
+}
+
+// cancelOpenInvoices аннулирует неоплаченные счета отменённого бронирования
+func cancelOpenInvoices(tx *gorm.DB, bookingID uuid.UUID) error {
+	err := tx.Model(&models.Payment{}).
+		Where("booking_id = ? AND method = ? AND status = ?",
+			bookingID, models.PaymentMethodBankTransfer, models.PaymentStatusPending).
+		Update("status", models.PaymentStatusCancelled).Error
+	if err != nil {
+		return fmt.Errorf("failed to cancel open invoices: %w", err)
+	}
+	return nil
+}
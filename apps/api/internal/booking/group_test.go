@@ -0,0 +1,108 @@
+package booking
+
+import (
+	"testing"
+
+	"skypark/internal/models"
+)
+
+// groupBooking возвращает групповое бронирование из children детей и adults взрослых
+func groupBooking(park *models.Park, children, adults int) *models.Booking {
+	var guests []models.GuestInfo
+	for i := 0; i < children; i++ {
+		guests = append(guests, models.GuestInfo{Name: "Ученик", AgeCategory: models.AgeCategoryChild})
+	}
+	for i := 0; i < adults; i++ {
+		guests = append(guests, models.GuestInfo{Name: "Учитель", AgeCategory: models.AgeCategoryAdult})
+	}
+	return &models.Booking{Items: PriceItems(park, guests, 0)}
+}
+
+func TestApplyGroupPricingFreeChaperones(t *testing.T) {
+	tests := []struct {
+		name          string
+		children      int
+		adults        int
+		perChaperone  int
+		wantFree      int
+		wantTotal     float64
+		wantDiscounts int
+	}{
+		{"one free per ten children", 20, 3, 10, 2, 20*500 + 300, 1},
+		{"ratio rounds down", 19, 3, 10, 1, 19*500 + 2*300, 1},
+		{"no more free than chaperones", 30, 2, 10, 2, 30 * 500, 1},
+		{"too few children", 9, 1, 10, 0, 9*500 + 300, 0},
+		{"disabled", 20, 2, 0, 0, 20*500 + 2*300, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			park := testPark()
+			booking := groupBooking(park, tt.children, tt.adults)
+			settings := models.GroupBookingSettings{ChildrenPerFreeChaperone: tt.perChaperone}
+
+			applyGroupPricing(park, settings, booking)
+			applyTotals(booking)
+
+			free := 0
+			for _, item := range booking.Items {
+				if item.GuestInfo.TicketType != models.TicketTypeGroup {
+					t.Errorf("ticket type = %q, want %q", item.GuestInfo.TicketType, models.TicketTypeGroup)
+				}
+				if item.FinalPrice == 0 {
+					if !isChaperone(item.GuestInfo) {
+						t.Errorf("child %s got a free ticket", item.ID)
+					}
+					free++
+				}
+			}
+			if free != tt.wantFree {
+				t.Errorf("free tickets = %d, want %d", free, tt.wantFree)
+			}
+			if booking.TotalAmount != tt.wantTotal {
+				t.Errorf("total = %v, want %v", booking.TotalAmount, tt.wantTotal)
+			}
+			if len(booking.Discounts) != tt.wantDiscounts {
+				t.Errorf("discounts = %+v, want %d", booking.Discounts, tt.wantDiscounts)
+			}
+		})
+	}
+}
+
+func TestApplyGroupPricingCombinesDiscounts(t *testing.T) {
+	park := testPark()
+	park.GroupDiscount = 10
+	booking := groupBooking(park, 10, 2)
+
+	applyGroupPricing(park, models.GroupBookingSettings{ChildrenPerFreeChaperone: 10}, booking)
+	applyTotals(booking)
+
+	// Один сопровождающий бесплатно, остальные билеты со скидкой 10%
+	if want := roundMoney(10*450 + 270); booking.TotalAmount != want {
+		t.Errorf("total = %v, want %v", booking.TotalAmount, want)
+	}
+	if len(booking.Discounts) != 2 {
+		t.Fatalf("discounts = %+v, want group and chaperone discounts", booking.Discounts)
+	}
+	if booking.Discounts[0].Amount != 10*50+30 || booking.Discounts[1].Amount != 300 {
+		t.Errorf("discount amounts = %v and %v, want 530 and 300", booking.Discounts[0].Amount, booking.Discounts[1].Amount)
+	}
+}
+
+func TestApplyGroupPricingSkipsPassGuests(t *testing.T) {
+	park := testPark()
+	park.GroupDiscount = 10
+	booking := groupBooking(park, 2, 0)
+	passID := models.GenerateUUID()
+	booking.Items[0].GuestInfo.PassTicketID = &passID
+	booking.Items[0].DiscountAmount = booking.Items[0].BasePrice
+	booking.Items[0].FinalPrice = 0
+
+	applyGroupPricing(park, models.GroupBookingSettings{}, booking)
+
+	if item := booking.Items[0]; item.DiscountAmount != item.BasePrice || item.GuestInfo.TicketType == models.TicketTypeGroup {
+		t.Errorf("pass guest was repriced: %+v", item)
+	}
+	if booking.Items[1].FinalPrice != 450 {
+		t.Errorf("paying guest final price = %v, want 450", booking.Items[1].FinalPrice)
+	}
+}
@@ -1,81 +1,2474 @@
-﻿package booking
+package booking
 
 import (
-"net/http"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
-"github.com/gin-gonic/gin"
-"gorm.io/gorm"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"skypark/internal/etag"
+	"skypark/internal/models"
+	"skypark/internal/ticketsign"
+	"skypark/internal/wallet"
 )
 
 type BookingHandlers struct {
-db *gorm.DB
+	db      *gorm.DB
+	service *BookingService
 }
 
-func NewBookingHandlers(db *gorm.DB) *BookingHandlers {
-return &BookingHandlers{
-db: db,
-}
+func NewBookingHandlers(db *gorm.DB, service *BookingService) *BookingHandlers {
+	return &BookingHandlers{
+		db:      db,
+		service: service,
+	}
 }
 
+// CreateBooking создаёт бронирование текущего пользователя
 func (h *BookingHandlers) CreateBooking(c *gin.Context) {
-c.JSON(http.StatusNotImplemented, gin.H{
-"success": false,
-"error": map[string]interface{}{
-"code":    "NOT_IMPLEMENTED", 
-"message": "Create booking not implemented yet",
-},
-})
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	var req CreateBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	booking, err := h.service.CreateBooking(userID, req)
+	if err != nil {
+		respondBookingError(c, err, "Failed to create booking")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    booking,
+		"message": "Booking created successfully",
+	})
 }
 
 func (h *BookingHandlers) GetUserBookings(c *gin.Context) {
-c.JSON(http.StatusNotImplemented, gin.H{
-"success": false,
-"error": map[string]interface{}{
-"code":    "NOT_IMPLEMENTED",
-"message": "Get bookings not implemented yet", 
-},
-})
+	c.JSON(http.StatusNotImplemented, gin.H{
+		"success": false,
+		"error": map[string]interface{}{
+			"code":    "NOT_IMPLEMENTED",
+			"message": "Get bookings not implemented yet",
+		},
+	})
 }
 
+// GetBookingByID возвращает бронирование текущего пользователя с его версией в ETag
 func (h *BookingHandlers) GetBookingByID(c *gin.Context) {
-c.JSON(http.StatusNotImplemented, gin.H{
-"success": false,
-"error": map[string]interface{}{
-"code":    "NOT_IMPLEMENTED",
-"message": "Get booking by ID not implemented yet",
-},
-})
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	bookingID, ok := bookingIDParam(c)
+	if !ok {
+		return
+	}
+
+	booking, err := h.service.GetBooking(bookingID)
+	if err == nil && booking.UserID != userID {
+		err = ErrBookingNotFound
+	}
+	if err != nil {
+		respondBookingError(c, err, "Failed to get booking")
+		return
+	}
+
+	etag.Set(c, booking.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    booking,
+	})
 }
 
+// UpdateBooking переносит бронирование или меняет состав гостей
 func (h *BookingHandlers) UpdateBooking(c *gin.Context) {
-c.JSON(http.StatusNotImplemented, gin.H{
-"success": false,
-"error": map[string]interface{}{
-"code":    "NOT_IMPLEMENTED",
-"message": "Update booking not implemented yet",
-},
-})
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	bookingID, ok := bookingIDParam(c)
+	if !ok {
+		return
+	}
+	version, ok := etag.ParseIfMatch(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	result, err := h.service.UpdateBooking(bookingID, userID, req, version)
+	if err != nil {
+		respondBookingError(c, err, "Failed to update booking")
+		return
+	}
+
+	etag.Set(c, result.Booking.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+		"message": "Booking updated successfully",
+	})
 }
 
+// CancelBooking отменяет бронирование пользователя с возвратом по политике парка
 func (h *BookingHandlers) CancelBooking(c *gin.Context) {
-c.JSON(http.StatusNotImplemented, gin.H{
-"success": false,
-"error": map[string]interface{}{
-"code":    "NOT_IMPLEMENTED",
-"message": "Cancel booking not implemented yet",
-},
-})
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	bookingID, ok := bookingIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req CancelRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": map[string]interface{}{
+					"code":    "INVALID_REQUEST",
+					"message": "Invalid request format",
+					"details": err.Error(),
+				},
+			})
+			return
+		}
+	}
+
+	result, err := h.service.CancelBooking(bookingID, userID, req)
+	if err != nil {
+		respondBookingError(c, err, "Failed to cancel booking")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+		"message": "Booking cancelled successfully",
+	})
+}
+
+// GetBookingByNumber возвращает бронирование текущего пользователя по короткому номеру
+func (h *BookingHandlers) GetBookingByNumber(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	booking, err := h.service.GetBookingByNumber(c.Param("number"))
+	if err == nil && booking.UserID != userID {
+		// Чужие бронирования не раскрываются даже по существованию номера
+		err = ErrBookingNotFound
+	}
+	if err != nil {
+		respondBookingError(c, err, "Failed to get booking")
+		return
+	}
+
+	etag.Set(c, booking.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    booking,
+	})
+}
+
+// LookupGuestBooking находит бронирование по номеру и телефону без входа
+// в приложение
+func (h *BookingHandlers) LookupGuestBooking(c *gin.Context) {
+	var req struct {
+		Phone         string `json:"phone" binding:"required"`
+		BookingNumber string `json:"bookingNumber" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	booking, err := h.service.LookupGuestBooking(req.Phone, req.BookingNumber, c.ClientIP())
+	if err != nil {
+		respondBookingError(c, err, "Failed to find booking")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    booking,
+	})
+}
+
+// GetTicketByNumber возвращает билет текущего пользователя по короткому номеру
+func (h *BookingHandlers) GetTicketByNumber(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	ticket, err := h.service.GetTicketByNumber(c.Param("number"))
+	if err == nil && ticket.UserID != userID {
+		err = ErrTicketNotFound
+	}
+	if err != nil {
+		respondBookingError(c, err, "Failed to get ticket")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    ticket,
+	})
+}
+
+// ValidateTicket пропускает посетителя по отсканированному QR-коду (персонал
+// парка). При отказе код ошибки — причина для экрана турникета.
+func (h *BookingHandlers) ValidateTicket(c *gin.Context) {
+	staffID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	var req ValidateTicketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	result, err := h.service.ValidateTicket(staffID, req)
+	if err != nil {
+		respondBookingError(c, err, "Failed to validate ticket")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+		"message": "Entry allowed",
+	})
+}
+
+// GetScannerManifest отдаёт сканеру подписанный манифест билетов парка на
+// день (параметр date, по умолчанию сегодня). С параметром cursor из
+// предыдущего манифеста возвращаются только изменения.
+func (h *BookingHandlers) GetScannerManifest(c *gin.Context) {
+	parkID, err := uuid.Parse(c.Query("parkId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_PARK_ID",
+				"message": "Invalid park ID",
+			},
+		})
+		return
+	}
+
+	loc := parkLocation()
+	date := time.Now().In(loc)
+	if value := c.Query("date"); value != "" {
+		date, err = time.ParseInLocation("2006-01-02", value, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": map[string]interface{}{
+					"code":    "INVALID_DATE",
+					"message": "date must be in YYYY-MM-DD format",
+				},
+			})
+			return
+		}
+	}
+
+	manifest, err := h.service.GetScannerManifest(parkID, date, c.Query("cursor"))
+	if err != nil {
+		respondBookingError(c, err, "Failed to build scanner manifest")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    manifest,
+	})
 }
 
+// UploadScans принимает сканирования, накопленные устройством без связи
+func (h *BookingHandlers) UploadScans(c *gin.Context) {
+	staffID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	var req UploadScansRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	results, err := h.service.UploadScans(staffID, req)
+	if err != nil {
+		respondBookingError(c, err, "Failed to upload scans")
+		return
+	}
+
+	conflicts := 0
+	for _, result := range results {
+		if result.Outcome == models.TicketScanConflict {
+			conflicts++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"data":      results,
+		"total":     len(results),
+		"conflicts": conflicts,
+	})
+}
+
+// GetScanConflicts возвращает конфликты сканирований для разбора персоналом
+func (h *BookingHandlers) GetScanConflicts(c *gin.Context) {
+	var parkID *uuid.UUID
+	if value := c.Query("parkId"); value != "" {
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": map[string]interface{}{
+					"code":    "INVALID_PARK_ID",
+					"message": "Invalid park ID",
+				},
+			})
+			return
+		}
+		parkID = &parsed
+	}
+
+	conflicts, err := h.service.GetScanConflicts(parkID, c.Query("resolved") == "true")
+	if err != nil {
+		respondBookingError(c, err, "Failed to get scan conflicts")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    conflicts,
+		"total":   len(conflicts),
+	})
+}
+
+// ResolveScanConflict отмечает конфликт сканирования разобранным
+func (h *BookingHandlers) ResolveScanConflict(c *gin.Context) {
+	staffID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	scanID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_SCAN_ID",
+				"message": "Invalid scan ID",
+			},
+		})
+		return
+	}
+
+	var req struct {
+		Note string `json:"note"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": map[string]interface{}{
+					"code":    "INVALID_REQUEST",
+					"message": "Invalid request format",
+					"details": err.Error(),
+				},
+			})
+			return
+		}
+	}
+
+	scan, err := h.service.ResolveScanConflict(scanID, staffID, req.Note)
+	if err != nil {
+		respondBookingError(c, err, "Failed to resolve scan conflict")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    scan,
+		"message": "Conflict resolved",
+	})
+}
+
+// LookupBookingByNumber ищет любое бронирование по номеру (персонал парка)
+func (h *BookingHandlers) LookupBookingByNumber(c *gin.Context) {
+	booking, err := h.service.GetBookingByNumber(c.Param("number"))
+	if err != nil {
+		respondBookingError(c, err, "Failed to get booking")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    booking,
+	})
+}
+
+// LookupTicketByNumber ищет любой билет по номеру (персонал парка)
+func (h *BookingHandlers) LookupTicketByNumber(c *gin.Context) {
+	ticket, err := h.service.GetTicketByNumber(c.Param("number"))
+	if err != nil {
+		respondBookingError(c, err, "Failed to get ticket")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    ticket,
+	})
+}
+
+// GetAvailableTimeSlots возвращает слоты парка на дату с оставшимися местами
 func (h *BookingHandlers) GetAvailableTimeSlots(c *gin.Context) {
-c.JSON(http.StatusOK, gin.H{
-"success": true,
-"data": []string{
-"09:00-12:00",
-"12:00-15:00", 
-"15:00-18:00",
-"18:00-21:00",
-},
-"message": "Available time slots",
-})
+	parkID, err := uuid.Parse(c.Param("parkId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_PARK_ID",
+				"message": "Invalid park ID",
+			},
+		})
+		return
+	}
+
+	date := c.Query("date")
+	if date == "" {
+		date = time.Now().In(parkLocation()).Format("2006-01-02")
+	}
+
+	guests := 1
+	if value := c.Query("guests"); value != "" {
+		guests, err = strconv.Atoi(value)
+		if err != nil || guests < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": map[string]interface{}{
+					"code":    "INVALID_REQUEST",
+					"message": "Guests must be a positive number",
+				},
+			})
+			return
+		}
+	}
+
+	schedule, err := h.service.GetTimeSlots(parkID, date, guests)
+	if err != nil {
+		respondBookingError(c, err, "Failed to fetch time slots")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    schedule,
+		"message": "Available time slots",
+	})
+}
+
+// ConfirmBooking подтверждает бронирование (администратор)
+func (h *BookingHandlers) ConfirmBooking(c *gin.Context) {
+	h.transitionBooking(c, h.service.ConfirmBooking, "Booking confirmed successfully")
+}
+
+// CheckInBooking отмечает приход гостей (администратор)
+func (h *BookingHandlers) CheckInBooking(c *gin.Context) {
+	h.transitionBooking(c, h.service.CheckInBooking, "Booking checked in successfully")
+}
+
+// CompleteBooking завершает визит (администратор)
+func (h *BookingHandlers) CompleteBooking(c *gin.Context) {
+	h.transitionBooking(c, h.service.CompleteBooking, "Booking completed successfully")
+}
+
+// MarkNoShow отмечает неявку гостей (администратор)
+func (h *BookingHandlers) MarkNoShow(c *gin.Context) {
+	h.transitionBooking(c, h.service.MarkNoShow, "Booking marked as no-show")
+}
+
+// RejectBooking отклоняет бронирование с указанием причины (администратор)
+func (h *BookingHandlers) RejectBooking(c *gin.Context) {
+	bookingID, ok := bookingIDParam(c)
+	if !ok {
+		return
+	}
+	version, ok := etag.ParseIfMatch(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request",
+			},
+		})
+		return
+	}
+
+	actorID, _ := currentUserID(c)
+	booking, err := h.service.RejectBooking(bookingID, actorID, req.Reason, version)
+	if err != nil {
+		respondBookingError(c, err, "Failed to reject booking")
+		return
+	}
+
+	etag.Set(c, booking.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    booking,
+		"message": "Booking rejected successfully",
+	})
+}
+
+// AdminCancelBooking отменяет бронирование в обход политики отмены (администратор)
+func (h *BookingHandlers) AdminCancelBooking(c *gin.Context) {
+	bookingID, ok := bookingIDParam(c)
+	if !ok {
+		return
+	}
+	version, ok := etag.ParseIfMatch(c)
+	if !ok {
+		return
+	}
+
+	var req AdminCancelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	adminID, _ := currentUserID(c)
+	result, err := h.service.AdminCancelBooking(bookingID, adminID, req, version)
+	if err != nil {
+		respondBookingError(c, err, "Failed to cancel booking")
+		return
+	}
+
+	etag.Set(c, result.Booking.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+		"message": "Booking cancelled successfully",
+	})
+}
+
+// CreateGroupBooking создаёт групповое бронирование для школы или детского сада
+func (h *BookingHandlers) CreateGroupBooking(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	var req CreateGroupBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	result, err := h.service.CreateGroupBooking(userID, req)
+	if err != nil {
+		respondBookingError(c, err, "Failed to create group booking")
+		return
+	}
+
+	message := "Group booking created, invoice issued"
+	if result.Invoice == nil {
+		message = "Group booking created and awaiting approval"
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    result,
+		"message": message,
+	})
+}
+
+// GetBookingInvoice возвращает счёт на оплату бронирования текущего пользователя
+func (h *BookingHandlers) GetBookingInvoice(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	bookingID, ok := bookingIDParam(c)
+	if !ok {
+		return
+	}
+
+	invoice, err := h.service.GetBookingInvoice(bookingID, userID)
+	if err != nil {
+		respondBookingError(c, err, "Failed to get invoice")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    invoice,
+	})
+}
+
+// GetBookingPDF отдаёт печатное подтверждение бронирования с билетами в PDF
+// владельцу или персоналу парка. Язык задаётся параметром lang (ru или ky),
+// иначе берётся из Accept-Language.
+func (h *BookingHandlers) GetBookingPDF(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	bookingID, ok := bookingIDParam(c)
+	if !ok {
+		return
+	}
+
+	lang := c.Query("lang")
+	if lang == "" {
+		lang = "ru"
+		if strings.HasPrefix(strings.ToLower(c.GetHeader("Accept-Language")), "ky") {
+			lang = "ky"
+		}
+	}
+
+	booking, err := h.service.GetBooking(bookingID)
+	if err == nil && booking.UserID != userID && !isStaff(c) {
+		err = ErrBookingNotFound
+	}
+	if err != nil {
+		respondBookingError(c, err, "Failed to get booking")
+		return
+	}
+
+	document, err := RenderBookingPDF(booking, strings.ToLower(lang))
+	if err != nil {
+		respondBookingError(c, err, "Failed to render booking PDF")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="booking-%s.pdf"`, booking.BookingNumber))
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, "application/pdf", document)
+}
+
+// ApproveGroupBooking согласует групповое бронирование и выставляет счёт (администратор)
+func (h *BookingHandlers) ApproveGroupBooking(c *gin.Context) {
+	bookingID, ok := bookingIDParam(c)
+	if !ok {
+		return
+	}
+	version, ok := etag.ParseIfMatch(c)
+	if !ok {
+		return
+	}
+	adminID, _ := currentUserID(c)
+
+	result, err := h.service.ApproveGroupBooking(bookingID, adminID, version)
+	if err != nil {
+		respondBookingError(c, err, "Failed to approve group booking")
+		return
+	}
+
+	etag.Set(c, result.Booking.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+		"message": "Group booking approved, invoice issued",
+	})
+}
+
+// RecordInvoicePayment отмечает оплату счёта банковским переводом (администратор)
+func (h *BookingHandlers) RecordInvoicePayment(c *gin.Context) {
+	bookingID, ok := bookingIDParam(c)
+	if !ok {
+		return
+	}
+	version, ok := etag.ParseIfMatch(c)
+	if !ok {
+		return
+	}
+	adminID, _ := currentUserID(c)
+
+	var req RecordInvoicePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	result, err := h.service.RecordInvoicePayment(bookingID, adminID, req, version)
+	if err != nil {
+		respondBookingError(c, err, "Failed to record invoice payment")
+		return
+	}
+
+	etag.Set(c, result.Booking.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+		"message": "Invoice payment recorded, booking confirmed",
+	})
+}
+
+// RecordSurchargePayment отмечает оплату доплаты за изменение бронирования (администратор)
+func (h *BookingHandlers) RecordSurchargePayment(c *gin.Context) {
+	bookingID, ok := bookingIDParam(c)
+	if !ok {
+		return
+	}
+	version, ok := etag.ParseIfMatch(c)
+	if !ok {
+		return
+	}
+	adminID, _ := currentUserID(c)
+
+	var req RecordSurchargePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	result, err := h.service.RecordSurchargePayment(bookingID, adminID, req, version)
+	if err != nil {
+		respondBookingError(c, err, "Failed to record surcharge payment")
+		return
+	}
+
+	etag.Set(c, result.Booking.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+		"message": "Surcharge payment recorded, tickets issued",
+	})
+}
+
+// GetPassCatalog возвращает абонементы, которые можно купить в парке
+func (h *BookingHandlers) GetPassCatalog(c *gin.Context) {
+	parkID, err := uuid.Parse(c.Param("parkId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_PARK_ID",
+				"message": "Invalid park ID",
+			},
+		})
+		return
+	}
+
+	products, err := h.service.GetPassCatalog(parkID)
+	if err != nil {
+		respondBookingError(c, err, "Failed to get pass catalog")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    products,
+		"total":   len(products),
+	})
+}
+
+// PurchasePass оформляет покупку абонемента текущим пользователем
+func (h *BookingHandlers) PurchasePass(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	var req PurchasePassRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	result, err := h.service.PurchasePass(userID, req)
+	if err != nil {
+		respondBookingError(c, err, "Failed to purchase pass")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    result,
+		"message": "Pass purchase created, awaiting payment",
+	})
+}
+
+// GetUserPasses возвращает абонементы текущего пользователя
+func (h *BookingHandlers) GetUserPasses(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	passes, err := h.service.GetUserPasses(userID)
+	if err != nil {
+		respondBookingError(c, err, "Failed to get passes")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    passes,
+		"total":   len(passes),
+	})
+}
+
+// GetTicketSigningKeys публикует открытые ключи, которыми турникеты
+// проверяют подпись QR-кодов без связи с сервером
+func (h *BookingHandlers) GetTicketSigningKeys(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": map[string]interface{}{
+			"algorithm":   ticketsign.Algorithm,
+			"tokenPrefix": ticketsign.TokenPrefix,
+			"keys":        h.service.TicketSigningKeys(),
+		},
+	})
+}
+
+// GetTicketQR отдаёт изображение QR-кода билета владельцу или персоналу
+// парка. Формат, размер и уровень коррекции ошибок по умолчанию берутся из
+// билета и переопределяются параметрами format, size и level.
+func (h *BookingHandlers) GetTicketQR(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	ticketID, ok := ticketIDParam(c)
+	if !ok {
+		return
+	}
+
+	ticket, err := h.service.GetTicket(ticketID)
+	if err == nil && ticket.UserID != userID && !isStaff(c) {
+		err = ErrTicketNotFound
+	}
+	if err != nil {
+		respondBookingError(c, err, "Failed to get ticket")
+		return
+	}
+
+	opts := QRImageOptions{
+		Format:               c.DefaultQuery("format", ticket.QRCode.Format),
+		Size:                 ticket.QRCode.Size,
+		ErrorCorrectionLevel: c.DefaultQuery("level", ticket.QRCode.ErrorCorrectionLevel),
+	}
+	if opts.Format == "" {
+		opts.Format = "png"
+	}
+	if opts.Size == 0 {
+		opts.Size = 256
+	}
+	if opts.ErrorCorrectionLevel == "" {
+		opts.ErrorCorrectionLevel = "M"
+	}
+	if raw := c.Query("size"); raw != "" {
+		size, err := strconv.Atoi(raw)
+		if err != nil {
+			respondBookingError(c, ErrInvalidQRSize, "Invalid QR code size")
+			return
+		}
+		opts.Size = size
+	}
+	if err := opts.Validate(); err != nil {
+		respondBookingError(c, err, "Invalid QR code options")
+		return
+	}
+
+	// Изображение меняется только вместе с билетом, поэтому его версия и
+	// параметры изображения однозначно определяют ответ
+	tag := fmt.Sprintf(`"%d-%s-%d-%s"`, ticket.Version, opts.Format, opts.Size, opts.ErrorCorrectionLevel)
+	c.Header("ETag", tag)
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.Header("Last-Modified", ticket.UpdatedAt.UTC().Format(http.TimeFormat))
+	if etag.NoneMatch(c, tag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	image, err := RenderQRCode(ticket.QRCode.Data, opts)
+	if err != nil {
+		respondBookingError(c, err, "Failed to render QR code")
+		return
+	}
+
+	name := ticket.ID.String()
+	if ticket.TicketNumber != "" {
+		name = ticket.TicketNumber
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="ticket-%s.%s"`, name, opts.Format))
+	c.Data(http.StatusOK, opts.ContentType(), image)
+}
+
+// GetUserTickets возвращает билеты текущего пользователя
+func (h *BookingHandlers) GetUserTickets(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	tickets, err := h.service.GetUserTickets(userID)
+	if err != nil {
+		respondBookingError(c, err, "Failed to get tickets")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    tickets,
+		"total":   len(tickets),
+	})
+}
+
+// StartTicketTransfer начинает передачу билета текущего пользователя другому
+// человеку: получателю уходит SMS с кодом подтверждения
+func (h *BookingHandlers) StartTicketTransfer(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	ticketID, ok := ticketIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req TransferTicketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	transfer, err := h.service.StartTicketTransfer(userID, ticketID, req)
+	if err != nil {
+		respondBookingError(c, err, "Failed to start ticket transfer")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    transfer,
+		"message": "Confirmation code sent to the recipient",
+	})
+}
+
+// CancelTicketTransfer отменяет неподтверждённую передачу билета
+func (h *BookingHandlers) CancelTicketTransfer(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	ticketID, ok := ticketIDParam(c)
+	if !ok {
+		return
+	}
+
+	transfer, err := h.service.CancelTicketTransfer(userID, ticketID)
+	if err != nil {
+		respondBookingError(c, err, "Failed to cancel ticket transfer")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    transfer,
+		"message": "Ticket transfer cancelled",
+	})
+}
+
+// GetIncomingTransfers возвращает билеты, которые передают текущему пользователю
+func (h *BookingHandlers) GetIncomingTransfers(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	transfers, err := h.service.GetIncomingTransfers(userID)
+	if err != nil {
+		respondBookingError(c, err, "Failed to get ticket transfers")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    transfers,
+		"total":   len(transfers),
+	})
+}
+
+// AcceptTicketTransfer принимает переданный билет по коду из SMS
+func (h *BookingHandlers) AcceptTicketTransfer(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	transferID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_TRANSFER_ID",
+				"message": "Invalid transfer ID",
+			},
+		})
+		return
+	}
+
+	var req AcceptTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	ticket, err := h.service.AcceptTicketTransfer(userID, transferID, req)
+	if err != nil {
+		respondBookingError(c, err, "Failed to accept ticket transfer")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    ticket,
+		"message": "Ticket transferred",
+	})
+}
+
+// GetAppleWalletPass отдаёт владельцу билета пасс Apple Wallet (.pkpass)
+func (h *BookingHandlers) GetAppleWalletPass(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	ticketID, ok := ticketIDParam(c)
+	if !ok {
+		return
+	}
+
+	pass, ticket, err := h.service.AppleWalletPass(userID, ticketID)
+	if err != nil {
+		respondBookingError(c, err, "Failed to build wallet pass")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="ticket-%s.pkpass"`, ticket.TicketNumber))
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, wallet.PKPassContentType, pass)
+}
+
+// GetGoogleWalletLink возвращает владельцу билета ссылку «Добавить в Google Кошелёк»
+func (h *BookingHandlers) GetGoogleWalletLink(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	ticketID, ok := ticketIDParam(c)
+	if !ok {
+		return
+	}
+
+	saveURL, err := h.service.GoogleWalletSaveURL(userID, ticketID)
+	if err != nil {
+		respondBookingError(c, err, "Failed to build wallet pass")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"saveUrl": saveURL,
+		},
+	})
+}
+
+// Веб-сервис пассов Apple Wallet. Приложение Wallet вызывает его само,
+// подписывая запросы токеном из пасса, поэтому ответы следуют протоколу Apple.
+
+// applePassToken достаёт токен пасса из заголовка «Authorization: ApplePass <токен>»
+func applePassToken(c *gin.Context) string {
+	token, _ := strings.CutPrefix(c.GetHeader("Authorization"), "ApplePass ")
+	return token
+}
+
+// RegisterWalletDevice подписывает устройство на обновления пасса
+func (h *BookingHandlers) RegisterWalletDevice(c *gin.Context) {
+	var req struct {
+		PushToken string `json:"pushToken" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	created, err := h.service.RegisterAppleDevice(c.Param("device"), c.Param("passType"), c.Param("serial"), applePassToken(c), req.PushToken)
+	if err != nil {
+		respondBookingError(c, err, "Failed to register device")
+		return
+	}
+	if created {
+		c.Status(http.StatusCreated)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// UnregisterWalletDevice отписывает устройство от обновлений пасса
+func (h *BookingHandlers) UnregisterWalletDevice(c *gin.Context) {
+	err := h.service.UnregisterAppleDevice(c.Param("device"), c.Param("passType"), c.Param("serial"), applePassToken(c))
+	if err != nil {
+		respondBookingError(c, err, "Failed to unregister device")
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// GetWalletSerials возвращает устройству серийные номера изменившихся пассов
+func (h *BookingHandlers) GetWalletSerials(c *gin.Context) {
+	serials, lastUpdated, err := h.service.UpdatedApplePasses(c.Param("device"), c.Param("passType"), c.Query("passesUpdatedSince"))
+	if err != nil {
+		respondBookingError(c, err, "Failed to get updated passes")
+		return
+	}
+	if len(serials) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"serialNumbers": serials,
+		"lastUpdated":   lastUpdated,
+	})
+}
+
+// GetWalletPass отдаёт приложению Wallet актуальную версию пасса
+func (h *BookingHandlers) GetWalletPass(c *gin.Context) {
+	pass, ticket, err := h.service.ApplePassBySerial(c.Param("passType"), c.Param("serial"), applePassToken(c))
+	if err != nil {
+		respondBookingError(c, err, "Failed to build wallet pass")
+		return
+	}
+
+	modified := ticket.UpdatedAt.UTC().Truncate(time.Second)
+	c.Header("Last-Modified", modified.Format(http.TimeFormat))
+	if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && !modified.After(since) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, wallet.PKPassContentType, pass)
+}
+
+// LogWalletMessages записывает в журнал ошибки, о которых сообщает Wallet
+func (h *BookingHandlers) LogWalletMessages(c *gin.Context) {
+	var req struct {
+		Logs []string `json:"logs"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	for _, message := range req.Logs {
+		log.Printf("📲 Apple Wallet: %s", message)
+	}
+	c.Status(http.StatusOK)
+}
+
+// CreatePassProduct добавляет абонемент в каталог парка (администратор)
+func (h *BookingHandlers) CreatePassProduct(c *gin.Context) {
+	parkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_PARK_ID",
+				"message": "Invalid park ID",
+			},
+		})
+		return
+	}
+
+	var req PassProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	product, err := h.service.CreatePassProduct(parkID, req)
+	if err != nil {
+		respondBookingError(c, err, "Failed to create pass product")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    product,
+		"message": "Pass product created",
+	})
+}
+
+// UpdatePassProduct изменяет абонемент каталога парка (администратор)
+func (h *BookingHandlers) UpdatePassProduct(c *gin.Context) {
+	parkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_PARK_ID",
+				"message": "Invalid park ID",
+			},
+		})
+		return
+	}
+	productID, ok := passProductIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req PassProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	product, err := h.service.UpdatePassProduct(parkID, productID, req)
+	if err != nil {
+		respondBookingError(c, err, "Failed to update pass product")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    product,
+		"message": "Pass product updated",
+	})
+}
+
+// DeactivatePassProduct снимает абонемент с продажи (администратор)
+func (h *BookingHandlers) DeactivatePassProduct(c *gin.Context) {
+	parkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_PARK_ID",
+				"message": "Invalid park ID",
+			},
+		})
+		return
+	}
+	productID, ok := passProductIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeactivatePassProduct(parkID, productID); err != nil {
+		respondBookingError(c, err, "Failed to deactivate pass product")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Pass product deactivated",
+	})
+}
+
+// GetPartyPackages возвращает пакеты праздников парка
+func (h *BookingHandlers) GetPartyPackages(c *gin.Context) {
+	parkID, err := uuid.Parse(c.Param("parkId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_PARK_ID",
+				"message": "Invalid park ID",
+			},
+		})
+		return
+	}
+
+	packages, err := h.service.GetPartyPackages(parkID)
+	if err != nil {
+		respondBookingError(c, err, "Failed to get party packages")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    packages,
+		"total":   len(packages),
+	})
+}
+
+// GetPartyRoomCalendar возвращает занятость комнат для праздников на дату
+func (h *BookingHandlers) GetPartyRoomCalendar(c *gin.Context) {
+	parkID, err := uuid.Parse(c.Param("parkId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_PARK_ID",
+				"message": "Invalid park ID",
+			},
+		})
+		return
+	}
+
+	date := c.Query("date")
+	if date == "" {
+		date = time.Now().In(parkLocation()).Format("2006-01-02")
+	}
+
+	calendar, err := h.service.GetPartyRoomCalendar(parkID, date)
+	if err != nil {
+		respondBookingError(c, err, "Failed to get party room calendar")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    calendar,
+		"date":    date,
+	})
+}
+
+// CreatePartyBooking бронирует праздник для текущего пользователя
+func (h *BookingHandlers) CreatePartyBooking(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	var req CreatePartyBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	booking, err := h.service.CreatePartyBooking(userID, req)
+	if err != nil {
+		respondBookingError(c, err, "Failed to create party booking")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    booking,
+		"message": "Party booked, awaiting deposit",
+	})
+}
+
+// CreatePartyRoom добавляет комнату для праздников (администратор)
+func (h *BookingHandlers) CreatePartyRoom(c *gin.Context) {
+	parkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_PARK_ID",
+				"message": "Invalid park ID",
+			},
+		})
+		return
+	}
+
+	var req PartyRoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	result, err := h.service.CreatePartyRoom(parkID, req)
+	if err != nil {
+		respondBookingError(c, err, "Failed to create party room")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    result,
+		"message": "Party room created",
+	})
+}
+
+// UpdatePartyRoom изменяет комнату для праздников (администратор)
+func (h *BookingHandlers) UpdatePartyRoom(c *gin.Context) {
+	parkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_PARK_ID",
+				"message": "Invalid park ID",
+			},
+		})
+		return
+	}
+	roomID, ok := partyRoomIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req PartyRoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	result, err := h.service.UpdatePartyRoom(parkID, roomID, req)
+	if err != nil {
+		respondBookingError(c, err, "Failed to update party room")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+		"message": "Party room updated",
+	})
+}
+
+// CreatePartyPackage добавляет пакет праздника (администратор)
+func (h *BookingHandlers) CreatePartyPackage(c *gin.Context) {
+	parkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_PARK_ID",
+				"message": "Invalid park ID",
+			},
+		})
+		return
+	}
+
+	var req PartyPackageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	result, err := h.service.CreatePartyPackage(parkID, req)
+	if err != nil {
+		respondBookingError(c, err, "Failed to create party package")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    result,
+		"message": "Party package created",
+	})
+}
+
+// UpdatePartyPackage изменяет пакет праздника (администратор)
+func (h *BookingHandlers) UpdatePartyPackage(c *gin.Context) {
+	parkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_PARK_ID",
+				"message": "Invalid park ID",
+			},
+		})
+		return
+	}
+	packageID, ok := partyPackageIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req PartyPackageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	result, err := h.service.UpdatePartyPackage(parkID, packageID, req)
+	if err != nil {
+		respondBookingError(c, err, "Failed to update party package")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+		"message": "Party package updated",
+	})
+}
+
+// DeactivatePartyPackage снимает пакет праздника с продажи (администратор)
+func (h *BookingHandlers) DeactivatePartyPackage(c *gin.Context) {
+	parkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_PARK_ID",
+				"message": "Invalid park ID",
+			},
+		})
+		return
+	}
+	packageID, ok := partyPackageIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeactivatePartyPackage(parkID, packageID); err != nil {
+		respondBookingError(c, err, "Failed to deactivate party package")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Party package deactivated",
+	})
+}
+
+// RecordPartyPayment отмечает оплату по графику платежей праздника (администратор)
+func (h *BookingHandlers) RecordPartyPayment(c *gin.Context) {
+	bookingID, ok := bookingIDParam(c)
+	if !ok {
+		return
+	}
+	adminID, _ := currentUserID(c)
+
+	var req RecordPartyPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	result, err := h.service.RecordPartyPayment(bookingID, adminID, req)
+	if err != nil {
+		respondBookingError(c, err, "Failed to record party payment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+		"message": "Party payment recorded",
+	})
+}
+
+// JoinWaitlist ставит пользователя в лист ожидания заполненного слота
+func (h *BookingHandlers) JoinWaitlist(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	var req JoinWaitlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	entry, err := h.service.JoinWaitlist(userID, req)
+	if err != nil {
+		respondBookingError(c, err, "Failed to join waitlist")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    entry,
+		"message": "Added to the waitlist",
+	})
+}
+
+// GetUserWaitlist возвращает записи текущего пользователя в листах ожидания
+func (h *BookingHandlers) GetUserWaitlist(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	entries, err := h.service.GetUserWaitlist(userID)
+	if err != nil {
+		respondBookingError(c, err, "Failed to get waitlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    entries,
+		"total":   len(entries),
+	})
+}
+
+// LeaveWaitlist снимает запись текущего пользователя из листа ожидания
+func (h *BookingHandlers) LeaveWaitlist(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	entryID, ok := waitlistEntryIDParam(c)
+	if !ok {
+		return
+	}
+
+	entry, err := h.service.LeaveWaitlist(entryID, userID)
+	if err != nil {
+		respondBookingError(c, err, "Failed to leave waitlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    entry,
+		"message": "Removed from the waitlist",
+	})
+}
+
+// ClaimWaitlistOffer принимает предложение мест и создаёт бронирование
+func (h *BookingHandlers) ClaimWaitlistOffer(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	entryID, ok := waitlistEntryIDParam(c)
+	if !ok {
+		return
+	}
+
+	booking, err := h.service.ClaimWaitlistOffer(entryID, userID)
+	if err != nil {
+		respondBookingError(c, err, "Failed to claim waitlist offer")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    booking,
+		"message": "Booking created from waitlist offer",
+	})
+}
+
+// RunSweep запускает уборку неявок и устаревших бронирований (администратор)
+func (h *BookingHandlers) RunSweep(c *gin.Context) {
+	run, err := h.service.Sweep()
+	if err != nil && run == nil {
+		respondBookingError(c, err, "Failed to run booking sweep")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"data":    run,
+			"error": map[string]interface{}{
+				"code":    "SWEEP_FAILED",
+				"message": "Booking sweep failed",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    run,
+		"message": "Booking sweep completed",
+	})
+}
+
+// GetBookingStats возвращает статистику бронирований (администратор).
+// По умолчанию — последние 30 дней по всем паркам с разбивкой по дням.
+func (h *BookingHandlers) GetBookingStats(c *gin.Context) {
+	loc := parkLocation()
+	today := time.Now().In(loc)
+	filter := StatsFilter{
+		From:    time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, -29),
+		To:      time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, loc),
+		GroupBy: c.Query("groupBy"),
+	}
+
+	for param, target := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseInLocation("2006-01-02", value, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": map[string]interface{}{
+					"code":    "INVALID_DATE_RANGE",
+					"message": fmt.Sprintf("%s must be a date in YYYY-MM-DD format", param),
+				},
+			})
+			return
+		}
+		*target = parsed
+	}
+
+	if value := c.Query("parkId"); value != "" {
+		parkID, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": map[string]interface{}{
+					"code":    "INVALID_PARK_ID",
+					"message": "Invalid park ID",
+				},
+			})
+			return
+		}
+		filter.ParkID = &parkID
+	}
+
+	stats, err := h.service.GetBookingStats(filter)
+	if err != nil {
+		respondBookingError(c, err, "Failed to get booking statistics")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    stats,
+	})
+}
+
+// GetSweepRuns возвращает журнал запусков уборки (администратор)
+func (h *BookingHandlers) GetSweepRuns(c *gin.Context) {
+	limit := 20
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": map[string]interface{}{
+					"code":    "INVALID_LIMIT",
+					"message": "limit must be between 1 and 100",
+				},
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	runs, err := h.service.ListSweepRuns(limit)
+	if err != nil {
+		respondBookingError(c, err, "Failed to get booking sweep runs")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    runs,
+		"total":   len(runs),
+	})
+}
+
+// transitionBooking выполняет переход статуса бронирования из параметра :id
+func (h *BookingHandlers) transitionBooking(c *gin.Context, action func(bookingID, actorID uuid.UUID, version *int64) (*models.Booking, error), message string) {
+	bookingID, ok := bookingIDParam(c)
+	if !ok {
+		return
+	}
+	version, ok := etag.ParseIfMatch(c)
+	if !ok {
+		return
+	}
+
+	actorID, _ := currentUserID(c)
+	booking, err := action(bookingID, actorID, version)
+	if err != nil {
+		respondBookingError(c, err, "Failed to update booking status")
+		return
+	}
+
+	etag.Set(c, booking.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    booking,
+		"message": message,
+	})
+}
+
+// bookingIDParam разбирает параметр :id; при ошибке отвечает 400
+func bookingIDParam(c *gin.Context) (uuid.UUID, bool) {
+	bookingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_BOOKING_ID",
+				"message": "Invalid booking ID",
+			},
+		})
+		return uuid.Nil, false
+	}
+	return bookingID, true
+}
+
+// ticketIDParam разбирает параметр :id билета
+func ticketIDParam(c *gin.Context) (uuid.UUID, bool) {
+	ticketID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_TICKET_ID",
+				"message": "Invalid ticket ID",
+			},
+		})
+		return uuid.Nil, false
+	}
+	return ticketID, true
+}
+
+// passProductIDParam разбирает параметр :passId абонемента каталога
+func passProductIDParam(c *gin.Context) (uuid.UUID, bool) {
+	productID, err := uuid.Parse(c.Param("passId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_PASS_PRODUCT_ID",
+				"message": "Invalid pass product ID",
+			},
+		})
+		return uuid.Nil, false
+	}
+	return productID, true
+}
+
+// partyRoomIDParam разбирает параметр :roomId комнаты для праздников
+func partyRoomIDParam(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("roomId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_PARTY_ROOM_ID",
+				"message": "Invalid party room ID",
+			},
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// partyPackageIDParam разбирает параметр :packageId пакета праздника
+func partyPackageIDParam(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("packageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_PARTY_PACKAGE_ID",
+				"message": "Invalid party package ID",
+			},
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// waitlistEntryIDParam разбирает параметр :id записи листа ожидания
+func waitlistEntryIDParam(c *gin.Context) (uuid.UUID, bool) {
+	entryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_WAITLIST_ENTRY_ID",
+				"message": "Invalid waitlist entry ID",
+			},
+		})
+		return uuid.Nil, false
+	}
+	return entryID, true
+}
+
+// currentUserID возвращает ID пользователя, установленный AuthMiddleware
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	value, exists := c.Get("user_id")
+	if !exists {
+		return uuid.Nil, false
+	}
+	userID, ok := value.(uuid.UUID)
+	return userID, ok && userID != uuid.Nil
+}
+
+// isStaff сообщает, что запрос сделан сотрудником парка или администратором
+func isStaff(c *gin.Context) bool {
+	switch models.UserRole(c.GetString("user_role")) {
+	case models.UserRoleStaff, models.UserRoleManager, models.UserRoleAdmin, "super_admin":
+		return true
+	}
+	return false
+}
+
+// respondBookingError отвечает клиенту по ошибке сервиса бронирования.
+// Внутренние ошибки не раскрываются, вместо них возвращается fallbackMessage.
+func respondBookingError(c *gin.Context, err error, fallbackMessage string) {
+	var conflictErr *VersionConflictError
+	if errors.As(err, &conflictErr) {
+		etag.RespondConflict(c, conflictErr.Current, conflictErr.Current.Version)
+		return
+	}
+
+	var deniedErr *AdmissionDeniedError
+	if errors.As(err, &deniedErr) {
+		status := http.StatusUnprocessableEntity
+		switch deniedErr.Reason {
+		case AdmissionTicketNotFound:
+			status = http.StatusNotFound
+		case AdmissionAlreadyUsed:
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    deniedErr.Reason,
+				"message": deniedErr.Error(),
+			},
+			"data": deniedErr.Ticket,
+		})
+		return
+	}
+
+	var transitionErr *TransitionError
+	if errors.As(err, &transitionErr) {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_STATUS_TRANSITION",
+				"message": transitionErr.Error(),
+				"from":    transitionErr.From,
+				"to":      transitionErr.To,
+			},
+		})
+		return
+	}
+
+	status, code := bookingErrorStatus(err)
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		message = fallbackMessage
+	}
+
+	c.JSON(status, gin.H{
+		"success": false,
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": message,
+		},
+	})
+}
+
+// bookingErrorStatus сопоставляет ошибки сервиса с HTTP статусом и кодом ошибки
+func bookingErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, ErrBookingNotFound):
+		return http.StatusNotFound, "BOOKING_NOT_FOUND"
+	case errors.Is(err, ErrTicketNotFound):
+		return http.StatusNotFound, "TICKET_NOT_FOUND"
+	case errors.Is(err, models.ErrInvalidNumber):
+		return http.StatusBadRequest, "INVALID_NUMBER"
+	case errors.Is(err, ErrParkNotFound):
+		return http.StatusNotFound, "PARK_NOT_FOUND"
+	case errors.Is(err, ErrParkUnavailable):
+		return http.StatusUnprocessableEntity, "PARK_UNAVAILABLE"
+	case errors.Is(err, ErrParkClosed):
+		return http.StatusUnprocessableEntity, "PARK_CLOSED"
+	case errors.Is(err, ErrInvalidVisitDate):
+		return http.StatusBadRequest, "INVALID_VISIT_DATE"
+	case errors.Is(err, ErrInvalidTimeSlot):
+		return http.StatusBadRequest, "INVALID_TIME_SLOT"
+	case errors.Is(err, ErrNoGuests), errors.Is(err, ErrInvalidGuest):
+		return http.StatusBadRequest, "INVALID_GUESTS"
+	case errors.Is(err, ErrInvalidContact):
+		return http.StatusBadRequest, "INVALID_CONTACT_INFO"
+	case errors.Is(err, ErrBookingNotModifiable):
+		return http.StatusConflict, "BOOKING_NOT_MODIFIABLE"
+	case errors.Is(err, ErrTooManyLookups):
+		return http.StatusTooManyRequests, "TOO_MANY_ATTEMPTS"
+	case errors.Is(err, ErrSurchargeDue):
+		return http.StatusConflict, "SURCHARGE_DUE"
+	case errors.Is(err, ErrSurchargeNotFound):
+		return http.StatusNotFound, "SURCHARGE_NOT_FOUND"
+	case errors.Is(err, ErrInvalidRefundAmount):
+		return http.StatusBadRequest, "INVALID_REFUND_AMOUNT"
+	case errors.Is(err, ErrInvoiceNotFound):
+		return http.StatusNotFound, "INVOICE_NOT_FOUND"
+	case errors.Is(err, ErrGroupTooSmall):
+		return http.StatusBadRequest, "GROUP_TOO_SMALL"
+	case errors.Is(err, ErrInvalidOrganization):
+		return http.StatusBadRequest, "INVALID_ORGANIZATION"
+	case errors.Is(err, ErrNotAwaitingApproval):
+		return http.StatusConflict, "NOT_AWAITING_APPROVAL"
+	case errors.Is(err, ErrPassProductNotFound):
+		return http.StatusNotFound, "PASS_PRODUCT_NOT_FOUND"
+	case errors.Is(err, ErrInvalidPassProduct):
+		return http.StatusBadRequest, "INVALID_PASS_PRODUCT"
+	case errors.Is(err, ErrInvalidPass):
+		return http.StatusUnprocessableEntity, "INVALID_PASS"
+	case errors.Is(err, ErrPassExhausted):
+		return http.StatusConflict, "PASS_EXHAUSTED"
+	case errors.Is(err, ErrPartyPackageNotFound):
+		return http.StatusNotFound, "PARTY_PACKAGE_NOT_FOUND"
+	case errors.Is(err, ErrPartyRoomNotFound):
+		return http.StatusNotFound, "PARTY_ROOM_NOT_FOUND"
+	case errors.Is(err, ErrInvalidPartyPackage):
+		return http.StatusBadRequest, "INVALID_PARTY_PACKAGE"
+	case errors.Is(err, ErrInvalidPartyAddOn):
+		return http.StatusBadRequest, "INVALID_PARTY_ADD_ON"
+	case errors.Is(err, ErrPartyTooLarge):
+		return http.StatusBadRequest, "PARTY_TOO_LARGE"
+	case errors.Is(err, ErrPartyRoomUnavailable):
+		return http.StatusConflict, "PARTY_ROOM_UNAVAILABLE"
+	case errors.Is(err, ErrNoPaymentDue):
+		return http.StatusConflict, "NO_PAYMENT_DUE"
+	case errors.Is(err, ErrInvalidPaymentMethod):
+		return http.StatusBadRequest, "INVALID_PAYMENT_METHOD"
+	case errors.Is(err, ErrInvalidStatsRange):
+		return http.StatusBadRequest, "INVALID_DATE_RANGE"
+	case errors.Is(err, ErrInvalidManifestCursor):
+		return http.StatusBadRequest, "INVALID_CURSOR"
+	case errors.Is(err, ErrScanBatchTooLarge):
+		return http.StatusRequestEntityTooLarge, "SCAN_BATCH_TOO_LARGE"
+	case errors.Is(err, ErrScanNotFound):
+		return http.StatusNotFound, "SCAN_NOT_FOUND"
+	case errors.Is(err, ErrScanNotConflict):
+		return http.StatusConflict, "SCAN_NOT_CONFLICT"
+	case errors.Is(err, ErrTransfersDisabled):
+		return http.StatusForbidden, "TRANSFERS_DISABLED"
+	case errors.Is(err, ErrTicketNotTransferable):
+		return http.StatusConflict, "TICKET_NOT_TRANSFERABLE"
+	case errors.Is(err, ErrTicketAlreadyValidated):
+		return http.StatusConflict, "TICKET_ALREADY_VALIDATED"
+	case errors.Is(err, ErrInvalidTransferRecipient):
+		return http.StatusBadRequest, "INVALID_RECIPIENT"
+	case errors.Is(err, ErrTransferNotFound):
+		return http.StatusNotFound, "TRANSFER_NOT_FOUND"
+	case errors.Is(err, ErrTransferExpired):
+		return http.StatusGone, "TRANSFER_EXPIRED"
+	case errors.Is(err, ErrInvalidTransferCode):
+		return http.StatusBadRequest, "INVALID_TRANSFER_CODE"
+	case errors.Is(err, ErrTransferredGuestRemoved):
+		return http.StatusConflict, "TICKET_TRANSFERRED"
+	case errors.Is(err, ErrInvalidQRFormat):
+		return http.StatusBadRequest, "INVALID_QR_FORMAT"
+	case errors.Is(err, ErrInvalidQRSize):
+		return http.StatusBadRequest, "INVALID_QR_SIZE"
+	case errors.Is(err, ErrInvalidQRLevel):
+		return http.StatusBadRequest, "INVALID_QR_LEVEL"
+	case errors.Is(err, ErrInvalidPDFLanguage):
+		return http.StatusBadRequest, "INVALID_LANGUAGE"
+	case errors.Is(err, ErrWalletNotConfigured):
+		return http.StatusServiceUnavailable, "WALLET_NOT_CONFIGURED"
+	case errors.Is(err, ErrWalletPassUnavailable):
+		return http.StatusConflict, "WALLET_PASS_UNAVAILABLE"
+	case errors.Is(err, ErrWalletPassNotFound):
+		return http.StatusNotFound, "WALLET_PASS_NOT_FOUND"
+	case errors.Is(err, ErrInvalidWalletToken):
+		return http.StatusUnauthorized, "INVALID_WALLET_TOKEN"
+	case errors.Is(err, ErrInvalidStatsGroupBy):
+		return http.StatusBadRequest, "INVALID_GROUP_BY"
+	case errors.Is(err, ErrWaitlistEntryNotFound):
+		return http.StatusNotFound, "WAITLIST_ENTRY_NOT_FOUND"
+	case errors.Is(err, ErrSlotAvailable):
+		return http.StatusConflict, "SLOT_AVAILABLE"
+	case errors.Is(err, ErrAlreadyWaitlisted):
+		return http.StatusConflict, "ALREADY_WAITLISTED"
+	case errors.Is(err, ErrOfferNotActive):
+		return http.StatusConflict, "OFFER_NOT_ACTIVE"
+	case errors.Is(err, ErrSweepInProgress):
+		return http.StatusConflict, "SWEEP_IN_PROGRESS"
+	case errors.Is(err, ErrInsufficientCapacity):
+		return http.StatusConflict, "INSUFFICIENT_CAPACITY"
+	default:
+		return http.StatusInternalServerError, "DATABASE_ERROR"
+	}
 }
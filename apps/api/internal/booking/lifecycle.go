@@ -0,0 +1,278 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"skypark/internal/models"
+)
+
+// allowedTransitions описывает жизненный цикл бронирования:
+// draft → pending_payment → confirmed → checked_in → completed,
+// а также отмену, возврат и неявку.
+var allowedTransitions = map[models.BookingStatus][]models.BookingStatus{
+	models.BookingStatusDraft:          {models.BookingStatusPendingPayment, models.BookingStatusCancelled},
+	models.BookingStatusPendingPayment: {models.BookingStatusConfirmed, models.BookingStatusCancelled},
+	models.BookingStatusConfirmed: {
+		models.BookingStatusCheckedIn,
+		models.BookingStatusCancelled,
+		models.BookingStatusRefunded,
+		models.BookingStatusNoShow,
+	},
+	models.BookingStatusCheckedIn: {models.BookingStatusCompleted},
+	models.BookingStatusCancelled: {models.BookingStatusRefunded},
+	models.BookingStatusCompleted: {},
+	models.BookingStatusRefunded:  {},
+	models.BookingStatusNoShow:    {},
+}
+
+// systemActor — исполнитель переходов, выполняемых фоновыми задачами
+var systemActor = uuid.Nil
+
+// TransitionError возвращается при попытке недопустимой смены статуса
+type TransitionError struct {
+	BookingID uuid.UUID
+	From      models.BookingStatus
+	To        models.BookingStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("booking %s cannot move from %s to %s", e.BookingID, e.From, e.To)
+}
+
+// VersionConflictError возвращается, если бронирование изменили после того,
+// как клиент получил его версию (заголовок If-Match)
+type VersionConflictError struct {
+	Current *models.Booking
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("booking %s was changed, current version is %d", e.Current.ID, e.Current.Version)
+}
+
+// StatusChange — запись истории статусов в Booking.Metadata["statusHistory"]
+type StatusChange struct {
+	From   models.BookingStatus `json:"from"`
+	To     models.BookingStatus `json:"to"`
+	At     time.Time            `json:"at"`
+	By     uuid.UUID            `json:"by"`
+	Reason string               `json:"reason,omitempty"`
+}
+
+// CanTransition сообщает, допустим ли переход между статусами
+func CanTransition(from, to models.BookingStatus) bool {
+	for _, allowed := range allowedTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// loadBookingForUpdate загружает бронирование с блокировкой строки
+func loadBookingForUpdate(tx *gorm.DB, bookingID uuid.UUID) (*models.Booking, error) {
+	var booking models.Booking
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND deleted_at IS NULL", bookingID).
+		First(&booking).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookingNotFound
+		}
+		return nil, err
+	}
+	return &booking, nil
+}
+
+// checkVersion сверяет версию заблокированного бронирования с версией,
+// которую видел клиент. Без версии изменение выполняется без проверки.
+func checkVersion(booking *models.Booking, version *int64) error {
+	if version != nil && *version != booking.Version {
+		return &VersionConflictError{Current: booking}
+	}
+	return nil
+}
+
+// reloadVersion перечитывает версию бронирования: её увеличивает триггер
+// при каждом изменении строки
+func reloadVersion(tx *gorm.DB, booking *models.Booking) error {
+	return tx.Model(&models.Booking{}).Select("version").Where("id = ?", booking.ID).Scan(&booking.Version).Error
+}
+
+// transition переводит бронирование в статус to внутри транзакции tx.
+// Повторный перевод в текущий статус ничего не меняет и возвращает false.
+func (s *BookingService) transition(tx *gorm.DB, booking *models.Booking, to models.BookingStatus, actorID uuid.UUID, reason string) (bool, error) {
+	from := booking.Status
+	if from == to {
+		return false, nil
+	}
+	if !CanTransition(from, to) {
+		return false, &TransitionError{BookingID: booking.ID, From: from, To: to}
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status": to,
+	}
+
+	switch to {
+	case models.BookingStatusPendingPayment:
+		if err := s.holdSpots(tx, booking); err != nil {
+			return false, err
+		}
+		updates["hold_expires_at"] = booking.HoldExpiresAt
+	case models.BookingStatusConfirmed:
+		// Если удержание истекло, места могли занять — проверяем заново
+		if consumesCapacity(booking) && (booking.HoldExpiresAt == nil || !booking.HoldExpiresAt.After(now)) {
+			if _, err := s.checkSpots(tx, booking); err != nil {
+				return false, err
+			}
+		}
+		booking.ConfirmedAt = &now
+		booking.HoldExpiresAt = nil
+		updates["confirmed_at"] = now
+		updates["hold_expires_at"] = nil
+	case models.BookingStatusCheckedIn:
+		booking.CheckedInAt = &now
+		updates["checked_in_at"] = now
+	case models.BookingStatusCompleted:
+		booking.CompletedAt = &now
+		updates["completed_at"] = now
+	case models.BookingStatusCancelled:
+		booking.CancelledAt = &now
+		booking.HoldExpiresAt = nil
+		updates["cancelled_at"] = now
+		updates["hold_expires_at"] = nil
+		if actorID != systemActor {
+			booking.CancelledBy = &actorID
+			updates["cancelled_by"] = actorID
+		}
+		if reason != "" {
+			booking.CancellationReason = &reason
+			updates["cancellation_reason"] = reason
+		}
+	case models.BookingStatusRefunded:
+		booking.RefundedAt = &now
+		updates["refunded_at"] = now
+	}
+
+	booking.Metadata = appendStatusHistory(booking.Metadata, StatusChange{
+		From:   from,
+		To:     to,
+		At:     now,
+		By:     actorID,
+		Reason: reason,
+	})
+	updates["metadata"] = booking.Metadata
+	booking.Status = to
+
+	if err := tx.Model(&models.Booking{}).Where("id = ?", booking.ID).Updates(updates).Error; err != nil {
+		return false, fmt.Errorf("failed to update booking status: %w", err)
+	}
+	if err := syncBookingTickets(tx, booking, to, actorID, now); err != nil {
+		return false, err
+	}
+	if err := syncPassTickets(tx, booking, to, actorID, now); err != nil {
+		return false, err
+	}
+	if to == models.BookingStatusConfirmed {
+		if err := s.issueTickets(tx, booking, now); err != nil {
+			return false, err
+		}
+	}
+	if err := releasePartyRoom(tx, booking, to, now); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// consumesCapacity сообщает, занимает ли бронирование места в слоте.
+// Покупка абонемента мест не занимает — их занимают бронирования визитов по нему.
+func consumesCapacity(booking *models.Booking) bool {
+	return booking.BookingType != models.BookingTypePass
+}
+
+// holdSpots проверяет вместимость слота и удерживает места на время оплаты:
+// HoldTTL, а для групповых бронирований — до срока оплаты счёта
+func (s *BookingService) holdSpots(tx *gorm.DB, booking *models.Booking) error {
+	if !consumesCapacity(booking) {
+		return nil
+	}
+
+	park, err := s.checkSpots(tx, booking)
+	if err != nil {
+		return err
+	}
+
+	holdExpiresAt := time.Now().Add(s.config.HoldTTL)
+	if booking.BookingType == models.BookingTypeGroup {
+		holdExpiresAt = invoiceDueDate(park, booking, time.Now())
+	}
+	booking.HoldExpiresAt = &holdExpiresAt
+	return nil
+}
+
+// checkSpots проверяет, что в слоте бронирования хватает мест для его гостей
+func (s *BookingService) checkSpots(tx *gorm.DB, booking *models.Booking) (*models.Park, error) {
+	if booking.TimeSlot == nil {
+		return nil, ErrInvalidTimeSlot
+	}
+
+	var park models.Park
+	if err := tx.Where("id = ?", booking.ParkID).First(&park).Error; err != nil {
+		return nil, err
+	}
+	if err := reserveCapacity(tx, &park, booking.VisitDate, *booking.TimeSlot, booking.TotalGuests, booking.ID); err != nil {
+		return nil, err
+	}
+	return &park, nil
+}
+
+// appendStatusHistory добавляет запись в историю статусов метаданных
+func appendStatusHistory(metadata models.JSONB, change interface{}) models.JSONB {
+	if metadata == nil {
+		metadata = models.JSONB{}
+	}
+	history, _ := metadata["statusHistory"].([]interface{})
+	metadata["statusHistory"] = append(history, change)
+	return metadata
+}
+
+// TransitionBooking переводит бронирование в новый статус от имени actorID.
+// Если задана version, бронирование не должно было меняться с этой версии.
+func (s *BookingService) TransitionBooking(bookingID uuid.UUID, to models.BookingStatus, actorID uuid.UUID, reason string, version *int64) (*models.Booking, error) {
+	var booking *models.Booking
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		booking, err = loadBookingForUpdate(tx, bookingID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(booking, version); err != nil {
+			return err
+		}
+		if _, err = s.transition(tx, booking, to, actorID, reason); err != nil {
+			return err
+		}
+		return reloadVersion(tx, booking)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch to {
+	case models.BookingStatusCancelled, models.BookingStatusNoShow:
+		s.CapacityReleased(booking.ParkID)
+	default:
+		if err := s.RefreshParkCapacity(booking.ParkID); err != nil {
+			log.Printf("⚠️ Failed to refresh capacity for park %s: %v", booking.ParkID, err)
+		}
+	}
+	return booking, nil
+}
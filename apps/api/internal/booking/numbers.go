@@ -0,0 +1,187 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"skypark/internal/models"
+)
+
+// GetBooking возвращает бронирование по идентификатору
+func (s *BookingService) GetBooking(bookingID uuid.UUID) (*models.Booking, error) {
+	var booking models.Booking
+	err := s.db.Where("id = ? AND deleted_at IS NULL", bookingID).
+		Preload("Park").
+		Preload("Tickets", "deleted_at IS NULL").
+		First(&booking).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookingNotFound
+		}
+		return nil, err
+	}
+	hideTransferredQRCodes(&booking)
+	return &booking, nil
+}
+
+// GetBookingByNumber ищет бронирование по короткому номеру. Номер может быть
+// введён в любом регистре, с пробелами или без дефисов.
+func (s *BookingService) GetBookingByNumber(number string) (*models.Booking, error) {
+	canonical, err := models.ParseBookingNumber(number)
+	if err != nil {
+		return nil, err
+	}
+
+	var booking models.Booking
+	err = s.db.Where("booking_number = ? AND deleted_at IS NULL", canonical).
+		Preload("Park").
+		Preload("Tickets", "deleted_at IS NULL").
+		First(&booking).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookingNotFound
+		}
+		return nil, err
+	}
+	hideTransferredQRCodes(&booking)
+	return &booking, nil
+}
+
+// LookupGuestBooking ищет бронирование по номеру и телефону, указанному в
+// контактах бронирования или в учётной записи, для гостей без входа в
+// приложение. Если телефон не совпадает, бронирование не раскрывается.
+// Номер и телефон не секретны, поэтому билеты с QR-кодами в ответ не
+// попадают, а число попыток с адреса clientIP и на телефон ограничено.
+func (s *BookingService) LookupGuestBooking(phone, number, clientIP string) (*models.Booking, error) {
+	now := time.Now()
+	if !s.lookups.allow("ip:"+clientIP, s.config.LookupsPerIP, now) {
+		return nil, ErrTooManyLookups
+	}
+	phone = normalizePhone(phone)
+	if phone == "" {
+		return nil, ErrBookingNotFound
+	}
+	if !s.lookups.allow("phone:"+phone, s.config.LookupsPerPhone, now) {
+		return nil, ErrTooManyLookups
+	}
+
+	booking, err := s.GetBookingByNumber(number)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidNumber) {
+			return nil, ErrBookingNotFound
+		}
+		return nil, err
+	}
+	booking.Tickets = nil
+	if normalizePhone(booking.ContactInfo.PhoneNumber) == phone {
+		return booking, nil
+	}
+
+	var user models.User
+	if err := s.db.Select("phone_number").Where("id = ?", booking.UserID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookingNotFound
+		}
+		return nil, err
+	}
+	if normalizePhone(user.PhoneNumber) != phone {
+		return nil, ErrBookingNotFound
+	}
+	return booking, nil
+}
+
+// normalizePhone приводит номер, введённый человеком, к виду +996XXXXXXXXX:
+// убирает пробелы, скобки и дефисы и дописывает код страны
+func normalizePhone(phone string) string {
+	var digits strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	number := digits.String()
+	switch {
+	case number == "":
+		return ""
+	case len(number) == 10 && strings.HasPrefix(number, "0"):
+		// Местный формат: 0XXX XXX XXX
+		number = "996" + number[1:]
+	case len(number) == 9:
+		number = "996" + number
+	}
+	return "+" + number
+}
+
+// GetTicket возвращает билет по ID
+func (s *BookingService) GetTicket(ticketID uuid.UUID) (*models.Ticket, error) {
+	var ticket models.Ticket
+	err := s.db.Where("id = ? AND deleted_at IS NULL", ticketID).First(&ticket).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTicketNotFound
+		}
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// GetTicketByNumber ищет билет по короткому номеру
+func (s *BookingService) GetTicketByNumber(number string) (*models.Ticket, error) {
+	canonical, err := models.ParseTicketNumber(number)
+	if err != nil {
+		return nil, err
+	}
+
+	var ticket models.Ticket
+	err = s.db.Where("ticket_number = ? AND deleted_at IS NULL", canonical).
+		Preload("Park").
+		First(&ticket).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTicketNotFound
+		}
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// AssignMissingNumbers выдаёт номера бронированиям и билетам, созданным до
+// появления коротких номеров. Возвращает количество обновлённых записей.
+func (s *BookingService) AssignMissingNumbers() (int, error) {
+	assigned := 0
+
+	var bookingIDs []string
+	if err := s.db.Model(&models.Booking{}).Where("booking_number IS NULL").Pluck("id", &bookingIDs).Error; err != nil {
+		return assigned, err
+	}
+	for _, id := range bookingIDs {
+		ok, err := models.AssignBookingNumber(s.db, id)
+		if err != nil {
+			return assigned, fmt.Errorf("failed to assign booking number: %w", err)
+		}
+		if ok {
+			assigned++
+		}
+	}
+
+	var ticketIDs []string
+	if err := s.db.Model(&models.Ticket{}).Where("ticket_number IS NULL").Pluck("id", &ticketIDs).Error; err != nil {
+		return assigned, err
+	}
+	for _, id := range ticketIDs {
+		ok, err := models.AssignTicketNumber(s.db, id)
+		if err != nil {
+			return assigned, fmt.Errorf("failed to assign ticket number: %w", err)
+		}
+		if ok {
+			assigned++
+		}
+	}
+
+	return assigned, nil
+}
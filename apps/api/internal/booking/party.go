@@ -0,0 +1,730 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"skypark/internal/models"
+)
+
+// defaultDepositPercent — депозит за праздник, если пакет не задаёт свой
+const defaultDepositPercent = 30
+
+// PartyRoomRequest описывает комнату или зону для праздников (администратор)
+type PartyRoomRequest struct {
+	Name        string  `json:"name" binding:"required"`
+	Description *string `json:"description,omitempty"`
+	MaxGuests   int     `json:"maxGuests" binding:"required"`
+	IsActive    *bool   `json:"isActive,omitempty"`
+}
+
+// PartyPackageRequest описывает пакет праздника (администратор)
+type PartyPackageRequest struct {
+	RoomID          uuid.UUID           `json:"roomId" binding:"required"`
+	Name            string              `json:"name" binding:"required"`
+	Description     *string             `json:"description,omitempty"`
+	DurationMinutes int                 `json:"durationMinutes" binding:"required"`
+	Price           float64             `json:"price"`
+	IncludedGuests  int                 `json:"includedGuests" binding:"required"`
+	ExtraGuestPrice float64             `json:"extraGuestPrice"`
+	AddOns          []models.PartyAddOn `json:"addOns,omitempty"`
+	DepositPercent  *float64            `json:"depositPercent,omitempty"`
+	BalanceDueHours int                 `json:"balanceDueHours"`
+	IsActive        *bool               `json:"isActive,omitempty"`
+}
+
+// PartyAddOnRequest — дополнительная услуга, заказанная к празднику
+type PartyAddOnRequest struct {
+	Code     string `json:"code" binding:"required"`
+	Quantity int    `json:"quantity"`
+}
+
+// CreatePartyBookingRequest описывает бронирование праздника. Гости, которых
+// нет в Guests, добавляются как дети без имени.
+type CreatePartyBookingRequest struct {
+	PackageID     uuid.UUID            `json:"packageId" binding:"required"`
+	VisitDate     string               `json:"visitDate" binding:"required"` // YYYY-MM-DD
+	TimeSlot      string               `json:"timeSlot" binding:"required"`  // HH:MM
+	GuestCount    int                  `json:"guestCount" binding:"required"`
+	Guests        []models.GuestInfo   `json:"guests,omitempty"`
+	AddOns        []PartyAddOnRequest  `json:"addOns,omitempty"`
+	CelebrantName *string              `json:"celebrantName,omitempty"`
+	CelebrantAge  *int                 `json:"celebrantAge,omitempty"`
+	ContactInfo   models.ContactInfo   `json:"contactInfo" binding:"required"`
+	Source        models.BookingSource `json:"source,omitempty"`
+	Notes         *string              `json:"notes,omitempty"`
+}
+
+// RecordPartyPaymentRequest описывает оплату очередного платежа по графику.
+// PayInFull оплачивает сразу весь остаток.
+type RecordPartyPaymentRequest struct {
+	Method    models.PaymentMethod `json:"method,omitempty"` // по умолчанию cash
+	Reference *string              `json:"reference,omitempty"`
+	PayInFull bool                 `json:"payInFull,omitempty"`
+}
+
+// PartyPaymentResult описывает бронирование праздника и проведённый платёж
+type PartyPaymentResult struct {
+	Booking *models.Booking `json:"booking"`
+	Payment *models.Payment `json:"payment"`
+}
+
+// PartyRoomCalendar — занятость комнаты для праздников на дату
+type PartyRoomCalendar struct {
+	Room         models.PartyRoom              `json:"room"`
+	Reservations []models.PartyRoomReservation `json:"reservations"`
+}
+
+// recordablePartyMethods — способы оплаты, которые администратор может отметить вручную
+var recordablePartyMethods = map[models.PaymentMethod]bool{
+	models.PaymentMethodCash:         true,
+	models.PaymentMethodBankCard:     true,
+	models.PaymentMethodBankTransfer: true,
+	models.PaymentMethodELQR:         true,
+	models.PaymentMethodElcart:       true,
+	models.PaymentMethodMBank:        true,
+	models.PaymentMethodODengi:       true,
+}
+
+// GetPartyPackages возвращает пакеты праздников парка вместе с комнатами
+func (s *BookingService) GetPartyPackages(parkID uuid.UUID) ([]models.PartyPackage, error) {
+	var packages []models.PartyPackage
+	err := s.db.Where("park_id = ? AND is_active = ? AND deleted_at IS NULL", parkID, true).
+		Preload("Room").
+		Order("price ASC").
+		Find(&packages).Error
+	if err != nil {
+		return nil, err
+	}
+	return packages, nil
+}
+
+// GetPartyRoomCalendar возвращает занятость комнат парка для праздников на дату
+func (s *BookingService) GetPartyRoomCalendar(parkID uuid.UUID, date string) ([]PartyRoomCalendar, error) {
+	loc := parkLocation()
+	day, err := time.ParseInLocation("2006-01-02", date, loc)
+	if err != nil {
+		return nil, ErrInvalidVisitDate
+	}
+
+	var rooms []models.PartyRoom
+	if err := s.db.Where("park_id = ? AND is_active = ? AND deleted_at IS NULL", parkID, true).
+		Order("name ASC").
+		Find(&rooms).Error; err != nil {
+		return nil, err
+	}
+
+	calendar := make([]PartyRoomCalendar, 0, len(rooms))
+	for _, room := range rooms {
+		var reservations []models.PartyRoomReservation
+		if err := s.db.Where("room_id = ? AND released_at IS NULL AND starts_at < ? AND ends_at > ?",
+			room.ID, day.AddDate(0, 0, 1), day).
+			Order("starts_at ASC").
+			Find(&reservations).Error; err != nil {
+			return nil, err
+		}
+		calendar = append(calendar, PartyRoomCalendar{Room: room, Reservations: reservations})
+	}
+	return calendar, nil
+}
+
+// CreatePartyRoom добавляет комнату для праздников (администратор)
+func (s *BookingService) CreatePartyRoom(parkID uuid.UUID, req PartyRoomRequest) (*models.PartyRoom, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || req.MaxGuests < 1 {
+		return nil, ErrInvalidPartyPackage
+	}
+	if err := s.ensurePark(parkID); err != nil {
+		return nil, err
+	}
+
+	room := models.PartyRoom{
+		ParkID:      parkID,
+		Name:        req.Name,
+		Description: req.Description,
+		MaxGuests:   req.MaxGuests,
+		IsActive:    true,
+	}
+	if err := s.db.Create(&room).Error; err != nil {
+		return nil, fmt.Errorf("failed to create party room: %w", err)
+	}
+	if req.IsActive != nil && !*req.IsActive {
+		room.IsActive = false
+		if err := s.db.Model(&room).Update("is_active", false).Error; err != nil {
+			return nil, fmt.Errorf("failed to update party room: %w", err)
+		}
+	}
+	return &room, nil
+}
+
+// UpdatePartyRoom изменяет комнату для праздников (администратор)
+func (s *BookingService) UpdatePartyRoom(parkID, roomID uuid.UUID, req PartyRoomRequest) (*models.PartyRoom, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || req.MaxGuests < 1 {
+		return nil, ErrInvalidPartyPackage
+	}
+
+	room, err := findPartyRoom(s.db, parkID, roomID)
+	if err != nil {
+		return nil, err
+	}
+	room.Name = req.Name
+	room.Description = req.Description
+	room.MaxGuests = req.MaxGuests
+	if req.IsActive != nil {
+		room.IsActive = *req.IsActive
+	}
+
+	if err := s.db.Model(room).
+		Select("name", "description", "max_guests", "is_active").
+		Updates(room).Error; err != nil {
+		return nil, fmt.Errorf("failed to update party room: %w", err)
+	}
+	return room, nil
+}
+
+// CreatePartyPackage добавляет пакет праздника (администратор)
+func (s *BookingService) CreatePartyPackage(parkID uuid.UUID, req PartyPackageRequest) (*models.PartyPackage, error) {
+	if err := validatePartyPackage(&req); err != nil {
+		return nil, err
+	}
+	room, err := findPartyRoom(s.db, parkID, req.RoomID)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := models.PartyPackage{
+		ParkID:   parkID,
+		RoomID:   room.ID,
+		Currency: "KGS",
+		IsActive: true,
+	}
+	applyPartyPackageRequest(&pkg, req)
+	if err := s.db.Create(&pkg).Error; err != nil {
+		return nil, fmt.Errorf("failed to create party package: %w", err)
+	}
+	if req.IsActive != nil && !*req.IsActive {
+		pkg.IsActive = false
+		if err := s.db.Model(&pkg).Update("is_active", false).Error; err != nil {
+			return nil, fmt.Errorf("failed to update party package: %w", err)
+		}
+	}
+	pkg.Room = room
+	return &pkg, nil
+}
+
+// UpdatePartyPackage изменяет пакет праздника. Уже созданные бронирования
+// сохраняют цены, действовавшие при бронировании.
+func (s *BookingService) UpdatePartyPackage(parkID, packageID uuid.UUID, req PartyPackageRequest) (*models.PartyPackage, error) {
+	if err := validatePartyPackage(&req); err != nil {
+		return nil, err
+	}
+	room, err := findPartyRoom(s.db, parkID, req.RoomID)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := findPartyPackage(s.db, parkID, packageID)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg.RoomID = room.ID
+	applyPartyPackageRequest(pkg, req)
+	if req.IsActive != nil {
+		pkg.IsActive = *req.IsActive
+	}
+	if err := s.db.Model(pkg).
+		Select("room_id", "name", "description", "duration_minutes", "price", "included_guests",
+			"extra_guest_price", "add_ons", "deposit_percent", "balance_due_hours", "is_active").
+		Updates(pkg).Error; err != nil {
+		return nil, fmt.Errorf("failed to update party package: %w", err)
+	}
+	pkg.Room = room
+	return pkg, nil
+}
+
+// DeactivatePartyPackage снимает пакет праздника с продажи
+func (s *BookingService) DeactivatePartyPackage(parkID, packageID uuid.UUID) error {
+	pkg, err := findPartyPackage(s.db, parkID, packageID)
+	if err != nil {
+		return err
+	}
+	return s.db.Model(pkg).Update("is_active", false).Error
+}
+
+func (s *BookingService) ensurePark(parkID uuid.UUID) error {
+	var park models.Park
+	if err := s.db.Where("id = ? AND deleted_at IS NULL", parkID).Select("id").First(&park).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrParkNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func findPartyRoom(tx *gorm.DB, parkID, roomID uuid.UUID) (*models.PartyRoom, error) {
+	var room models.PartyRoom
+	err := tx.Where("id = ? AND park_id = ? AND deleted_at IS NULL", roomID, parkID).First(&room).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPartyRoomNotFound
+		}
+		return nil, err
+	}
+	return &room, nil
+}
+
+func findPartyPackage(tx *gorm.DB, parkID, packageID uuid.UUID) (*models.PartyPackage, error) {
+	var pkg models.PartyPackage
+	query := tx.Where("id = ? AND deleted_at IS NULL", packageID)
+	if parkID != uuid.Nil {
+		query = query.Where("park_id = ?", parkID)
+	}
+	if err := query.First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPartyPackageNotFound
+		}
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+func validatePartyPackage(req *PartyPackageRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || req.DurationMinutes < 1 || req.IncludedGuests < 1 ||
+		req.Price < 0 || req.ExtraGuestPrice < 0 || req.BalanceDueHours < 0 {
+		return ErrInvalidPartyPackage
+	}
+	if req.DepositPercent != nil && (*req.DepositPercent < 0 || *req.DepositPercent > 100) {
+		return ErrInvalidPartyPackage
+	}
+
+	codes := map[string]bool{}
+	for i := range req.AddOns {
+		addOn := &req.AddOns[i]
+		addOn.Code = strings.TrimSpace(addOn.Code)
+		addOn.Name = strings.TrimSpace(addOn.Name)
+		if addOn.Code == "" || addOn.Name == "" || addOn.Price < 0 || codes[addOn.Code] {
+			return ErrInvalidPartyPackage
+		}
+		codes[addOn.Code] = true
+		addOn.Price = roundMoney(addOn.Price)
+	}
+	return nil
+}
+
+func applyPartyPackageRequest(pkg *models.PartyPackage, req PartyPackageRequest) {
+	pkg.Name = req.Name
+	pkg.Description = req.Description
+	pkg.DurationMinutes = req.DurationMinutes
+	pkg.Price = roundMoney(req.Price)
+	pkg.IncludedGuests = req.IncludedGuests
+	pkg.ExtraGuestPrice = roundMoney(req.ExtraGuestPrice)
+	pkg.AddOns = req.AddOns
+	if pkg.AddOns == nil {
+		pkg.AddOns = []models.PartyAddOn{}
+	}
+	pkg.DepositPercent = defaultDepositPercent
+	if req.DepositPercent != nil {
+		pkg.DepositPercent = *req.DepositPercent
+	}
+	pkg.BalanceDueHours = req.BalanceDueHours
+}
+
+// CreatePartyBooking бронирует праздник: комнату пакета на его длительность
+// и места в слоте парка для всех гостей. Стоимость складывается из цены
+// пакета, доплаты за гостей сверх включённых и дополнительных услуг.
+// Оплата делится на депозит, который нужно внести в течение
+// PartyDepositWindow, и остаток к сроку пакета.
+func (s *BookingService) CreatePartyBooking(userID uuid.UUID, req CreatePartyBookingRequest) (*models.Booking, error) {
+	pkg, err := findPartyPackage(s.db, uuid.Nil, req.PackageID)
+	if err != nil {
+		return nil, err
+	}
+	if !pkg.IsActive {
+		return nil, ErrPartyPackageNotFound
+	}
+
+	guestCount := req.GuestCount
+	if len(req.Guests) > guestCount {
+		guestCount = len(req.Guests)
+	}
+	if guestCount < 1 {
+		return nil, ErrNoGuests
+	}
+	guests := make([]models.GuestInfo, 0, guestCount)
+	for _, guest := range req.Guests {
+		guest.PassTicketID = nil
+		guests = append(guests, guest)
+	}
+	for i := len(guests); i < guestCount; i++ {
+		guests = append(guests, models.GuestInfo{
+			Name:        fmt.Sprintf("Гость %d", i+1),
+			AgeCategory: models.AgeCategoryChild,
+		})
+	}
+
+	addOns, err := partyAddOnLines(pkg, req.AddOns)
+	if err != nil {
+		return nil, err
+	}
+
+	bookingReq := CreateBookingRequest{
+		ParkID:      pkg.ParkID,
+		VisitDate:   req.VisitDate,
+		TimeSlot:    req.TimeSlot,
+		ContactInfo: req.ContactInfo,
+		Guests:      guests,
+		Source:      req.Source,
+		Notes:       req.Notes,
+	}
+
+	var reservation models.PartyRoomReservation
+	return s.createBooking(userID, bookingReq, createOptions{
+		prepare: func(tx *gorm.DB, park *models.Park, booking *models.Booking) error {
+			room, err := lockPartyRoom(tx, pkg.ParkID, pkg.RoomID)
+			if err != nil {
+				return err
+			}
+			if booking.TotalGuests > room.MaxGuests {
+				return fmt.Errorf("%w: room fits %d guests", ErrPartyTooLarge, room.MaxGuests)
+			}
+
+			startsAt := visitStart(booking)
+			endsAt := startsAt.Add(time.Duration(pkg.DurationMinutes) * time.Minute)
+			if err := checkPartyRoomFree(tx, room.ID, startsAt, endsAt); err != nil {
+				return err
+			}
+
+			booking.BookingType = models.BookingTypeParty
+			booking.Duration = pkg.DurationMinutes
+			booking.Party = &models.PartyDetails{
+				PackageID:       pkg.ID,
+				PackageName:     pkg.Name,
+				RoomID:          room.ID,
+				RoomName:        room.Name,
+				StartsAt:        startsAt,
+				EndsAt:          endsAt,
+				PackagePrice:    pkg.Price,
+				IncludedGuests:  pkg.IncludedGuests,
+				ExtraGuestPrice: pkg.ExtraGuestPrice,
+				AddOns:          addOns,
+				CelebrantName:   req.CelebrantName,
+				CelebrantAge:    req.CelebrantAge,
+			}
+			applyPartyPricing(booking)
+			applyTotals(booking)
+
+			booking.Status = models.BookingStatusPendingPayment
+			booking.PaymentSchedule = partyPaymentSchedule(pkg, booking, time.Now(), s.config.PartyDepositWindow)
+			if len(booking.PaymentSchedule) > 0 {
+				// Комната и места держатся до срока первого платежа
+				firstDue := booking.PaymentSchedule[0].DueAt
+				booking.HoldExpiresAt = &firstDue
+			}
+
+			reservation = models.PartyRoomReservation{
+				RoomID:    room.ID,
+				BookingID: booking.ID,
+				StartsAt:  startsAt,
+				EndsAt:    endsAt,
+			}
+			return nil
+		},
+		created: func(tx *gorm.DB, park *models.Park, booking *models.Booking) error {
+			if err := tx.Create(&reservation).Error; err != nil {
+				return fmt.Errorf("failed to reserve party room: %w", err)
+			}
+			return nil
+		},
+	})
+}
+
+// partyAddOnLines сопоставляет заказанные услуги с услугами пакета
+func partyAddOnLines(pkg *models.PartyPackage, requested []PartyAddOnRequest) ([]models.PartyAddOnLine, error) {
+	lines := make([]models.PartyAddOnLine, 0, len(requested))
+	for _, req := range requested {
+		quantity := req.Quantity
+		if quantity == 0 {
+			quantity = 1
+		}
+		if quantity < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPartyAddOn, req.Code)
+		}
+
+		var addOn *models.PartyAddOn
+		for i := range pkg.AddOns {
+			if pkg.AddOns[i].Code == req.Code {
+				addOn = &pkg.AddOns[i]
+				break
+			}
+		}
+		if addOn == nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPartyAddOn, req.Code)
+		}
+
+		lines = append(lines, models.PartyAddOnLine{
+			Code:      addOn.Code,
+			Name:      addOn.Name,
+			Quantity:  quantity,
+			UnitPrice: addOn.Price,
+			Total:     roundMoney(addOn.Price * float64(quantity)),
+		})
+	}
+	return lines, nil
+}
+
+// applyPartyPricing оценивает гостей праздника: включённые в пакет гости
+// бесплатны, остальные оплачиваются по цене пакета за гостя
+func applyPartyPricing(booking *models.Booking) {
+	party := booking.Party
+	party.ExtraGuests = 0
+	for i := range booking.Items {
+		item := &booking.Items[i]
+		price := 0.0
+		if i >= party.IncludedGuests {
+			price = party.ExtraGuestPrice
+			party.ExtraGuests++
+		}
+		item.BasePrice = price
+		item.DiscountAmount = 0
+		item.FinalPrice = price
+	}
+}
+
+// partyExtrasTotal возвращает стоимость пакета и услуг праздника сверх
+// позиций гостей
+func partyExtrasTotal(party *models.PartyDetails) float64 {
+	if party == nil {
+		return 0
+	}
+	total := party.PackagePrice
+	for _, addOn := range party.AddOns {
+		total += addOn.Total
+	}
+	return total
+}
+
+// partyPaymentSchedule делит стоимость праздника на депозит и остаток.
+// Депозит нужно внести в течение depositWindow, остаток — за BalanceDueHours
+// до начала праздника; оба срока не позже начала. Нулевые платежи в график
+// не попадают.
+func partyPaymentSchedule(pkg *models.PartyPackage, booking *models.Booking, now time.Time, depositWindow time.Duration) []models.ScheduledPayment {
+	startsAt := booking.Party.StartsAt
+	depositDue := now.Add(depositWindow)
+	if depositDue.After(startsAt) {
+		depositDue = startsAt
+	}
+	balanceDue := startsAt.Add(-time.Duration(pkg.BalanceDueHours) * time.Hour)
+	if balanceDue.Before(depositDue) {
+		balanceDue = depositDue
+	}
+
+	deposit := roundMoney(booking.TotalAmount * pkg.DepositPercent / 100)
+	balance := roundMoney(booking.TotalAmount - deposit)
+
+	schedule := []models.ScheduledPayment{}
+	if deposit > 0 {
+		schedule = append(schedule, models.ScheduledPayment{
+			Type:   models.ScheduledPaymentDeposit,
+			Amount: deposit,
+			DueAt:  depositDue,
+			Status: models.PaymentStatusPending,
+		})
+	}
+	if balance > 0 {
+		dueAt := balanceDue
+		if deposit <= 0 {
+			// Без депозита остаток держит комнату, как депозит
+			dueAt = depositDue
+		}
+		schedule = append(schedule, models.ScheduledPayment{
+			Type:   models.ScheduledPaymentBalance,
+			Amount: balance,
+			DueAt:  dueAt,
+			Status: models.PaymentStatusPending,
+		})
+	}
+	return schedule
+}
+
+// lockPartyRoom загружает комнату с блокировкой строки, чтобы проверки
+// занятости одной комнаты выполнялись последовательно
+func lockPartyRoom(tx *gorm.DB, parkID, roomID uuid.UUID) (*models.PartyRoom, error) {
+	var room models.PartyRoom
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND park_id = ? AND is_active = ? AND deleted_at IS NULL", roomID, parkID, true).
+		First(&room).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPartyRoomNotFound
+		}
+		return nil, err
+	}
+	return &room, nil
+}
+
+// checkPartyRoomFree проверяет, что комната свободна в интервале [startsAt, endsAt).
+// Ограничение-исключение в таблице остаётся последней защитой от пересечений.
+func checkPartyRoomFree(tx *gorm.DB, roomID uuid.UUID, startsAt, endsAt time.Time) error {
+	var count int64
+	err := tx.Model(&models.PartyRoomReservation{}).
+		Where("room_id = ? AND released_at IS NULL AND starts_at < ? AND ends_at > ?", roomID, endsAt, startsAt).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrPartyRoomUnavailable
+	}
+	return nil
+}
+
+// releasePartyRoom освобождает комнату праздника, если бронирование отменено
+// или гости не пришли
+func releasePartyRoom(tx *gorm.DB, booking *models.Booking, to models.BookingStatus, now time.Time) error {
+	if booking.BookingType != models.BookingTypeParty {
+		return nil
+	}
+	switch to {
+	case models.BookingStatusCancelled, models.BookingStatusNoShow:
+	default:
+		return nil
+	}
+
+	err := tx.Model(&models.PartyRoomReservation{}).
+		Where("booking_id = ? AND released_at IS NULL", booking.ID).
+		Update("released_at", now).Error
+	if err != nil {
+		return fmt.Errorf("failed to release party room: %w", err)
+	}
+	return nil
+}
+
+// RecordPartyPayment отмечает оплату очередного платежа по графику праздника
+// (администратор). Оплата депозита подтверждает бронирование.
+func (s *BookingService) RecordPartyPayment(bookingID, adminID uuid.UUID, req RecordPartyPaymentRequest) (*PartyPaymentResult, error) {
+	method := req.Method
+	if method == "" {
+		method = models.PaymentMethodCash
+	}
+	if !recordablePartyMethods[method] {
+		return nil, ErrInvalidPaymentMethod
+	}
+
+	result := &PartyPaymentResult{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		booking, err := loadBookingForUpdate(tx, bookingID)
+		if err != nil {
+			return err
+		}
+		if booking.BookingType != models.BookingTypeParty {
+			return ErrNoPaymentDue
+		}
+		switch booking.Status {
+		case models.BookingStatusPendingPayment, models.BookingStatusConfirmed:
+		default:
+			return ErrNoPaymentDue
+		}
+
+		var due []int
+		var amount float64
+		var installments []string
+		for i, installment := range booking.PaymentSchedule {
+			if installment.Status != models.PaymentStatusPending {
+				continue
+			}
+			due = append(due, i)
+			amount += installment.Amount
+			installments = append(installments, string(installment.Type))
+			if !req.PayInFull {
+				break
+			}
+		}
+		if len(due) == 0 {
+			return ErrNoPaymentDue
+		}
+
+		now := time.Now()
+		amount = roundMoney(amount)
+		description := fmt.Sprintf("Оплата праздника %s: %s", booking.BookingNumber, strings.Join(installments, ", "))
+		payment := models.Payment{
+			BookingID:      booking.ID,
+			UserID:         booking.UserID,
+			Method:         method,
+			Status:         models.PaymentStatusCompleted,
+			Amount:         amount,
+			OriginalAmount: amount,
+			NetAmount:      amount,
+			Currency:       booking.Currency,
+			Details: models.PaymentDetails{
+				Provider:              models.PaymentProviderInternal,
+				ProviderTransactionID: req.Reference,
+				Metadata:              models.JSONB{},
+			},
+			Refunds:     []models.RefundDetails{},
+			InitiatedAt: &now,
+			CapturedAt:  &now,
+			Description: &description,
+			Metadata: models.JSONB{
+				"type":         "party_installment",
+				"installments": installments,
+				"recordedBy":   adminID,
+			},
+		}
+		if err := tx.Create(&payment).Error; err != nil {
+			return fmt.Errorf("failed to record party payment: %w", err)
+		}
+
+		for _, i := range due {
+			booking.PaymentSchedule[i].Status = models.PaymentStatusCompleted
+			booking.PaymentSchedule[i].PaymentID = &payment.ID
+			booking.PaymentSchedule[i].PaidAt = &now
+		}
+		if booking.Status == models.BookingStatusPendingPayment {
+			if _, err := s.transition(tx, booking, models.BookingStatusConfirmed, adminID, "party payment received"); err != nil {
+				return err
+			}
+		}
+
+		booking.PaymentStatus = models.PaymentStatusPending
+		if partyFullyPaid(booking) {
+			booking.PaymentStatus = models.PaymentStatusCompleted
+		}
+		if err := tx.Model(booking).
+			Select("payment_schedule", "payment_status").
+			Updates(booking).Error; err != nil {
+			return fmt.Errorf("failed to update payment schedule: %w", err)
+		}
+
+		result.Booking = booking
+		result.Payment = &payment
+		return reloadVersion(tx, booking)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.RefreshParkCapacity(result.Booking.ParkID); err != nil {
+		log.Printf("⚠️ Failed to refresh capacity for park %s: %v", result.Booking.ParkID, err)
+	}
+	return result, nil
+}
+
+// partyFullyPaid сообщает, оплачены ли все платежи графика
+func partyFullyPaid(booking *models.Booking) bool {
+	for _, installment := range booking.PaymentSchedule {
+		if installment.Status == models.PaymentStatusPending {
+			return false
+		}
+	}
+	return true
+}
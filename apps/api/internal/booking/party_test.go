@@ -0,0 +1,104 @@
+package booking
+
+import (
+	"testing"
+	"time"
+
+	"skypark/internal/models"
+)
+
+func TestPartyPaymentSchedule(t *testing.T) {
+	now := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	window := 24 * time.Hour
+
+	tests := []struct {
+		name           string
+		depositPercent float64
+		balanceHours   int
+		startsIn       time.Duration
+		total          float64
+		wantDeposit    float64
+		wantDepositDue time.Time
+		wantBalance    float64
+		wantBalanceDue time.Time
+	}{
+		{
+			name: "party far ahead", depositPercent: 30, balanceHours: 48, startsIn: 10 * 24 * time.Hour, total: 10000,
+			wantDeposit: 3000, wantDepositDue: now.Add(window),
+			wantBalance: 7000, wantBalanceDue: now.Add(8 * 24 * time.Hour),
+		},
+		{
+			// Срок остатка не может наступить раньше срока депозита
+			name: "balance due clamped to the deposit", depositPercent: 30, balanceHours: 48, startsIn: 60 * time.Hour, total: 10000,
+			wantDeposit: 3000, wantDepositDue: now.Add(window),
+			wantBalance: 7000, wantBalanceDue: now.Add(window),
+		},
+		{
+			// Вечеринка раньше окна депозита: оба платежа к началу вечеринки
+			name: "both due clamped to the start", depositPercent: 30, balanceHours: 48, startsIn: 5 * time.Hour, total: 10000,
+			wantDeposit: 3000, wantDepositDue: now.Add(5 * time.Hour),
+			wantBalance: 7000, wantBalanceDue: now.Add(5 * time.Hour),
+		},
+		{
+			name: "deposit rounded to tyiyn", depositPercent: 33.333, balanceHours: 0, startsIn: 10 * 24 * time.Hour, total: 1000,
+			wantDeposit: 333.33, wantDepositDue: now.Add(window),
+			wantBalance: 666.67, wantBalanceDue: now.Add(10 * 24 * time.Hour),
+		},
+		{
+			name: "full prepayment", depositPercent: 100, balanceHours: 48, startsIn: 10 * 24 * time.Hour, total: 10000,
+			wantDeposit: 10000, wantDepositDue: now.Add(window),
+		},
+		{
+			// Без депозита остаток держит комнату и оплачивается в окно депозита
+			name: "no deposit", depositPercent: 0, balanceHours: 48, startsIn: 10 * 24 * time.Hour, total: 10000,
+			wantBalance: 10000, wantBalanceDue: now.Add(window),
+		},
+		{
+			name: "free party", depositPercent: 30, balanceHours: 48, startsIn: 10 * 24 * time.Hour, total: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg := &models.PartyPackage{DepositPercent: tt.depositPercent, BalanceDueHours: tt.balanceHours}
+			booking := &models.Booking{
+				TotalAmount: tt.total,
+				Party:       &models.PartyDetails{StartsAt: now.Add(tt.startsIn)},
+			}
+
+			schedule := partyPaymentSchedule(pkg, booking, now, window)
+
+			var deposit, balance *models.ScheduledPayment
+			for i := range schedule {
+				if schedule[i].Status != models.PaymentStatusPending {
+					t.Errorf("%s status = %q, want %q", schedule[i].Type, schedule[i].Status, models.PaymentStatusPending)
+				}
+				switch schedule[i].Type {
+				case models.ScheduledPaymentDeposit:
+					deposit = &schedule[i]
+				case models.ScheduledPaymentBalance:
+					balance = &schedule[i]
+				}
+			}
+			checkScheduledPayment(t, "deposit", deposit, tt.wantDeposit, tt.wantDepositDue)
+			checkScheduledPayment(t, "balance", balance, tt.wantBalance, tt.wantBalanceDue)
+		})
+	}
+}
+
+// checkScheduledPayment проверяет платёж графика; нулевая сумма означает, что платежа быть не должно
+func checkScheduledPayment(t *testing.T, name string, got *models.ScheduledPayment, wantAmount float64, wantDue time.Time) {
+	t.Helper()
+	if wantAmount == 0 {
+		if got != nil {
+			t.Errorf("unexpected %s payment %+v", name, *got)
+		}
+		return
+	}
+	if got == nil {
+		t.Errorf("missing %s payment", name)
+		return
+	}
+	if got.Amount != wantAmount || !got.DueAt.Equal(wantDue) {
+		t.Errorf("%s = %v due %s, want %v due %s", name, got.Amount, got.DueAt, wantAmount, wantDue)
+	}
+}
@@ -0,0 +1,463 @@
+package booking
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"skypark/internal/models"
+)
+
+// PassProductRequest описывает абонемент в каталоге парка (администратор)
+type PassProductRequest struct {
+	Name         string            `json:"name" binding:"required"`
+	Description  *string           `json:"description,omitempty"`
+	TicketType   models.TicketType `json:"ticketType" binding:"required"` // pass или unlimited
+	Visits       int               `json:"visits"`                        // только для pass
+	ValidityDays int               `json:"validityDays" binding:"required"`
+	Price        float64           `json:"price"`
+	IsActive     *bool             `json:"isActive,omitempty"`
+}
+
+// PurchasePassRequest описывает покупку абонемента
+type PurchasePassRequest struct {
+	PassProductID uuid.UUID          `json:"passProductId" binding:"required"`
+	StartDate     string             `json:"startDate,omitempty"` // YYYY-MM-DD, по умолчанию сегодня
+	Holder        models.GuestInfo   `json:"holder" binding:"required"`
+	ContactInfo   models.ContactInfo `json:"contactInfo" binding:"required"`
+}
+
+// PassPurchaseResult описывает бронирование-покупку и выпущенный абонемент.
+// Абонемент становится активным после подтверждения оплаты бронирования.
+type PassPurchaseResult struct {
+	Booking *models.Booking `json:"booking"`
+	Pass    *models.Ticket  `json:"pass"`
+}
+
+// isPassType сообщает, является ли тип билета абонементом
+func isPassType(ticketType models.TicketType) bool {
+	return ticketType == models.TicketTypePass || ticketType == models.TicketTypeUnlimited
+}
+
+// GetPassCatalog возвращает активные абонементы парка
+func (s *BookingService) GetPassCatalog(parkID uuid.UUID) ([]models.PassProduct, error) {
+	var products []models.PassProduct
+	err := s.db.Where("park_id = ? AND is_active = ? AND deleted_at IS NULL", parkID, true).
+		Order("price ASC").
+		Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// CreatePassProduct добавляет абонемент в каталог парка (администратор)
+func (s *BookingService) CreatePassProduct(parkID uuid.UUID, req PassProductRequest) (*models.PassProduct, error) {
+	if err := validatePassProduct(&req); err != nil {
+		return nil, err
+	}
+
+	if err := s.ensurePark(parkID); err != nil {
+		return nil, err
+	}
+
+	product := models.PassProduct{
+		ParkID:       parkID,
+		Name:         req.Name,
+		Description:  req.Description,
+		TicketType:   req.TicketType,
+		Visits:       req.Visits,
+		ValidityDays: req.ValidityDays,
+		Price:        roundMoney(req.Price),
+		Currency:     "KGS",
+		IsActive:     true,
+	}
+	if err := s.db.Create(&product).Error; err != nil {
+		return nil, fmt.Errorf("failed to create pass product: %w", err)
+	}
+	if req.IsActive != nil && !*req.IsActive {
+		product.IsActive = false
+		if err := s.db.Model(&product).Update("is_active", false).Error; err != nil {
+			return nil, fmt.Errorf("failed to update pass product: %w", err)
+		}
+	}
+	return &product, nil
+}
+
+// UpdatePassProduct изменяет абонемент каталога. Уже проданные абонементы
+// сохраняют условия, действовавшие при покупке.
+func (s *BookingService) UpdatePassProduct(parkID, productID uuid.UUID, req PassProductRequest) (*models.PassProduct, error) {
+	if err := validatePassProduct(&req); err != nil {
+		return nil, err
+	}
+
+	product, err := s.findPassProduct(s.db, parkID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	product.Name = req.Name
+	product.Description = req.Description
+	product.TicketType = req.TicketType
+	product.Visits = req.Visits
+	product.ValidityDays = req.ValidityDays
+	product.Price = roundMoney(req.Price)
+	if req.IsActive != nil {
+		product.IsActive = *req.IsActive
+	}
+
+	if err := s.db.Model(product).
+		Select("name", "description", "ticket_type", "visits", "validity_days", "price", "is_active").
+		Updates(product).Error; err != nil {
+		return nil, fmt.Errorf("failed to update pass product: %w", err)
+	}
+	return product, nil
+}
+
+// DeactivatePassProduct снимает абонемент с продажи
+func (s *BookingService) DeactivatePassProduct(parkID, productID uuid.UUID) error {
+	product, err := s.findPassProduct(s.db, parkID, productID)
+	if err != nil {
+		return err
+	}
+	return s.db.Model(product).Update("is_active", false).Error
+}
+
+func (s *BookingService) findPassProduct(tx *gorm.DB, parkID, productID uuid.UUID) (*models.PassProduct, error) {
+	var product models.PassProduct
+	query := tx.Where("id = ? AND deleted_at IS NULL", productID)
+	if parkID != uuid.Nil {
+		query = query.Where("park_id = ?", parkID)
+	}
+	if err := query.First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPassProductNotFound
+		}
+		return nil, err
+	}
+	return &product, nil
+}
+
+func validatePassProduct(req *PassProductRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || req.ValidityDays < 1 || req.Price < 0 {
+		return ErrInvalidPassProduct
+	}
+	switch req.TicketType {
+	case models.TicketTypePass:
+		if req.Visits < 1 {
+			return ErrInvalidPassProduct
+		}
+	case models.TicketTypeUnlimited:
+		req.Visits = 0
+	default:
+		return ErrInvalidPassProduct
+	}
+	return nil
+}
+
+// PurchasePass оформляет покупку абонемента: создаёт бронирование типа pass
+// на его стоимость и выпускает билет-абонемент. Бронирование не занимает мест
+// в парке, а абонемент активируется, когда бронирование подтверждено.
+func (s *BookingService) PurchasePass(userID uuid.UUID, req PurchasePassRequest) (*PassPurchaseResult, error) {
+	if err := validateGuests([]models.GuestInfo{req.Holder}); err != nil {
+		return nil, err
+	}
+	if err := validateContactInfo(&req.ContactInfo); err != nil {
+		return nil, err
+	}
+
+	startDate := req.StartDate
+	if startDate == "" {
+		startDate = time.Now().In(parkLocation()).Format("2006-01-02")
+	}
+	validFrom, err := parseVisitDate(startDate)
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := s.findPassProduct(s.db, uuid.Nil, req.PassProductID)
+	if err != nil {
+		return nil, err
+	}
+	if !product.IsActive {
+		return nil, ErrPassProductNotFound
+	}
+
+	var park models.Park
+	if err := s.db.Where("id = ? AND deleted_at IS NULL", product.ParkID).First(&park).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrParkNotFound
+		}
+		return nil, err
+	}
+	if park.Status != models.ParkStatusActive {
+		return nil, ErrParkUnavailable
+	}
+
+	holder := req.Holder
+	holder.AgeCategory = resolveAgeCategory(holder)
+	holder.TicketType = product.TicketType
+	holder.PassTicketID = nil
+	if holder.SpecialRequirements == nil {
+		holder.SpecialRequirements = models.StringArray{}
+	}
+
+	booking := models.Booking{
+		BaseModel:     models.BaseModel{ID: models.GenerateUUID()},
+		UserID:        userID,
+		ParkID:        park.ID,
+		Status:        models.BookingStatusPendingPayment,
+		PaymentStatus: models.PaymentStatusPending,
+		Source:        models.BookingSourceWeb,
+		BookingType:   models.BookingTypePass,
+		VisitDate:     validFrom,
+		Items: []models.BookingItem{{
+			ID:         models.GenerateUUID(),
+			GuestInfo:  holder,
+			BasePrice:  product.Price,
+			FinalPrice: product.Price,
+		}},
+		Currency:            product.Currency,
+		Discounts:           []models.DiscountInfo{},
+		ContactInfo:         req.ContactInfo,
+		SpecialRequirements: models.StringArray{},
+		BookedAt:            time.Now(),
+		Metadata: models.JSONB{
+			"passProductId": product.ID,
+		},
+	}
+	applyTotals(&booking)
+
+	validTo := validFrom.AddDate(0, 0, product.ValidityDays)
+	maxUsages := product.Visits
+	if product.TicketType == models.TicketTypeUnlimited {
+		// Безлимитный абонемент — не больше одного визита в день
+		maxUsages = product.ValidityDays
+	}
+	passID := models.GenerateUUID()
+	qrCode, err := s.newQRCode(passID, park.ID, validFrom, validTo, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	description := fmt.Sprintf("Действует с %s по %s", validFrom.Format("02.01.2006"), validTo.AddDate(0, 0, -1).Format("02.01.2006"))
+	pass := models.Ticket{
+		BaseModel:           models.BaseModel{ID: passID},
+		BookingID:           booking.ID,
+		ParkID:              park.ID,
+		UserID:              userID,
+		Type:                product.TicketType,
+		AgeCategory:         holder.AgeCategory,
+		Status:              models.TicketStatusPending,
+		Title:               product.Name,
+		Description:         &description,
+		Price:               product.Price,
+		OriginalPrice:       product.Price,
+		Currency:            product.Currency,
+		ValidFrom:           validFrom,
+		ValidTo:             validTo,
+		MaxUsages:           maxUsages,
+		QRCode:              qrCode,
+		Validations:         []models.TicketValidation{},
+		HolderName:          strings.TrimSpace(holder.Name),
+		HolderAge:           holder.Age,
+		SpecialRequirements: holder.SpecialRequirements,
+		Metadata: models.JSONB{
+			"passProductId": product.ID,
+		},
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := models.CreateBooking(tx, &booking); err != nil {
+			return fmt.Errorf("failed to create pass booking: %w", err)
+		}
+		if err := models.CreateTicket(tx, &pass); err != nil {
+			return fmt.Errorf("failed to issue pass: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &PassPurchaseResult{Booking: &booking, Pass: &pass}, nil
+}
+
+// GetUserPasses возвращает абонементы пользователя
+func (s *BookingService) GetUserPasses(userID uuid.UUID) ([]models.Ticket, error) {
+	var passes []models.Ticket
+	err := s.db.Where("user_id = ? AND type IN ? AND deleted_at IS NULL", userID,
+		[]models.TicketType{models.TicketTypePass, models.TicketTypeUnlimited}).
+		Preload("Park").
+		Order("valid_to DESC").
+		Find(&passes).Error
+	if err != nil {
+		return nil, err
+	}
+	return passes, nil
+}
+
+// applyPasses делает бесплатными позиции бронирования, гости которых
+// предъявили абонемент. Места в слоте такие гости занимают как обычно, а визит
+// списывается с абонемента при входе в парк. Пока визит не состоялся, он
+// резервирует одно посещение абонемента.
+func applyPasses(tx *gorm.DB, booking *models.Booking) error {
+	var discount float64
+	var covered []uuid.UUID
+	seen := map[uuid.UUID]bool{}
+
+	for i := range booking.Items {
+		item := &booking.Items[i]
+		if item.GuestInfo.PassTicketID == nil {
+			continue
+		}
+		passID := *item.GuestInfo.PassTicketID
+		if seen[passID] {
+			return fmt.Errorf("%w: pass used for more than one guest", ErrInvalidPass)
+		}
+		seen[passID] = true
+
+		pass, err := checkPass(tx, booking, passID)
+		if err != nil {
+			return err
+		}
+
+		item.GuestInfo.TicketType = pass.Type
+		item.DiscountAmount = item.BasePrice
+		item.FinalPrice = 0
+		discount += item.DiscountAmount
+		covered = append(covered, item.ID)
+	}
+
+	if len(covered) > 0 {
+		booking.Discounts = append(booking.Discounts, models.DiscountInfo{
+			Type:        "fixed",
+			Description: "Посещение по абонементу",
+			Amount:      roundMoney(discount),
+			AppliedTo:   covered,
+			UsageCount:  len(covered),
+		})
+	}
+	return nil
+}
+
+// checkPass проверяет, что абонемент passID можно использовать для визита
+// бронирования: он принадлежит владельцу бронирования, активен в этом парке
+// на дату визита и у него остались посещения
+func checkPass(tx *gorm.DB, booking *models.Booking, passID uuid.UUID) (*models.Ticket, error) {
+	var pass models.Ticket
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND user_id = ? AND deleted_at IS NULL", passID, booking.UserID).
+		First(&pass).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: pass not found", ErrInvalidPass)
+		}
+		return nil, err
+	}
+
+	if !isPassType(pass.Type) || pass.ParkID != booking.ParkID {
+		return nil, fmt.Errorf("%w: pass is not valid in this park", ErrInvalidPass)
+	}
+	if pass.Status != models.TicketStatusActive {
+		return nil, fmt.Errorf("%w: pass is %s", ErrInvalidPass, pass.Status)
+	}
+	start := visitStart(booking)
+	if start.Before(pass.ValidFrom) || !start.Before(pass.ValidTo) {
+		return nil, fmt.Errorf("%w: pass is not valid on the visit date", ErrInvalidPass)
+	}
+
+	// Визиты по абонементу в других бронированиях, ещё не списанные с него
+	var reserved []models.Booking
+	err = tx.Model(&models.Booking{}).
+		Select("id", "visit_date", "status").
+		Where("id <> ? AND deleted_at IS NULL AND items @> ?", booking.ID, passItemsFilter(passID)).
+		Where("(status IN ? OR (status = ? AND hold_expires_at > ?))",
+			[]models.BookingStatus{models.BookingStatusConfirmed, models.BookingStatusCheckedIn, models.BookingStatusCompleted},
+			models.BookingStatusPendingPayment, time.Now()).
+		Find(&reserved).Error
+	if err != nil {
+		return nil, err
+	}
+
+	visitDay := booking.VisitDate.Format("2006-01-02")
+	pending := 0
+	for _, other := range reserved {
+		if pass.Type == models.TicketTypeUnlimited && other.VisitDate.Format("2006-01-02") == visitDay {
+			return nil, fmt.Errorf("%w: unlimited pass allows one visit per day", ErrPassExhausted)
+		}
+		if other.Status == models.BookingStatusConfirmed || other.Status == models.BookingStatusPendingPayment {
+			pending++
+		}
+	}
+	if pass.UsageCount+pending >= pass.MaxUsages {
+		return nil, ErrPassExhausted
+	}
+	return &pass, nil
+}
+
+// passItemsFilter возвращает JSONB-фильтр позиций, оплаченных абонементом passID
+func passItemsFilter(passID uuid.UUID) string {
+	filter, _ := json.Marshal([]map[string]interface{}{
+		{"guestInfo": map[string]interface{}{"passTicketId": passID}},
+	})
+	return string(filter)
+}
+
+// syncPassTickets списывает посещения с абонементов, которыми оплачен визит,
+// когда гости проходят в парк. Сам абонемент активируется и аннулируется
+// вместе с бронированием покупки, как и любой билет (syncBookingTickets).
+func syncPassTickets(tx *gorm.DB, booking *models.Booking, to models.BookingStatus, actorID uuid.UUID, now time.Time) error {
+	if booking.BookingType == models.BookingTypePass || to != models.BookingStatusCheckedIn {
+		return nil
+	}
+	for _, item := range booking.Items {
+		if item.GuestInfo.PassTicketID == nil {
+			continue
+		}
+		if err := usePass(tx, *item.GuestInfo.PassTicketID, booking.ParkID, actorID, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// usePass списывает посещение с абонемента и записывает проверку
+func usePass(tx *gorm.DB, passID, parkID, actorID uuid.UUID, now time.Time) error {
+	var pass models.Ticket
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND deleted_at IS NULL", passID).
+		First(&pass).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: pass not found", ErrInvalidPass)
+		}
+		return err
+	}
+	if pass.Status != models.TicketStatusActive || pass.UsageCount >= pass.MaxUsages {
+		return ErrPassExhausted
+	}
+
+	if useTicket(&pass, models.TicketValidation{
+		TicketID:    pass.ID,
+		ParkID:      parkID,
+		ValidatedAt: now,
+		ValidatedBy: actorID,
+		Metadata:    models.JSONB{},
+	}) {
+		setTicketStatus(&pass, models.TicketStatusUsed, actorID, "all visits used", now)
+	}
+
+	if err := tx.Model(&pass).
+		Select("usage_count", "validations", "status", "metadata").
+		Updates(&pass).Error; err != nil {
+		return fmt.Errorf("failed to record pass usage: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,467 @@
+package booking
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+
+	"skypark/internal/models"
+)
+
+// Шрифт DejaVu Sans Condensed содержит кириллицу, включая кыргызские Ң, Ө и Ү
+// (лицензия DejaVu Fonts, fonts/README.md)
+var (
+	//go:embed fonts/DejaVuSansCondensed.ttf
+	pdfFontRegular []byte
+	//go:embed fonts/DejaVuSansCondensed-Bold.ttf
+	pdfFontBold []byte
+)
+
+const pdfFont = "DejaVu"
+
+// pdfStrings — подписи документа на одном языке
+type pdfStrings struct {
+	Title           string
+	Booking         string
+	Status          string
+	Payment         string
+	VisitDate       string
+	Time            string
+	Duration        string
+	Minutes         string
+	Park            string
+	Address         string
+	Phone           string
+	Email           string
+	Website         string
+	Contact         string
+	Organization    string
+	TaxID           string
+	Tickets         string
+	Ticket          string
+	Holder          string
+	AgeCategory     string
+	Valid           string
+	Transferred     string
+	Used            string
+	Pricing         string
+	Guest           string
+	Price           string
+	Discount        string
+	Amount          string
+	PartyPackage    string
+	ExtraGuests     string
+	Subtotal        string
+	Tax             string
+	Total           string
+	Footer          string
+	Generated       string
+	AgeCategories   map[models.AgeCategory]string
+	Statuses        map[models.BookingStatus]string
+	PaymentStatuses map[models.PaymentStatus]string
+}
+
+// pdfTemplates — русский и кыргызский варианты документа
+var pdfTemplates = map[string]pdfStrings{
+	"ru": {
+		Title:        "Подтверждение бронирования",
+		Booking:      "Бронирование №",
+		Status:       "Статус",
+		Payment:      "Оплата",
+		VisitDate:    "Дата визита",
+		Time:         "Время",
+		Duration:     "Продолжительность",
+		Minutes:      "мин",
+		Park:         "Парк",
+		Address:      "Адрес",
+		Phone:        "Телефон",
+		Email:        "Эл. почта",
+		Website:      "Сайт",
+		Contact:      "Контактное лицо",
+		Organization: "Организация",
+		TaxID:        "ИНН",
+		Tickets:      "Билеты",
+		Ticket:       "Билет №",
+		Holder:       "Гость",
+		AgeCategory:  "Категория",
+		Valid:        "Действует",
+		Transferred:  "Билет передан другому человеку",
+		Used:         "Билет использован",
+		Pricing:      "Стоимость",
+		Guest:        "Гость",
+		Price:        "Цена",
+		Discount:     "Скидка",
+		Amount:       "Сумма",
+		PartyPackage: "Пакет праздника",
+		ExtraGuests:  "Дополнительные гости",
+		Subtotal:     "Сумма без скидок",
+		Tax:          "Налог",
+		Total:        "Итого",
+		Footer:       "Покажите QR-код билета на входе в парк.",
+		Generated:    "Документ сформирован",
+		AgeCategories: map[models.AgeCategory]string{
+			models.AgeCategoryBaby:   "Малыш",
+			models.AgeCategoryChild:  "Ребёнок",
+			models.AgeCategoryTeen:   "Подросток",
+			models.AgeCategoryAdult:  "Взрослый",
+			models.AgeCategorySenior: "Пенсионер",
+		},
+		Statuses: map[models.BookingStatus]string{
+			models.BookingStatusDraft:          "Черновик",
+			models.BookingStatusPendingPayment: "Ожидает оплаты",
+			models.BookingStatusConfirmed:      "Подтверждено",
+			models.BookingStatusCheckedIn:      "Гости в парке",
+			models.BookingStatusCompleted:      "Завершено",
+			models.BookingStatusCancelled:      "Отменено",
+			models.BookingStatusRefunded:       "Возвращено",
+			models.BookingStatusNoShow:         "Неявка",
+		},
+		PaymentStatuses: map[models.PaymentStatus]string{
+			models.PaymentStatusPending:           "Не оплачено",
+			models.PaymentStatusProcessing:        "Обрабатывается",
+			models.PaymentStatusCompleted:         "Оплачено",
+			models.PaymentStatusFailed:            "Ошибка оплаты",
+			models.PaymentStatusCancelled:         "Отменено",
+			models.PaymentStatusRefunded:          "Возвращено",
+			models.PaymentStatusPartiallyRefunded: "Частично возвращено",
+		},
+	},
+	"ky": {
+		Title:        "Брондоону ырастоо",
+		Booking:      "Брондоо №",
+		Status:       "Абалы",
+		Payment:      "Төлөм",
+		VisitDate:    "Келүү күнү",
+		Time:         "Убактысы",
+		Duration:     "Узактыгы",
+		Minutes:      "мүн",
+		Park:         "Парк",
+		Address:      "Дареги",
+		Phone:        "Телефон",
+		Email:        "Эл. почта",
+		Website:      "Сайт",
+		Contact:      "Байланыш үчүн адам",
+		Organization: "Уюм",
+		TaxID:        "ИСН",
+		Tickets:      "Билеттер",
+		Ticket:       "Билет №",
+		Holder:       "Конок",
+		AgeCategory:  "Категориясы",
+		Valid:        "Жарактуу",
+		Transferred:  "Билет башка адамга өткөрүлүп берилген",
+		Used:         "Билет колдонулган",
+		Pricing:      "Баа эсеби",
+		Guest:        "Конок",
+		Price:        "Баасы",
+		Discount:     "Арзандатуу",
+		Amount:       "Сумма",
+		PartyPackage: "Майрам пакети",
+		ExtraGuests:  "Кошумча коноктор",
+		Subtotal:     "Арзандатуусуз сумма",
+		Tax:          "Салык",
+		Total:        "Жыйынтыгы",
+		Footer:       "Паркка киреринде билеттин QR-кодун көрсөтүңүз.",
+		Generated:    "Документ түзүлгөн",
+		AgeCategories: map[models.AgeCategory]string{
+			models.AgeCategoryBaby:   "Бөбөк",
+			models.AgeCategoryChild:  "Бала",
+			models.AgeCategoryTeen:   "Өспүрүм",
+			models.AgeCategoryAdult:  "Чоң киши",
+			models.AgeCategorySenior: "Пенсионер",
+		},
+		Statuses: map[models.BookingStatus]string{
+			models.BookingStatusDraft:          "Долбоор",
+			models.BookingStatusPendingPayment: "Төлөм күтүлүүдө",
+			models.BookingStatusConfirmed:      "Ырасталды",
+			models.BookingStatusCheckedIn:      "Коноктор паркта",
+			models.BookingStatusCompleted:      "Аяктады",
+			models.BookingStatusCancelled:      "Жокко чыгарылды",
+			models.BookingStatusRefunded:       "Кайтарылды",
+			models.BookingStatusNoShow:         "Келген жок",
+		},
+		PaymentStatuses: map[models.PaymentStatus]string{
+			models.PaymentStatusPending:           "Төлөнгөн жок",
+			models.PaymentStatusProcessing:        "Иштетилүүдө",
+			models.PaymentStatusCompleted:         "Төлөндү",
+			models.PaymentStatusFailed:            "Төлөм катасы",
+			models.PaymentStatusCancelled:         "Жокко чыгарылды",
+			models.PaymentStatusRefunded:          "Кайтарылды",
+			models.PaymentStatusPartiallyRefunded: "Жарым-жартылай кайтарылды",
+		},
+	},
+}
+
+// Размеры страницы A4 в миллиметрах
+const (
+	pdfMargin     = 15.0
+	pdfPageWidth  = 210.0 - 2*pdfMargin
+	pdfPageBottom = 297.0 - pdfMargin
+	pdfQRSize     = 38.0
+)
+
+// RenderBookingPDF формирует печатное подтверждение бронирования на языке
+// lang (ru или ky): сведения о парке, билеты с QR-кодами и расчёт стоимости.
+// Бронирование должно быть загружено вместе с парком и билетами.
+func RenderBookingPDF(booking *models.Booking, lang string) ([]byte, error) {
+	t, ok := pdfTemplates[lang]
+	if !ok {
+		return nil, ErrInvalidPDFLanguage
+	}
+	loc := parkLocation()
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.AddUTF8FontFromBytes(pdfFont, "", pdfFontRegular)
+	pdf.AddUTF8FontFromBytes(pdfFont, "B", pdfFontBold)
+	pdf.SetTitle(t.Title+" "+booking.BookingNumber, true)
+	pdf.SetCreator("Sky Park", true)
+	generatedAt := time.Now().In(loc).Format("02.01.2006 15:04")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pdfMargin)
+		pdf.SetFont(pdfFont, "", 8)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(pdfPageWidth/2, 5, t.Generated+" "+generatedAt, "", 0, "L", false, 0, "")
+		pdf.CellFormat(pdfPageWidth/2, 5, fmt.Sprintf("%d", pdf.PageNo()), "", 0, "R", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	})
+	pdf.AddPage()
+
+	// Заголовок
+	pdf.SetFont(pdfFont, "B", 18)
+	pdf.CellFormat(pdfPageWidth, 10, t.Title, "", 1, "L", false, 0, "")
+	pdf.SetFont(pdfFont, "B", 12)
+	pdf.CellFormat(pdfPageWidth, 7, t.Booking+" "+booking.BookingNumber, "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+
+	pdf.SetFont(pdfFont, "", 10)
+	pdfField(pdf, t.Status, pdfLabel(t.Statuses, booking.Status))
+	pdfField(pdf, t.Payment, pdfLabel(t.PaymentStatuses, booking.PaymentStatus))
+	pdfField(pdf, t.VisitDate, booking.VisitDate.Format("02.01.2006"))
+	if booking.TimeSlot != nil {
+		pdfField(pdf, t.Time, *booking.TimeSlot)
+	}
+	pdfField(pdf, t.Duration, fmt.Sprintf("%d %s", booking.Duration, t.Minutes))
+	contact := strings.TrimSpace(booking.ContactInfo.FirstName + " " + booking.ContactInfo.LastName)
+	if booking.ContactInfo.PhoneNumber != "" {
+		contact += ", " + booking.ContactInfo.PhoneNumber
+	}
+	pdfField(pdf, t.Contact, contact)
+	if org, ok := booking.Metadata["organization"].(map[string]interface{}); ok {
+		if name, _ := org["name"].(string); name != "" {
+			pdfField(pdf, t.Organization, name)
+		}
+		if taxID, _ := org["taxId"].(string); taxID != "" {
+			pdfField(pdf, t.TaxID, taxID)
+		}
+	}
+
+	// Парк
+	if park := booking.Park; park != nil {
+		pdfSection(pdf, t.Park)
+		pdf.SetFont(pdfFont, "B", 11)
+		pdf.CellFormat(pdfPageWidth, 6, park.Name, "", 1, "L", false, 0, "")
+		pdf.SetFont(pdfFont, "", 10)
+		if address := parkAddress(park); address != "" {
+			pdfField(pdf, t.Address, address)
+		}
+		if park.PhoneNumber != nil {
+			pdfField(pdf, t.Phone, *park.PhoneNumber)
+		}
+		if park.Email != nil {
+			pdfField(pdf, t.Email, *park.Email)
+		}
+		if park.Website != nil {
+			pdfField(pdf, t.Website, *park.Website)
+		}
+	}
+
+	// Билеты
+	tickets := make([]models.Ticket, 0, len(booking.Tickets))
+	for _, ticket := range booking.Tickets {
+		if !isVoidTicketStatus(ticket.Status) {
+			tickets = append(tickets, ticket)
+		}
+	}
+	if len(tickets) > 0 {
+		pdfSection(pdf, t.Tickets)
+		for _, ticket := range tickets {
+			if err := pdfTicket(pdf, t, &ticket, loc); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Стоимость
+	pdfSection(pdf, t.Pricing)
+	pdfPricing(pdf, t, booking)
+
+	pdf.Ln(6)
+	pdf.SetFont(pdfFont, "", 9)
+	pdf.MultiCell(pdfPageWidth, 5, t.Footer, "", "L", false)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render booking PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// pdfTicket рисует билет: QR-код слева, сведения о госте справа
+func pdfTicket(pdf *gofpdf.Fpdf, t pdfStrings, ticket *models.Ticket, loc *time.Location) error {
+	height := pdfQRSize + 4
+	if pdf.GetY()+height > pdfPageBottom {
+		pdf.AddPage()
+	}
+	x, y := pdf.GetX(), pdf.GetY()
+	pdf.SetDrawColor(200, 200, 200)
+	pdf.Rect(x, y, pdfPageWidth, height, "D")
+
+	note := ""
+	switch {
+	case ticket.QRCode.Data == "":
+		// QR-коды переданных билетов покупателю не показываются
+		note = t.Transferred
+	case ticket.Status == models.TicketStatusUsed:
+		note = t.Used
+	default:
+		image, err := RenderQRCode(ticket.QRCode.Data, QRImageOptions{Format: "png", Size: 512, ErrorCorrectionLevel: "M"})
+		if err != nil {
+			return err
+		}
+		name := "qr-" + ticket.ID.String()
+		pdf.RegisterImageOptionsReader(name, gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(image))
+		pdf.ImageOptions(name, x+2, y+2, pdfQRSize, pdfQRSize, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	}
+
+	textX := x + pdfQRSize + 6
+	textWidth := pdfPageWidth - pdfQRSize - 8
+	pdf.SetXY(textX, y+3)
+	pdf.SetFont(pdfFont, "B", 11)
+	number := ticket.TicketNumber
+	if number == "" {
+		number = ticket.ID.String()
+	}
+	pdf.CellFormat(textWidth, 6, t.Ticket+" "+number, "", 2, "L", false, 0, "")
+	pdf.SetFont(pdfFont, "", 10)
+	pdf.CellFormat(textWidth, 5, ticket.Title, "", 2, "L", false, 0, "")
+	pdf.CellFormat(textWidth, 5, t.Holder+": "+ticket.HolderName, "", 2, "L", false, 0, "")
+	pdf.CellFormat(textWidth, 5, t.AgeCategory+": "+pdfLabel(t.AgeCategories, ticket.AgeCategory), "", 2, "L", false, 0, "")
+	validity := ticket.ValidFrom.In(loc).Format("02.01.2006 15:04") + " – " + ticket.ValidTo.In(loc).Format("02.01.2006 15:04")
+	pdf.CellFormat(textWidth, 5, t.Valid+": "+validity, "", 2, "L", false, 0, "")
+	if note != "" {
+		pdf.SetFont(pdfFont, "B", 10)
+		pdf.CellFormat(textWidth, 5, note, "", 2, "L", false, 0, "")
+	}
+
+	pdf.SetXY(x, y+height+3)
+	return pdf.Error()
+}
+
+// pdfPricing рисует расчёт стоимости: строки гостей, пакет праздника и итоги
+func pdfPricing(pdf *gofpdf.Fpdf, t pdfStrings, booking *models.Booking) {
+	widths := []float64{70, 35, 25, 25, 25}
+	header := []string{t.Guest, t.AgeCategory, t.Price, t.Discount, t.Amount}
+	pdf.SetFont(pdfFont, "B", 9)
+	pdf.SetFillColor(240, 240, 240)
+	for i, title := range header {
+		align := "R"
+		if i < 2 {
+			align = "L"
+		}
+		pdf.CellFormat(widths[i], 7, title, "B", 0, align, true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont(pdfFont, "", 9)
+	for _, item := range booking.Items {
+		pdf.CellFormat(widths[0], 6, item.GuestInfo.Name, "", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 6, pdfLabel(t.AgeCategories, item.GuestInfo.AgeCategory), "", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[2], 6, formatMoney(item.BasePrice), "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[3], 6, formatMoney(item.DiscountAmount), "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[4], 6, formatMoney(item.FinalPrice), "", 1, "R", false, 0, "")
+	}
+	if party := booking.Party; party != nil {
+		pdfPricingLine(pdf, t.PartyPackage+": "+party.PackageName, party.PackagePrice)
+		if party.ExtraGuests > 0 {
+			pdfPricingLine(pdf, fmt.Sprintf("%s: %d × %s", t.ExtraGuests, party.ExtraGuests, formatMoney(party.ExtraGuestPrice)),
+				float64(party.ExtraGuests)*party.ExtraGuestPrice)
+		}
+		for _, addOn := range party.AddOns {
+			pdfPricingLine(pdf, fmt.Sprintf("%s: %d × %s", addOn.Name, addOn.Quantity, formatMoney(addOn.UnitPrice)), addOn.Total)
+		}
+	}
+
+	pdf.Ln(2)
+	pdfTotalLine(pdf, t.Subtotal, booking.Subtotal, booking.Currency, false)
+	if booking.DiscountAmount > 0 {
+		pdfTotalLine(pdf, t.Discount, -booking.DiscountAmount, booking.Currency, false)
+	}
+	if booking.TaxAmount > 0 {
+		pdfTotalLine(pdf, t.Tax, booking.TaxAmount, booking.Currency, false)
+	}
+	pdfTotalLine(pdf, t.Total, booking.TotalAmount, booking.Currency, true)
+}
+
+// pdfPricingLine — строка расчёта без разбивки по колонкам
+func pdfPricingLine(pdf *gofpdf.Fpdf, title string, amount float64) {
+	pdf.CellFormat(155, 6, title, "", 0, "L", false, 0, "")
+	pdf.CellFormat(25, 6, formatMoney(amount), "", 1, "R", false, 0, "")
+}
+
+// pdfTotalLine — итоговая строка, выровненная по правому краю
+func pdfTotalLine(pdf *gofpdf.Fpdf, title string, amount float64, currency string, bold bool) {
+	style := ""
+	if bold {
+		style = "B"
+	}
+	pdf.SetFont(pdfFont, style, 10)
+	pdf.CellFormat(140, 6, title, "", 0, "R", false, 0, "")
+	pdf.CellFormat(40, 6, formatMoney(amount)+" "+currency, "", 1, "R", false, 0, "")
+}
+
+// pdfSection начинает раздел документа с заголовком
+func pdfSection(pdf *gofpdf.Fpdf, title string) {
+	pdf.Ln(4)
+	pdf.SetFont(pdfFont, "B", 13)
+	pdf.CellFormat(pdfPageWidth, 8, title, "B", 1, "L", false, 0, "")
+	pdf.Ln(2)
+	pdf.SetFont(pdfFont, "", 10)
+}
+
+// pdfField выводит строку «подпись: значение»
+func pdfField(pdf *gofpdf.Fpdf, label, value string) {
+	pdf.SetFont(pdfFont, "", 10)
+	pdf.SetTextColor(100, 100, 100)
+	pdf.CellFormat(45, 5.5, label, "", 0, "L", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+	pdf.MultiCell(pdfPageWidth-45, 5.5, value, "", "L", false)
+}
+
+// pdfLabel переводит значение перечисления, оставляя его как есть, если
+// перевода нет
+func pdfLabel[K ~string](labels map[K]string, value K) string {
+	if label, ok := labels[value]; ok {
+		return label
+	}
+	return string(value)
+}
+
+// formatMoney форматирует сумму с двумя знаками после запятой
+func formatMoney(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
+
+// isVoidTicketStatus сообщает, что по билету уже нельзя пройти
+func isVoidTicketStatus(status models.TicketStatus) bool {
+	for _, void := range voidTicketStatuses {
+		if status == void {
+			return true
+		}
+	}
+	return false
+}
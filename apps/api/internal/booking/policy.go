@@ -0,0 +1,60 @@
+package booking
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"skypark/internal/models"
+)
+
+// DefaultCancellationPolicy применяется, если у парка нет своей политики:
+// полный возврат за 24 часа до визита, 50% за 2 часа, позже — без возврата.
+func DefaultCancellationPolicy() models.CancellationPolicy {
+	return models.CancellationPolicy{
+		Rules: []models.CancellationRule{
+			{HoursBefore: 24, RefundPercent: 100},
+			{HoursBefore: 2, RefundPercent: 50},
+		},
+	}
+}
+
+// cancellationPolicyFor возвращает политику отмены парка
+func cancellationPolicyFor(park *models.Park) models.CancellationPolicy {
+	if park.Settings.CancellationPolicy != nil && len(park.Settings.CancellationPolicy.Rules) > 0 {
+		return *park.Settings.CancellationPolicy
+	}
+	return DefaultCancellationPolicy()
+}
+
+// EvaluateRefundPercent возвращает процент возврата при отмене в момент at
+// визита, начинающегося в visitStart. Срабатывает правило с наибольшим
+// HoursBefore, которое ещё выполняется. Процент вне 0..100 приводится к
+// ближайшей границе.
+func EvaluateRefundPercent(policy models.CancellationPolicy, visitStart, at time.Time) float64 {
+	rules := make([]models.CancellationRule, len(policy.Rules))
+	copy(rules, policy.Rules)
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].HoursBefore > rules[j].HoursBefore
+	})
+
+	left := visitStart.Sub(at)
+	for _, rule := range rules {
+		if left >= time.Duration(rule.HoursBefore)*time.Hour {
+			return math.Min(math.Max(rule.RefundPercent, 0), 100)
+		}
+	}
+	return 0
+}
+
+// visitStart возвращает момент начала визита по дате и слоту бронирования
+func visitStart(booking *models.Booking) time.Time {
+	loc := parkLocation()
+	start := time.Date(booking.VisitDate.Year(), booking.VisitDate.Month(), booking.VisitDate.Day(), 0, 0, 0, 0, loc)
+	if booking.TimeSlot != nil {
+		if minutes, err := parseClock(*booking.TimeSlot); err == nil {
+			start = start.Add(time.Duration(minutes) * time.Minute)
+		}
+	}
+	return start
+}
@@ -0,0 +1,62 @@
+package booking
+
+import (
+	"testing"
+	"time"
+
+	"skypark/internal/models"
+)
+
+func TestEvaluateRefundPercent(t *testing.T) {
+	start := time.Date(2024, 6, 1, 14, 0, 0, 0, time.UTC)
+	// Правила намеренно не по порядку: срабатывает наибольший выполненный HoursBefore
+	policy := models.CancellationPolicy{Rules: []models.CancellationRule{
+		{HoursBefore: 2, RefundPercent: 50},
+		{HoursBefore: 48, RefundPercent: 100},
+		{HoursBefore: 24, RefundPercent: 80},
+	}}
+
+	tests := []struct {
+		name   string
+		policy models.CancellationPolicy
+		before time.Duration
+		want   float64
+	}{
+		{"well ahead", policy, 72 * time.Hour, 100},
+		{"exactly at the first boundary", policy, 48 * time.Hour, 100},
+		{"just past the first boundary", policy, 48*time.Hour - time.Second, 80},
+		{"exactly at the second boundary", policy, 24 * time.Hour, 80},
+		{"between the last rules", policy, 3 * time.Hour, 50},
+		{"exactly at the last boundary", policy, 2 * time.Hour, 50},
+		{"after the last boundary", policy, 2*time.Hour - time.Second, 0},
+		{"after the visit started", policy, -time.Hour, 0},
+		{"no rules", models.CancellationPolicy{}, 72 * time.Hour, 0},
+		{"zero hours rule applies until the start", models.CancellationPolicy{Rules: []models.CancellationRule{{HoursBefore: 0, RefundPercent: 30}}}, 0, 30},
+		{"default policy full refund", DefaultCancellationPolicy(), 24 * time.Hour, 100},
+		{"default policy half refund", DefaultCancellationPolicy(), 23 * time.Hour, 50},
+		{"default policy no refund", DefaultCancellationPolicy(), time.Hour, 0},
+		// Политику задаёт парк, проценты вне 0..100 приводятся к границам
+		{"percent above 100 is clamped", models.CancellationPolicy{Rules: []models.CancellationRule{{HoursBefore: 1, RefundPercent: 150}}}, 2 * time.Hour, 100},
+		{"negative percent is clamped", models.CancellationPolicy{Rules: []models.CancellationRule{{HoursBefore: 1, RefundPercent: -20}}}, 2 * time.Hour, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EvaluateRefundPercent(tt.policy, start, start.Add(-tt.before)); got != tt.want {
+				t.Errorf("EvaluateRefundPercent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateRefundPercentKeepsRuleOrder(t *testing.T) {
+	policy := models.CancellationPolicy{Rules: []models.CancellationRule{
+		{HoursBefore: 2, RefundPercent: 50},
+		{HoursBefore: 24, RefundPercent: 100},
+	}}
+	start := time.Date(2024, 6, 1, 14, 0, 0, 0, time.UTC)
+	EvaluateRefundPercent(policy, start, start)
+
+	if policy.Rules[0].HoursBefore != 2 || policy.Rules[1].HoursBefore != 24 {
+		t.Errorf("EvaluateRefundPercent() reordered the park's rules: %+v", policy.Rules)
+	}
+}
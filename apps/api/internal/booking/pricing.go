@@ -0,0 +1,105 @@
+package booking
+
+import (
+	"math"
+
+	"skypark/internal/models"
+)
+
+// vatRate — ставка НДС Кыргызстана. Тарифы парков указываются с учётом НДС,
+// поэтому налог выделяется из итоговой суммы, а не начисляется сверху.
+const vatRate = 0.12
+
+// resolveAgeCategory определяет возрастную категорию гостя. Если указан
+// возраст, категория вычисляется по нему, иначе берётся переданная клиентом.
+func resolveAgeCategory(guest models.GuestInfo) models.AgeCategory {
+	if guest.Age != nil {
+		age := *guest.Age
+		switch {
+		case age < 3:
+			return models.AgeCategoryBaby
+		case age < 13:
+			return models.AgeCategoryChild
+		case age < 18:
+			return models.AgeCategoryTeen
+		case age < 60:
+			return models.AgeCategoryAdult
+		default:
+			return models.AgeCategorySenior
+		}
+	}
+
+	switch guest.AgeCategory {
+	case models.AgeCategoryBaby, models.AgeCategoryChild, models.AgeCategoryTeen,
+		models.AgeCategoryAdult, models.AgeCategorySenior:
+		return guest.AgeCategory
+	}
+	return models.AgeCategoryAdult
+}
+
+// priceForCategory возвращает тариф парка для возрастной категории.
+// Малыши проходят бесплатно, подростки — по детскому тарифу. Если тариф
+// категории не задан, используется базовая цена парка.
+func priceForCategory(park *models.Park, category models.AgeCategory) float64 {
+	var price float64
+	switch category {
+	case models.AgeCategoryBaby:
+		return 0
+	case models.AgeCategoryChild, models.AgeCategoryTeen:
+		price = park.ChildPrice
+	case models.AgeCategorySenior:
+		price = park.SeniorPrice
+	default:
+		price = park.AdultPrice
+	}
+
+	if price <= 0 {
+		price = park.BasePrice
+	}
+	return price
+}
+
+// PriceItems рассчитывает позиции бронирования по тарифам парка с учётом
+// надбавки или скидки слота pricePercent. Цены, переданные клиентом,
+// никогда не используются.
+func PriceItems(park *models.Park, guests []models.GuestInfo, pricePercent float64) []models.BookingItem {
+	items := make([]models.BookingItem, 0, len(guests))
+	for _, guest := range guests {
+		guest.AgeCategory = resolveAgeCategory(guest)
+		guest.TicketType = models.TicketTypeSingle
+		if guest.SpecialRequirements == nil {
+			guest.SpecialRequirements = models.StringArray{}
+		}
+
+		basePrice := roundMoney(priceForCategory(park, guest.AgeCategory) * (1 + pricePercent/100))
+		items = append(items, models.BookingItem{
+			ID:             models.GenerateUUID(),
+			GuestInfo:      guest,
+			BasePrice:      basePrice,
+			DiscountAmount: 0,
+			FinalPrice:     basePrice,
+		})
+	}
+	return items
+}
+
+// applyTotals заполняет итоговые суммы бронирования по его позициям
+// и, для праздников, по пакету и заказанным услугам.
+func applyTotals(booking *models.Booking) {
+	subtotal := partyExtrasTotal(booking.Party)
+	var discount float64
+	for _, item := range booking.Items {
+		subtotal += item.BasePrice
+		discount += item.DiscountAmount
+	}
+
+	booking.Subtotal = roundMoney(subtotal)
+	booking.DiscountAmount = roundMoney(discount)
+	booking.TotalAmount = roundMoney(subtotal - discount)
+	booking.TaxAmount = roundMoney(booking.TotalAmount * vatRate / (1 + vatRate))
+	booking.TotalGuests = len(booking.Items)
+}
+
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
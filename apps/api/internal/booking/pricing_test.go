@@ -0,0 +1,101 @@
+package booking
+
+import (
+	"testing"
+
+	"skypark/internal/models"
+)
+
+func testPark() *models.Park {
+	return &models.Park{
+		BasePrice:   400,
+		ChildPrice:  500,
+		AdultPrice:  300,
+		SeniorPrice: 200,
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestPriceItems(t *testing.T) {
+	tests := []struct {
+		name         string
+		park         *models.Park
+		guest        models.GuestInfo
+		pricePercent float64
+		wantCategory models.AgeCategory
+		wantPrice    float64
+	}{
+		{"child", testPark(), models.GuestInfo{Name: "Аскар", AgeCategory: models.AgeCategoryChild}, 0, models.AgeCategoryChild, 500},
+		{"teen pays the child price", testPark(), models.GuestInfo{Name: "Айгерим", AgeCategory: models.AgeCategoryTeen}, 0, models.AgeCategoryTeen, 500},
+		{"adult", testPark(), models.GuestInfo{Name: "Марат", AgeCategory: models.AgeCategoryAdult}, 0, models.AgeCategoryAdult, 300},
+		{"senior", testPark(), models.GuestInfo{Name: "Бакыт", AgeCategory: models.AgeCategorySenior}, 0, models.AgeCategorySenior, 200},
+		{"baby is free", testPark(), models.GuestInfo{Name: "Нурик", AgeCategory: models.AgeCategoryBaby}, 0, models.AgeCategoryBaby, 0},
+		{"baby stays free with a surcharge", testPark(), models.GuestInfo{Name: "Нурик", AgeCategory: models.AgeCategoryBaby}, 50, models.AgeCategoryBaby, 0},
+		// Категория вычисляется по возрасту, переданная клиентом игнорируется
+		{"age overrides the category", testPark(), models.GuestInfo{Name: "Аскар", Age: intPtr(2), AgeCategory: models.AgeCategoryAdult}, 0, models.AgeCategoryBaby, 0},
+		{"age 13 is a teen", testPark(), models.GuestInfo{Name: "Аскар", Age: intPtr(13)}, 0, models.AgeCategoryTeen, 500},
+		{"age 60 is a senior", testPark(), models.GuestInfo{Name: "Бакыт", Age: intPtr(60)}, 0, models.AgeCategorySenior, 200},
+		{"unknown category is adult", testPark(), models.GuestInfo{Name: "Марат", AgeCategory: "vip"}, 0, models.AgeCategoryAdult, 300},
+		{"missing tariff falls back to the base price", &models.Park{BasePrice: 400}, models.GuestInfo{Name: "Аскар", AgeCategory: models.AgeCategoryChild}, 0, models.AgeCategoryChild, 400},
+		{"slot surcharge", testPark(), models.GuestInfo{Name: "Аскар", AgeCategory: models.AgeCategoryChild}, 20, models.AgeCategoryChild, 600},
+		{"slot discount", testPark(), models.GuestInfo{Name: "Марат", AgeCategory: models.AgeCategoryAdult}, -15, models.AgeCategoryAdult, 255},
+		{"surcharge rounded to tyiyn", testPark(), models.GuestInfo{Name: "Марат", AgeCategory: models.AgeCategoryAdult}, 33.333, models.AgeCategoryAdult, 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := PriceItems(tt.park, []models.GuestInfo{tt.guest}, tt.pricePercent)
+			if len(items) != 1 {
+				t.Fatalf("PriceItems() returned %d items, want 1", len(items))
+			}
+			item := items[0]
+			if item.GuestInfo.AgeCategory != tt.wantCategory {
+				t.Errorf("category = %q, want %q", item.GuestInfo.AgeCategory, tt.wantCategory)
+			}
+			if item.BasePrice != tt.wantPrice || item.FinalPrice != tt.wantPrice || item.DiscountAmount != 0 {
+				t.Errorf("prices = %v/%v/%v, want base and final %v without discount",
+					item.BasePrice, item.DiscountAmount, item.FinalPrice, tt.wantPrice)
+			}
+			if item.GuestInfo.TicketType != models.TicketTypeSingle {
+				t.Errorf("ticket type = %q, want %q", item.GuestInfo.TicketType, models.TicketTypeSingle)
+			}
+		})
+	}
+}
+
+func TestPriceItemsGivesEachItemAnID(t *testing.T) {
+	guests := []models.GuestInfo{
+		{Name: "Аскар", AgeCategory: models.AgeCategoryChild},
+		{Name: "Аскар", AgeCategory: models.AgeCategoryChild},
+	}
+	items := PriceItems(testPark(), guests, 0)
+	if items[0].ID == items[1].ID {
+		t.Errorf("items share ID %s", items[0].ID)
+	}
+}
+
+func TestApplyTotalsExtractsVAT(t *testing.T) {
+	guests := []models.GuestInfo{
+		{Name: "Аскар", AgeCategory: models.AgeCategoryChild},
+		{Name: "Марат", AgeCategory: models.AgeCategoryAdult},
+		{Name: "Нурик", AgeCategory: models.AgeCategoryBaby},
+	}
+	booking := &models.Booking{Items: PriceItems(testPark(), guests, 0)}
+	booking.Items[1].DiscountAmount = 100
+
+	applyTotals(booking)
+
+	if booking.Subtotal != 800 || booking.DiscountAmount != 100 || booking.TotalAmount != 700 {
+		t.Errorf("subtotal/discount/total = %v/%v/%v, want 800/100/700",
+			booking.Subtotal, booking.DiscountAmount, booking.TotalAmount)
+	}
+	// Тарифы уже включают НДС 12%: налог — 12/112 итоговой суммы
+	if booking.TaxAmount != 75 {
+		t.Errorf("tax = %v, want 75", booking.TaxAmount)
+	}
+	if booking.TotalGuests != 3 {
+		t.Errorf("total guests = %d, want 3", booking.TotalGuests)
+	}
+}
@@ -0,0 +1,108 @@
+package booking
+
+import (
+	"bytes"
+	"fmt"
+	"image/jpeg"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Ограничения размера изображения QR-кода, как в models.QRCode
+const (
+	minQRImageSize = 64
+	maxQRImageSize = 1024
+)
+
+// QRImageOptions задаёт вид изображения QR-кода
+type QRImageOptions struct {
+	Format               string // png, svg или jpeg
+	Size                 int    // сторона изображения в пикселях
+	ErrorCorrectionLevel string // L, M, Q или H
+}
+
+var qrRecoveryLevels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+var qrContentTypes = map[string]string{
+	"png":  "image/png",
+	"svg":  "image/svg+xml",
+	"jpeg": "image/jpeg",
+}
+
+// Validate нормализует параметры и проверяет их допустимость
+func (o *QRImageOptions) Validate() error {
+	o.Format = strings.ToLower(o.Format)
+	if o.Format == "jpg" {
+		o.Format = "jpeg"
+	}
+	o.ErrorCorrectionLevel = strings.ToUpper(o.ErrorCorrectionLevel)
+
+	if _, ok := qrContentTypes[o.Format]; !ok {
+		return ErrInvalidQRFormat
+	}
+	if o.Size < minQRImageSize || o.Size > maxQRImageSize {
+		return ErrInvalidQRSize
+	}
+	if _, ok := qrRecoveryLevels[o.ErrorCorrectionLevel]; !ok {
+		return ErrInvalidQRLevel
+	}
+	return nil
+}
+
+// ContentType возвращает MIME-тип изображения
+func (o QRImageOptions) ContentType() string {
+	return qrContentTypes[o.Format]
+}
+
+// RenderQRCode рисует QR-код с данными data. Параметры должны пройти Validate.
+func RenderQRCode(data string, opts QRImageOptions) ([]byte, error) {
+	code, err := qrcode.New(data, qrRecoveryLevels[opts.ErrorCorrectionLevel])
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+
+	switch opts.Format {
+	case "svg":
+		return renderQRSVG(code.Bitmap(), opts.Size), nil
+	case "jpeg":
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, code.Image(opts.Size), &jpeg.Options{Quality: 95}); err != nil {
+			return nil, fmt.Errorf("failed to encode QR code: %w", err)
+		}
+		return buf.Bytes(), nil
+	default:
+		return code.PNG(opts.Size)
+	}
+}
+
+// renderQRSVG рисует модули QR-кода одним контуром. Координаты заданы в
+// модулях, поэтому изображение масштабируется без потери чёткости.
+func renderQRSVG(bitmap [][]bool, size int) []byte {
+	modules := len(bitmap)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, modules, modules)
+	for y, row := range bitmap {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			// Соседние тёмные модули строки объединяются в один прямоугольник
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", start, y, x-start, x-start)
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
@@ -0,0 +1,45 @@
+package booking
+
+import "testing"
+
+func TestQRImageOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      QRImageOptions
+		wantErr   error
+		wantFmt   string
+		wantLevel string
+	}{
+		{"png", QRImageOptions{Format: "png", Size: 256, ErrorCorrectionLevel: "M"}, nil, "png", "M"},
+		{"svg", QRImageOptions{Format: "svg", Size: 512, ErrorCorrectionLevel: "H"}, nil, "svg", "H"},
+		{"jpg is jpeg", QRImageOptions{Format: "jpg", Size: 256, ErrorCorrectionLevel: "L"}, nil, "jpeg", "L"},
+		{"case insensitive", QRImageOptions{Format: "PNG", Size: 256, ErrorCorrectionLevel: "q"}, nil, "png", "Q"},
+		{"minimum size", QRImageOptions{Format: "png", Size: 64, ErrorCorrectionLevel: "M"}, nil, "png", "M"},
+		{"maximum size", QRImageOptions{Format: "png", Size: 1024, ErrorCorrectionLevel: "M"}, nil, "png", "M"},
+		{"unknown format", QRImageOptions{Format: "gif", Size: 256, ErrorCorrectionLevel: "M"}, ErrInvalidQRFormat, "gif", "M"},
+		{"empty format", QRImageOptions{Size: 256, ErrorCorrectionLevel: "M"}, ErrInvalidQRFormat, "", "M"},
+		{"too small", QRImageOptions{Format: "png", Size: 63, ErrorCorrectionLevel: "M"}, ErrInvalidQRSize, "png", "M"},
+		{"too large", QRImageOptions{Format: "png", Size: 1025, ErrorCorrectionLevel: "M"}, ErrInvalidQRSize, "png", "M"},
+		{"unknown level", QRImageOptions{Format: "png", Size: 256, ErrorCorrectionLevel: "X"}, ErrInvalidQRLevel, "png", "X"},
+		{"empty level", QRImageOptions{Format: "png", Size: 256}, ErrInvalidQRLevel, "png", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			if err := opts.Validate(); err != tt.wantErr {
+				t.Fatalf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+			if opts.Format != tt.wantFmt || opts.ErrorCorrectionLevel != tt.wantLevel {
+				t.Errorf("normalized to %q/%q, want %q/%q", opts.Format, opts.ErrorCorrectionLevel, tt.wantFmt, tt.wantLevel)
+			}
+		})
+	}
+}
+
+func TestQRImageOptionsContentType(t *testing.T) {
+	for format, want := range map[string]string{"png": "image/png", "svg": "image/svg+xml", "jpeg": "image/jpeg"} {
+		if got := (QRImageOptions{Format: format}).ContentType(); got != want {
+			t.Errorf("ContentType(%s) = %q, want %q", format, got, want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package booking
+
+import (
+	"sync"
+	"time"
+)
+
+// attemptLimiter считает попытки по ключу (IP-адресу, телефону) в
+// фиксированном окне. Счётчики живут в памяти процесса, как SMS-коды.
+type attemptLimiter struct {
+	mu          sync.Mutex
+	window      time.Duration
+	attempts    map[string]*attemptWindow
+	nextCleanup time.Time
+}
+
+type attemptWindow struct {
+	count   int
+	resetAt time.Time
+}
+
+func newAttemptLimiter(window time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		window:   window,
+		attempts: make(map[string]*attemptWindow),
+	}
+}
+
+// allow учитывает попытку по key и сообщает, укладывается ли она в limit
+// попыток за окно
+func (l *attemptLimiter) allow(key string, limit int, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Истёкшие окна убираются не чаще раза за окно
+	if now.After(l.nextCleanup) {
+		for k, w := range l.attempts {
+			if !now.Before(w.resetAt) {
+				delete(l.attempts, k)
+			}
+		}
+		l.nextCleanup = now.Add(l.window)
+	}
+
+	w, ok := l.attempts[key]
+	if !ok || !now.Before(w.resetAt) {
+		w = &attemptWindow{resetAt: now.Add(l.window)}
+		l.attempts[key] = w
+	}
+	w.count++
+	return w.count <= limit
+}
@@ -0,0 +1,573 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"skypark/internal/models"
+)
+
+// UpdateBookingRequest описывает перенос или изменение бронирования.
+// Не переданные поля остаются прежними; Guests заменяет список гостей целиком.
+type UpdateBookingRequest struct {
+	VisitDate   *string             `json:"visitDate,omitempty"`
+	TimeSlot    *string             `json:"timeSlot,omitempty"`
+	Guests      []models.GuestInfo  `json:"guests,omitempty"`
+	ContactInfo *models.ContactInfo `json:"contactInfo,omitempty"`
+	Notes       *string             `json:"notes,omitempty"`
+}
+
+// Settlement описывает расчёт разницы в цене после изменения бронирования
+type Settlement struct {
+	Type    string                `json:"type"` // none, charge, refund
+	Amount  float64               `json:"amount"`
+	Payment *models.Payment       `json:"payment,omitempty"`
+	Refund  *models.RefundDetails `json:"refund,omitempty"`
+	// RefundPercent — доля удешевления, возвращаемая по политике отмены парка
+	RefundPercent float64                `json:"refundPercent,omitempty"`
+	Refunds       []models.RefundDetails `json:"refunds,omitempty"` // если возврат разбит по нескольким платежам
+}
+
+// UpdateResult описывает итог изменения бронирования
+type UpdateResult struct {
+	Booking    *models.Booking `json:"booking"`
+	PriceDelta float64         `json:"priceDelta"`
+	Settlement Settlement      `json:"settlement"`
+}
+
+// BookingChange — запись истории изменений в Booking.Metadata["changeHistory"]
+type BookingChange struct {
+	At         time.Time              `json:"at"`
+	By         uuid.UUID              `json:"by"`
+	Previous   map[string]interface{} `json:"previous"`
+	PriceDelta float64                `json:"priceDelta"`
+}
+
+// modifiableStatuses — статусы, в которых бронирование ещё можно изменить
+var modifiableStatuses = map[models.BookingStatus]bool{
+	models.BookingStatusDraft:          true,
+	models.BookingStatusPendingPayment: true,
+	models.BookingStatusConfirmed:      true,
+}
+
+// UpdateBooking переносит бронирование на другую дату или слот и/или меняет
+// состав гостей. Вместимость проверяется заново, цены пересчитываются по
+// тарифу парка, а разница оформляется доплатой или частичным возвратом.
+// Билеты на добавленных гостей выпускаются после оплаты доплаты, до тех пор
+// бронирование больше не меняется.
+func (s *BookingService) UpdateBooking(bookingID, userID uuid.UUID, req UpdateBookingRequest, version *int64) (*UpdateResult, error) {
+	if req.Guests != nil {
+		if err := validateGuests(req.Guests); err != nil {
+			return nil, err
+		}
+	}
+	if req.TimeSlot != nil && !timeSlotPattern.MatchString(*req.TimeSlot) {
+		return nil, ErrInvalidTimeSlot
+	}
+	if req.ContactInfo != nil {
+		if err := validateContactInfo(req.ContactInfo); err != nil {
+			return nil, err
+		}
+	}
+
+	var result *UpdateResult
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		booking, err := loadBookingForUpdate(tx, bookingID)
+		if err != nil {
+			return err
+		}
+		if booking.UserID != userID {
+			return ErrBookingNotFound
+		}
+		if err := checkVersion(booking, version); err != nil {
+			return err
+		}
+		if !modifiableStatuses[booking.Status] || !reschedulable(booking) {
+			return ErrBookingNotModifiable
+		}
+		// Начавшийся визит не переносится: гости уже могли пройти по билетам
+		if !time.Now().Before(visitStart(booking)) {
+			return ErrBookingNotModifiable
+		}
+		if _, ok := booking.Metadata[surchargePaymentKey]; ok {
+			return ErrSurchargeDue
+		}
+
+		result, err = s.applyUpdate(tx, booking, userID, req)
+		if err != nil {
+			return err
+		}
+		return reloadVersion(tx, result.Booking)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Прежний слот или часть мест в нём могли освободиться
+	s.CapacityReleased(result.Booking.ParkID)
+	return result, nil
+}
+
+// reschedulable сообщает, можно ли перенести бронирование. Покупка абонемента
+// не привязана к слоту, а праздник держит комнату по своему расписанию.
+func reschedulable(booking *models.Booking) bool {
+	return booking.BookingType != models.BookingTypePass && booking.BookingType != models.BookingTypeParty
+}
+
+func (s *BookingService) applyUpdate(tx *gorm.DB, booking *models.Booking, actorID uuid.UUID, req UpdateBookingRequest) (*UpdateResult, error) {
+	var park models.Park
+	if err := tx.Where("id = ?", booking.ParkID).First(&park).Error; err != nil {
+		return nil, err
+	}
+	// Удешевление — частичная отмена: возврат считается по политике парка
+	// от прежнего времени визита, иначе её можно обойти, убрав гостей
+	refundPercent := EvaluateRefundPercent(cancellationPolicyFor(&park), visitStart(booking), time.Now())
+
+	visitDate := booking.VisitDate
+	if req.VisitDate != nil {
+		parsed, err := parseVisitDate(*req.VisitDate)
+		if err != nil {
+			return nil, err
+		}
+		visitDate = parsed
+	} else {
+		visitDate = time.Date(visitDate.Year(), visitDate.Month(), visitDate.Day(), 0, 0, 0, 0, parkLocation())
+	}
+
+	timeSlot := ""
+	if booking.TimeSlot != nil {
+		timeSlot = *booking.TimeSlot
+	}
+	if req.TimeSlot != nil {
+		timeSlot = *req.TimeSlot
+	}
+
+	guests := req.Guests
+	if guests == nil {
+		guests = make([]models.GuestInfo, 0, len(booking.Items))
+		for _, item := range booking.Items {
+			guests = append(guests, item.GuestInfo)
+		}
+	}
+
+	schedule, err := s.buildSchedule(tx, &park, visitDate, len(guests), booking.ID)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.IsClosed {
+		return nil, ErrParkClosed
+	}
+	slot, ok := schedule.findSlot(timeSlot)
+	if !ok || slot.IsPast {
+		return nil, ErrInvalidTimeSlot
+	}
+
+	if err := reserveCapacity(tx, &park, visitDate, timeSlot, len(guests), booking.ID); err != nil {
+		return nil, err
+	}
+
+	previous := map[string]interface{}{
+		"visitDate":      booking.VisitDate.Format("2006-01-02"),
+		"timeSlot":       booking.TimeSlot,
+		"duration":       booking.Duration,
+		"items":          booking.Items,
+		"totalGuests":    booking.TotalGuests,
+		"subtotal":       booking.Subtotal,
+		"discountAmount": booking.DiscountAmount,
+		"taxAmount":      booking.TaxAmount,
+		"totalAmount":    booking.TotalAmount,
+	}
+	oldTotal := booking.TotalAmount
+	moved := booking.VisitDate.Format("2006-01-02") != visitDate.Format("2006-01-02") ||
+		booking.TimeSlot == nil || *booking.TimeSlot != timeSlot
+
+	booking.VisitDate = visitDate
+	booking.TimeSlot = &timeSlot
+	booking.Duration = slot.Duration
+	previousItems := booking.Items
+	booking.Items = PriceItems(&park, guests, slot.PricePercent)
+	keepItemIDs(previousItems, booking.Items)
+	if err := checkTransferredGuests(tx, booking); err != nil {
+		return nil, err
+	}
+	booking.Discounts = []models.DiscountInfo{}
+	if err := applyPasses(tx, booking); err != nil {
+		return nil, err
+	}
+	if booking.BookingType == models.BookingTypeGroup {
+		settings := groupSettingsFor(&park)
+		if len(booking.Items) < settings.MinGroupSize {
+			return nil, fmt.Errorf("%w: at least %d guests", ErrGroupTooSmall, settings.MinGroupSize)
+		}
+		applyGroupPricing(&park, settings, booking)
+	}
+	applyTotals(booking)
+
+	if req.ContactInfo != nil {
+		previous["contactInfo"] = booking.ContactInfo
+		booking.ContactInfo = *req.ContactInfo
+	}
+	if req.Notes != nil {
+		booking.Notes = req.Notes
+	}
+	if booking.Status == models.BookingStatusPendingPayment {
+		if booking.BookingType == models.BookingTypeGroup {
+			if err := updateOpenInvoices(tx, booking); err != nil {
+				return nil, err
+			}
+		}
+		// Удержание продлевается только при переносе на другой слот, иначе
+		// повтор того же запроса держал бы места бесконечно
+		if moved || booking.HoldExpiresAt == nil {
+			holdExpiresAt := time.Now().Add(s.config.HoldTTL)
+			if booking.BookingType == models.BookingTypeGroup {
+				holdExpiresAt = invoiceDueDate(&park, booking, time.Now())
+			}
+			booking.HoldExpiresAt = &holdExpiresAt
+		}
+	}
+
+	delta := roundMoney(booking.TotalAmount - oldTotal)
+	settlement, err := settleDifference(tx, booking, actorID, delta, refundPercent)
+	if err != nil {
+		return nil, err
+	}
+	if settlement.Type == "charge" {
+		holdAddedItems(booking, previousItems, settlement.Payment.ID)
+	}
+
+	booking.Metadata = appendChangeHistory(booking.Metadata, BookingChange{
+		At:         time.Now(),
+		By:         actorID,
+		Previous:   previous,
+		PriceDelta: delta,
+	})
+
+	if err := tx.Model(booking).
+		Select("visit_date", "time_slot", "duration", "items", "total_guests", "subtotal",
+			"discount_amount", "tax_amount", "total_amount", "contact_info", "notes",
+			"hold_expires_at", "payment_status", "discounts", "metadata").
+		Updates(booking).Error; err != nil {
+		return nil, fmt.Errorf("failed to update booking: %w", err)
+	}
+	if req.VisitDate != nil || req.TimeSlot != nil || req.Guests != nil {
+		if err := s.reissueTickets(tx, booking, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	return &UpdateResult{
+		Booking:    booking,
+		PriceDelta: delta,
+		Settlement: settlement,
+	}, nil
+}
+
+// settleDifference оформляет разницу в цене подтверждённого бронирования:
+// доплату отдельным платежом либо частичный возврат refundPercent процентов
+// удешевления по проведённым платежам. Для черновиков и бронирований,
+// ожидающих оплаты, меняется только сумма к оплате.
+func settleDifference(tx *gorm.DB, booking *models.Booking, actorID uuid.UUID, delta, refundPercent float64) (Settlement, error) {
+	settlement := Settlement{Type: "none"}
+	if delta == 0 {
+		return settlement, nil
+	}
+
+	if delta > 0 {
+		if booking.Status == models.BookingStatusDraft || booking.Status == models.BookingStatusPendingPayment {
+			return settlement, nil
+		}
+		// Подтверждённое бронирование могло обойтись без платежа: его
+		// подтвердил администратор или все гости прошли по абонементам.
+		// Доплата всё равно нужна, её вносят на кассе или по счёту.
+		payment, err := refundablePayment(tx, booking.ID)
+		if err != nil {
+			return settlement, err
+		}
+
+		now := time.Now()
+		description := "Доплата за изменение бронирования"
+		charge := models.Payment{
+			BookingID:      booking.ID,
+			UserID:         booking.UserID,
+			Method:         models.PaymentMethodCash,
+			Status:         models.PaymentStatusPending,
+			Amount:         delta,
+			OriginalAmount: delta,
+			NetAmount:      delta,
+			Currency:       booking.Currency,
+			Details:        models.PaymentDetails{Provider: models.PaymentProviderInternal, Metadata: models.JSONB{}},
+			Refunds:        []models.RefundDetails{},
+			InitiatedAt:    &now,
+			Description:    &description,
+			Metadata: models.JSONB{
+				"type": "booking_change_surcharge",
+			},
+		}
+		if payment != nil {
+			charge.Method = payment.Method
+			charge.Currency = payment.Currency
+			charge.Details.Provider = payment.Details.Provider
+			charge.Metadata["originalPaymentId"] = payment.ID
+		}
+		if err := tx.Create(&charge).Error; err != nil {
+			return settlement, fmt.Errorf("failed to create surcharge payment: %w", err)
+		}
+
+		booking.PaymentStatus = models.PaymentStatusPending
+		settlement.Type = "charge"
+		settlement.Amount = delta
+		settlement.Payment = &charge
+		return settlement, nil
+	}
+
+	// Бронирование могло оплачиваться несколькими платежами, возврат
+	// распределяется по ним так же, как при отмене
+	payments, err := refundablePayments(tx, booking.ID)
+	if err != nil {
+		return settlement, err
+	}
+	if len(payments) == 0 {
+		return settlement, nil
+	}
+	settlement.RefundPercent = refundPercent
+
+	amount := roundMoney(-delta * refundPercent / 100)
+	if refundable := refundableTotal(payments); amount > refundable {
+		amount = refundable
+	}
+	if amount <= 0 {
+		return settlement, nil
+	}
+
+	description := "Частичный возврат за изменение бронирования"
+	refunds, err := spreadRefund(tx, payments, amount, func(part float64) models.RefundDetails {
+		return models.RefundDetails{
+			ID:          models.GenerateUUID(),
+			Amount:      part,
+			Reason:      models.RefundReasonUserRequest,
+			Description: &description,
+			RequestedBy: actorID,
+			RequestedAt: time.Now(),
+			Status:      "pending",
+			Metadata: models.JSONB{
+				"type":          "booking_change",
+				"refundPercent": refundPercent,
+			},
+		}
+	})
+	if err != nil {
+		return settlement, err
+	}
+
+	booking.PaymentStatus = models.PaymentStatusPartiallyRefunded
+	settlement.Type = "refund"
+	settlement.Amount = amount
+	settlement.Refund = &refunds[0]
+	settlement.Refunds = refunds
+	return settlement, nil
+}
+
+// surchargePaymentKey и unpaidItemsKey — ключи Booking.Metadata, пока доплата
+// за изменение не внесена: платёж доплаты и добавленные гости, которым
+// билеты ещё не выпущены
+const (
+	surchargePaymentKey = "surchargePaymentId"
+	unpaidItemsKey      = "unpaidItems"
+)
+
+// holdAddedItems откладывает выпуск билетов на гостей, добавленных
+// изменением, до оплаты доплаты. Прежние гости своё место уже оплатили.
+func holdAddedItems(booking *models.Booking, previous []models.BookingItem, surchargeID uuid.UUID) {
+	paid := make(map[uuid.UUID]bool, len(previous))
+	for _, item := range previous {
+		paid[item.ID] = true
+	}
+	added := []string{}
+	for _, item := range booking.Items {
+		if !paid[item.ID] {
+			added = append(added, item.ID.String())
+		}
+	}
+
+	if booking.Metadata == nil {
+		booking.Metadata = models.JSONB{}
+	}
+	booking.Metadata[surchargePaymentKey] = surchargeID
+	booking.Metadata[unpaidItemsKey] = added
+}
+
+// unpaidItems возвращает позиции, билеты на которые ждут оплаты доплаты.
+// После чтения из базы список приходит как []interface{}.
+func unpaidItems(booking *models.Booking) map[string]bool {
+	unpaid := map[string]bool{}
+	switch items := booking.Metadata[unpaidItemsKey].(type) {
+	case []string:
+		for _, id := range items {
+			unpaid[id] = true
+		}
+	case []interface{}:
+		for _, id := range items {
+			if s, ok := id.(string); ok {
+				unpaid[s] = true
+			}
+		}
+	}
+	return unpaid
+}
+
+// RecordSurchargePaymentRequest описывает поступление доплаты за изменение
+type RecordSurchargePaymentRequest struct {
+	Reference string `json:"reference" binding:"required"` // номер транзакции или чека
+}
+
+// RecordSurchargePayment отмечает доплату за изменение бронирования
+// оплаченной и выпускает билеты на добавленных гостей (администратор)
+func (s *BookingService) RecordSurchargePayment(bookingID, adminID uuid.UUID, req RecordSurchargePaymentRequest, version *int64) (*UpdateResult, error) {
+	result := &UpdateResult{Settlement: Settlement{Type: "charge"}}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		booking, err := loadBookingForUpdate(tx, bookingID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(booking, version); err != nil {
+			return err
+		}
+
+		var surcharge models.Payment
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("booking_id = ? AND status = ? AND metadata->>'type' = 'booking_change_surcharge'",
+				booking.ID, models.PaymentStatusPending).
+			Order("created_at DESC").
+			First(&surcharge).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSurchargeNotFound
+			}
+			return err
+		}
+
+		now := time.Now()
+		reference := strings.TrimSpace(req.Reference)
+		surcharge.Status = models.PaymentStatusCompleted
+		surcharge.CapturedAt = &now
+		surcharge.Details.ProviderTransactionID = &reference
+		if surcharge.Metadata == nil {
+			surcharge.Metadata = models.JSONB{}
+		}
+		surcharge.Metadata["paidRecordedBy"] = adminID
+		if err := tx.Model(&surcharge).
+			Select("status", "captured_at", "details", "metadata").
+			Updates(&surcharge).Error; err != nil {
+			return fmt.Errorf("failed to record surcharge payment: %w", err)
+		}
+
+		booking.PaymentStatus = models.PaymentStatusCompleted
+		delete(booking.Metadata, surchargePaymentKey)
+		delete(booking.Metadata, unpaidItemsKey)
+		if err := tx.Model(booking).Select("payment_status", "metadata").Updates(booking).Error; err != nil {
+			return fmt.Errorf("failed to update booking payment status: %w", err)
+		}
+		if booking.Status == models.BookingStatusConfirmed {
+			if err := s.issueTickets(tx, booking, now); err != nil {
+				return err
+			}
+		}
+
+		result.Booking = booking
+		result.Settlement.Amount = surcharge.Amount
+		result.Settlement.Payment = &surcharge
+		return reloadVersion(tx, booking)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// cancelOpenSurcharges аннулирует неоплаченную доплату отменённого бронирования
+func cancelOpenSurcharges(tx *gorm.DB, booking *models.Booking) error {
+	err := tx.Model(&models.Payment{}).
+		Where("booking_id = ? AND status = ? AND metadata->>'type' = 'booking_change_surcharge'",
+			booking.ID, models.PaymentStatusPending).
+		Update("status", models.PaymentStatusCancelled).Error
+	if err != nil {
+		return fmt.Errorf("failed to cancel open surcharges: %w", err)
+	}
+	if _, ok := booking.Metadata[surchargePaymentKey]; !ok {
+		return nil
+	}
+	delete(booking.Metadata, surchargePaymentKey)
+	delete(booking.Metadata, unpaidItemsKey)
+	if err := tx.Model(booking).Update("metadata", booking.Metadata).Error; err != nil {
+		return fmt.Errorf("failed to update booking metadata: %w", err)
+	}
+	return nil
+}
+
+// keepItemIDs переносит идентификаторы прежних позиций на тех же гостей в
+// новом списке: по ним за гостями остаются выпущенные и переданные билеты
+func keepItemIDs(previous, items []models.BookingItem) {
+	used := make([]bool, len(previous))
+	for i := range items {
+		for j := range previous {
+			if !used[j] && sameGuest(previous[j].GuestInfo, items[i].GuestInfo) {
+				items[i].ID = previous[j].ID
+				used[j] = true
+				break
+			}
+		}
+	}
+}
+
+// sameGuest сообщает, что позиции описывают одного и того же гостя
+func sameGuest(a, b models.GuestInfo) bool {
+	if !strings.EqualFold(strings.TrimSpace(a.Name), strings.TrimSpace(b.Name)) || a.AgeCategory != b.AgeCategory {
+		return false
+	}
+	if a.Age == nil || b.Age == nil {
+		return a.Age == nil && b.Age == nil
+	}
+	return *a.Age == *b.Age
+}
+
+// checkTransferredGuests не даёт убрать из бронирования гостя, чей билет
+// передан другому человеку: иначе билет получателя аннулировался бы и
+// вернулся покупателю без ведома получателя
+func checkTransferredGuests(tx *gorm.DB, booking *models.Booking) error {
+	var itemIDs []string
+	err := tx.Model(&models.Ticket{}).
+		Where("booking_id = ? AND status IN ? AND usage_count = 0 AND user_id <> ? AND deleted_at IS NULL",
+			booking.ID, []models.TicketStatus{models.TicketStatusPending, models.TicketStatusActive}, booking.UserID).
+		Pluck("metadata->>'bookingItemId'", &itemIDs).Error
+	if err != nil {
+		return err
+	}
+
+	kept := make(map[string]bool, len(booking.Items))
+	for _, item := range booking.Items {
+		kept[item.ID.String()] = true
+	}
+	for _, itemID := range itemIDs {
+		if !kept[itemID] {
+			return ErrTransferredGuestRemoved
+		}
+	}
+	return nil
+}
+
+// appendChangeHistory добавляет запись в историю изменений метаданных
+func appendChangeHistory(metadata models.JSONB, change BookingChange) models.JSONB {
+	if metadata == nil {
+		metadata = models.JSONB{}
+	}
+	history, _ := metadata["changeHistory"].([]interface{})
+	metadata["changeHistory"] = append(history, change)
+	return metadata
+}
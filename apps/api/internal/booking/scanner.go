@@ -0,0 +1,597 @@
+package booking
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"skypark/internal/models"
+	"skypark/internal/ticketsign"
+)
+
+// Сканеры на турникетах работают и без связи. Они скачивают подписанный
+// манифест билетов парка на день, подписи QR-кодов проверяют открытыми
+// ключами, а проходы копят и выгружают пачками после восстановления связи.
+//
+// Выгрузки приходят с опозданием и в любом порядке, поэтому итог считается
+// заново по всем сканированиям билета: они упорядочиваются по (scanned_at,
+// device_id, scan_id) и проверяются по очереди. Проходы сверх разрешённого
+// помечаются конфликтами для персонала. Порядок выгрузки на итог не влияет.
+
+// manifestCursorOverlap — насколько раньше курсора ищутся изменения. Время
+// изменения строки — начало её транзакции, поэтому изменения, записанные
+// незадолго до выдачи курсора, могут стать видны позже. Повторно выданные
+// билеты сканер просто перезаписывает.
+const manifestCursorOverlap = 5 * time.Minute
+
+// maxScanUploadSize ограничивает пачку сканирований в одной выгрузке
+const maxScanUploadSize = 500
+
+// ManifestTicket — билет в манифесте сканера
+type ManifestTicket struct {
+	TicketID      uuid.UUID            `json:"ticketId"`
+	TicketNumber  string               `json:"ticketNumber,omitempty"`
+	Type          models.TicketType    `json:"type"`
+	Status        models.TicketStatus  `json:"status"`
+	BookingStatus models.BookingStatus `json:"bookingStatus"`
+	ValidFrom     time.Time            `json:"validFrom"`
+	ValidTo       time.Time            `json:"validTo"`
+	MaxUsages     int                  `json:"maxUsages"`
+	UsageCount    int                  `json:"usageCount"`
+	LastUsedAt    *time.Time           `json:"lastUsedAt,omitempty"`
+	QRHash        string               `json:"qrHash"` // SHA-256 действующего QR-кода, hex
+	HolderName    string               `json:"holderName"`
+	Version       int64                `json:"version"`
+	Removed       bool                 `json:"removed,omitempty"` // билет удалён, сканер должен его забыть
+}
+
+// ScannerManifest — билеты парка на день. Полный манифест заменяет данные
+// сканера, а манифест изменений (Full = false) дополняет их.
+type ScannerManifest struct {
+	ParkID            uuid.UUID        `json:"parkId"`
+	Date              string           `json:"date"`
+	GeneratedAt       time.Time        `json:"generatedAt"`
+	Full              bool             `json:"full"`
+	Cursor            string           `json:"cursor"` // передаётся в следующем запросе
+	EarlyEntryMinutes int              `json:"earlyEntryMinutes"`
+	Tickets           []ManifestTicket `json:"tickets"`
+}
+
+// manifestCursor — содержимое курсора манифеста
+type manifestCursor struct {
+	ParkID uuid.UUID
+	Date   string
+	At     time.Time
+}
+
+func (c manifestCursor) encode() string {
+	raw := fmt.Sprintf("%s|%s|%d", c.ParkID, c.Date, c.At.UnixMilli())
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeManifestCursor(cursor string) (*manifestCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidManifestCursor
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return nil, ErrInvalidManifestCursor
+	}
+	parkID, err := uuid.Parse(parts[0])
+	if err != nil {
+		return nil, ErrInvalidManifestCursor
+	}
+	millis, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidManifestCursor
+	}
+	return &manifestCursor{ParkID: parkID, Date: parts[1], At: time.UnixMilli(millis)}, nil
+}
+
+// GetScannerManifest возвращает подписанный манифест билетов парка,
+// действующих в день date. С курсором из предыдущего манифеста того же парка
+// и дня возвращаются только билеты, изменённые после него, включая
+// аннулированные и удалённые.
+func (s *BookingService) GetScannerManifest(parkID uuid.UUID, date time.Time, cursor string) (*ticketsign.SignedPayload, error) {
+	day := date.Format("2006-01-02")
+	var since *time.Time
+	if cursor != "" {
+		decoded, err := decodeManifestCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		if decoded.ParkID != parkID || decoded.Date != day {
+			return nil, ErrInvalidManifestCursor
+		}
+		from := decoded.At.Add(-manifestCursorOverlap)
+		since = &from
+	}
+
+	var park models.Park
+	if err := s.db.Select("id").Where("id = ? AND deleted_at IS NULL", parkID).First(&park).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrParkNotFound
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, parkLocation())
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	query := s.db.Model(&models.Ticket{}).
+		Select("tickets.*").
+		Joins("JOIN bookings b ON b.id = tickets.booking_id").
+		Where("tickets.park_id = ? AND tickets.valid_from < ? AND tickets.valid_to > ?",
+			parkID, dayEnd.Add(s.config.EarlyEntryWindow), dayStart)
+	if since != nil {
+		// Бронирование могли отменить, не меняя билет
+		query = query.Where("(tickets.updated_at > ? OR b.updated_at > ?)", *since, *since)
+	} else {
+		query = query.Where("tickets.deleted_at IS NULL")
+	}
+	var tickets []models.Ticket
+	if err := query.Order("tickets.id").Find(&tickets).Error; err != nil {
+		return nil, fmt.Errorf("failed to load manifest tickets: %w", err)
+	}
+
+	bookingStatuses := map[uuid.UUID]models.BookingStatus{}
+	if len(tickets) > 0 {
+		bookingIDs := make([]uuid.UUID, 0, len(tickets))
+		for _, ticket := range tickets {
+			bookingIDs = append(bookingIDs, ticket.BookingID)
+		}
+		var bookings []models.Booking
+		if err := s.db.Select("id", "status").Where("id IN ?", bookingIDs).Find(&bookings).Error; err != nil {
+			return nil, err
+		}
+		for _, booking := range bookings {
+			bookingStatuses[booking.ID] = booking.Status
+		}
+	}
+
+	manifest := ScannerManifest{
+		ParkID:            parkID,
+		Date:              day,
+		GeneratedAt:       now,
+		Full:              since == nil,
+		Cursor:            manifestCursor{ParkID: parkID, Date: day, At: now}.encode(),
+		EarlyEntryMinutes: int(s.config.EarlyEntryWindow / time.Minute),
+		Tickets:           make([]ManifestTicket, 0, len(tickets)),
+	}
+	for _, ticket := range tickets {
+		entry := ManifestTicket{
+			TicketID:      ticket.ID,
+			TicketNumber:  ticket.TicketNumber,
+			Type:          ticket.Type,
+			Status:        ticket.Status,
+			BookingStatus: bookingStatuses[ticket.BookingID],
+			ValidFrom:     ticket.ValidFrom,
+			ValidTo:       ticket.ValidTo,
+			MaxUsages:     ticket.MaxUsages,
+			UsageCount:    ticket.UsageCount,
+			QRHash:        qrHash(ticket.QRCode.Data),
+			HolderName:    ticket.HolderName,
+			Version:       ticket.Version,
+			Removed:       ticket.DeletedAt != nil,
+		}
+		for _, validation := range ticket.Validations {
+			if entry.LastUsedAt == nil || validation.ValidatedAt.After(*entry.LastUsedAt) {
+				usedAt := validation.ValidatedAt
+				entry.LastUsedAt = &usedAt
+			}
+		}
+		manifest.Tickets = append(manifest.Tickets, entry)
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	return s.signer.SignPayload(data)
+}
+
+// qrHash возвращает отпечаток QR-кода, по которому сканер отличает
+// действующий код билета от заменённого
+func qrHash(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// OfflineScan — сканирование, выполненное устройством без связи
+type OfflineScan struct {
+	ScanID    string    `json:"scanId" binding:"required,max=100"`
+	QRData    string    `json:"qrData" binding:"required"`
+	ScannedAt time.Time `json:"scannedAt" binding:"required"`
+	Admitted  bool      `json:"admitted"`         // пропустило ли устройство посетителя
+	Reason    *string   `json:"reason,omitempty"` // причина отказа на устройстве
+	Location  *string   `json:"location,omitempty"`
+}
+
+// UploadScansRequest — пачка сканирований одного устройства
+type UploadScansRequest struct {
+	ParkID   uuid.UUID     `json:"parkId" binding:"required"`
+	DeviceID string        `json:"deviceId" binding:"required,max=100"`
+	Scans    []OfflineScan `json:"scans" binding:"required,min=1,dive"`
+}
+
+// ScanResult — итог сканирования после сверки с сервером
+type ScanResult struct {
+	ScanID   string                   `json:"scanId"`
+	TicketID *uuid.UUID               `json:"ticketId,omitempty"`
+	Outcome  models.TicketScanOutcome `json:"outcome"`
+	Reason   *string                  `json:"reason,omitempty"`
+}
+
+// UploadScans принимает сканирования, накопленные устройством без связи.
+// Повторная выгрузка тех же scanId ничего не меняет. Возвращает итог по
+// каждому сканированию: принятые проходы списываются с билетов, проходы,
+// которые сервер принять не может, становятся конфликтами.
+func (s *BookingService) UploadScans(staffID uuid.UUID, req UploadScansRequest) ([]ScanResult, error) {
+	if len(req.Scans) > maxScanUploadSize {
+		return nil, ErrScanBatchTooLarge
+	}
+	deviceID := strings.TrimSpace(req.DeviceID)
+
+	scanIDs := make([]string, 0, len(req.Scans))
+	seen := map[string]bool{}
+	for _, scan := range req.Scans {
+		if !seen[scan.ScanID] {
+			seen[scan.ScanID] = true
+			scanIDs = append(scanIDs, scan.ScanID)
+		}
+	}
+
+	var existing []string
+	err := s.db.Model(&models.TicketScan{}).
+		Where("device_id = ? AND scan_id IN ?", deviceID, scanIDs).
+		Pluck("scan_id", &existing).Error
+	if err != nil {
+		return nil, err
+	}
+	uploaded := map[string]bool{}
+	for _, id := range existing {
+		uploaded[id] = true
+	}
+
+	byTicket := map[uuid.UUID][]models.TicketScan{}
+	var unmatched []models.TicketScan
+	for _, scan := range req.Scans {
+		if uploaded[scan.ScanID] {
+			continue
+		}
+		uploaded[scan.ScanID] = true
+
+		record := models.TicketScan{
+			ParkID:         req.ParkID,
+			DeviceID:       deviceID,
+			ScanID:         scan.ScanID,
+			Source:         models.TicketScanSourceOffline,
+			QRData:         strings.TrimSpace(scan.QRData),
+			ScannedAt:      scan.ScannedAt,
+			ScannedBy:      &staffID,
+			Location:       scan.Location,
+			DeviceAdmitted: scan.Admitted,
+			DeviceReason:   scan.Reason,
+			Outcome:        models.TicketScanAccepted,
+		}
+		if !scan.Admitted {
+			record.Outcome = models.TicketScanRefused
+		}
+
+		claims, err := s.signer.Verify(record.QRData)
+		if err != nil {
+			if scan.Admitted {
+				markScanConflict(&record, AdmissionInvalidQR)
+			}
+			unmatched = append(unmatched, record)
+			continue
+		}
+		ticketID := claims.TicketID
+		record.TicketID = &ticketID
+		byTicket[ticketID] = append(byTicket[ticketID], record)
+	}
+
+	if len(unmatched) > 0 {
+		if err := s.db.Create(&unmatched).Error; err != nil {
+			return nil, fmt.Errorf("failed to record scans: %w", err)
+		}
+	}
+
+	ticketIDs := make([]uuid.UUID, 0, len(byTicket))
+	for ticketID := range byTicket {
+		ticketIDs = append(ticketIDs, ticketID)
+	}
+	sort.Slice(ticketIDs, func(i, j int) bool { return ticketIDs[i].String() < ticketIDs[j].String() })
+
+	for _, ticketID := range ticketIDs {
+		scans := byTicket[ticketID]
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			var bookingID uuid.UUID
+			err := tx.Model(&models.Ticket{}).Select("booking_id").Where("id = ? AND deleted_at IS NULL", ticketID).Scan(&bookingID).Error
+			if err != nil {
+				return err
+			}
+			if bookingID == uuid.Nil {
+				// Подпись верна, но билета нет: фиксируем проход без привязки
+				for i := range scans {
+					scans[i].TicketID = nil
+					if scans[i].DeviceAdmitted {
+						markScanConflict(&scans[i], AdmissionTicketNotFound)
+					}
+				}
+				return tx.Create(&scans).Error
+			}
+
+			booking, err := loadBookingForUpdate(tx, bookingID)
+			if err != nil {
+				return err
+			}
+			ticket, err := loadTicketForUpdate(tx, ticketID)
+			if err != nil {
+				return err
+			}
+			if err := tx.Create(&scans).Error; err != nil {
+				return fmt.Errorf("failed to record scans: %w", err)
+			}
+			return s.resolveTicketScans(tx, booking, ticket, staffID)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var records []models.TicketScan
+	err = s.db.Where("device_id = ? AND scan_id IN ?", deviceID, scanIDs).Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	byScanID := make(map[string]models.TicketScan, len(records))
+	for _, record := range records {
+		byScanID[record.ScanID] = record
+	}
+	results := make([]ScanResult, 0, len(scanIDs))
+	for _, scanID := range scanIDs {
+		record := byScanID[scanID]
+		results = append(results, ScanResult{
+			ScanID:   scanID,
+			TicketID: record.TicketID,
+			Outcome:  record.Outcome,
+			Reason:   record.Reason,
+		})
+	}
+	return results, nil
+}
+
+// markScanConflict помечает сканирование конфликтом с причиной reason
+func markScanConflict(scan *models.TicketScan, reason AdmissionReason) {
+	code := string(reason)
+	scan.Outcome = models.TicketScanConflict
+	scan.Reason = &code
+}
+
+// recordOnlineScan записывает проход, разрешённый сервером при сканировании
+// со связью, чтобы его учитывала сверка офлайн-сканирований
+func recordOnlineScan(tx *gorm.DB, ticket *models.Ticket, req ValidateTicketRequest, staffID uuid.UUID, now time.Time) (*models.TicketScan, error) {
+	scan := models.TicketScan{
+		TicketID:       &ticket.ID,
+		ParkID:         req.ParkID,
+		DeviceID:       strings.TrimSpace(req.DeviceID),
+		ScanID:         models.GenerateUUID().String(),
+		Source:         models.TicketScanSourceOnline,
+		QRData:         strings.TrimSpace(req.QRData),
+		ScannedAt:      now,
+		ScannedBy:      &staffID,
+		Location:       req.Location,
+		DeviceAdmitted: true,
+		Outcome:        models.TicketScanAccepted,
+	}
+	if err := tx.Create(&scan).Error; err != nil {
+		return nil, fmt.Errorf("failed to record scan: %w", err)
+	}
+	return &scan, nil
+}
+
+// scanEntry — проход по билету при пересчёте: сканирование или проверка,
+// записанная без него (например, списание абонемента при check-in)
+type scanEntry struct {
+	at         time.Time
+	deviceID   string
+	scanID     string
+	scan       *models.TicketScan
+	validation *models.TicketValidation
+}
+
+// resolveTicketScans заново решает судьбу всех проходов по билету в
+// детерминированном порядке и пересчитывает проходы билета. Билет и его
+// бронирование должны быть заблокированы.
+func (s *BookingService) resolveTicketScans(tx *gorm.DB, booking *models.Booking, ticket *models.Ticket, actorID uuid.UUID) error {
+	var scans []models.TicketScan
+	err := tx.Where("ticket_id = ? AND outcome <> ? AND deleted_at IS NULL", ticket.ID, models.TicketScanRefused).
+		Find(&scans).Error
+	if err != nil {
+		return err
+	}
+
+	entries := make([]scanEntry, 0, len(scans)+len(ticket.Validations))
+	for i := range scans {
+		entries = append(entries, scanEntry{
+			at:       scans[i].ScannedAt,
+			deviceID: scans[i].DeviceID,
+			scanID:   scans[i].ScanID,
+			scan:     &scans[i],
+		})
+	}
+	for i := range ticket.Validations {
+		if _, fromScan := ticket.Validations[i].Metadata["scanId"]; fromScan {
+			continue
+		}
+		entries = append(entries, scanEntry{at: ticket.Validations[i].ValidatedAt, validation: &ticket.Validations[i]})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if !a.at.Equal(b.at) {
+			return a.at.Before(b.at)
+		}
+		if a.deviceID != b.deviceID {
+			return a.deviceID < b.deviceID
+		}
+		return a.scanID < b.scanID
+	})
+
+	// Проходы проигрываются с начала. Статусы used и expired следуют из
+	// проходов и срока, а состоявшийся визит не мешает принять опоздавшие
+	// сканирования, сделанные во время него.
+	replay := *ticket
+	replay.UsageCount = 0
+	replay.Validations = []models.TicketValidation{}
+	if replay.Status == models.TicketStatusUsed || replay.Status == models.TicketStatusExpired {
+		replay.Status = models.TicketStatusActive
+	}
+	replayBooking := *booking
+	switch booking.Status {
+	case models.BookingStatusCheckedIn, models.BookingStatusCompleted, models.BookingStatusNoShow:
+		replayBooking.Status = models.BookingStatusConfirmed
+	}
+
+	accepted := 0
+	for _, entry := range entries {
+		if entry.validation != nil {
+			useTicket(&replay, *entry.validation)
+			continue
+		}
+
+		scan := entry.scan
+		outcome, reason := models.TicketScanAccepted, AdmissionReason("")
+		if scan.QRData != ticket.QRCode.Data && scan.ScannedAt.After(ticket.QRCode.GeneratedAt) {
+			reason = AdmissionQRReplaced
+		} else {
+			reason = s.checkAdmission(&replay, &replayBooking, scan.ParkID, scan.ScannedAt)
+		}
+		if reason != "" {
+			outcome = models.TicketScanConflict
+		} else {
+			accepted++
+			validatedBy := actorID
+			if scan.ScannedBy != nil {
+				validatedBy = *scan.ScannedBy
+			}
+			deviceID := scan.DeviceID
+			useTicket(&replay, models.TicketValidation{
+				TicketID:    ticket.ID,
+				ParkID:      scan.ParkID,
+				ValidatedAt: scan.ScannedAt,
+				ValidatedBy: validatedBy,
+				DeviceID:    &deviceID,
+				Location:    scan.Location,
+				Metadata: models.JSONB{
+					"scanId": scan.ID,
+					"source": scan.Source,
+				},
+			})
+		}
+
+		var reasonCode *string
+		if reason != "" {
+			code := string(reason)
+			reasonCode = &code
+		}
+		if scan.Outcome != outcome || !sameReason(scan.Reason, reasonCode) {
+			err := tx.Model(&models.TicketScan{}).Where("id = ?", scan.ID).
+				Updates(map[string]interface{}{"outcome": outcome, "reason": reasonCode}).Error
+			if err != nil {
+				return fmt.Errorf("failed to update scan outcome: %w", err)
+			}
+		}
+	}
+
+	ticket.UsageCount = replay.UsageCount
+	ticket.Validations = replay.Validations
+	switch ticket.Status {
+	case models.TicketStatusActive, models.TicketStatusUsed:
+		status := models.TicketStatusActive
+		if ticket.UsageCount >= ticket.MaxUsages {
+			status = models.TicketStatusUsed
+		}
+		setTicketStatus(ticket, status, actorID, "offline scans reconciled", time.Now())
+	}
+	if err := tx.Model(ticket).
+		Select("usage_count", "validations", "status", "metadata").
+		Updates(ticket).Error; err != nil {
+		return fmt.Errorf("failed to update ticket usage: %w", err)
+	}
+
+	if accepted > 0 && booking.Status == models.BookingStatusConfirmed && booking.BookingType != models.BookingTypePass {
+		if _, err := s.transition(tx, booking, models.BookingStatusCheckedIn, actorID, "offline ticket scan"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sameReason(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// GetScanConflicts возвращает конфликты сканирований, новые первыми.
+// Без includeResolved — только ещё не разобранные.
+func (s *BookingService) GetScanConflicts(parkID *uuid.UUID, includeResolved bool) ([]models.TicketScan, error) {
+	query := s.db.Where("outcome = ? AND deleted_at IS NULL", models.TicketScanConflict)
+	if parkID != nil {
+		query = query.Where("park_id = ?", *parkID)
+	}
+	if !includeResolved {
+		query = query.Where("resolved_at IS NULL")
+	}
+
+	var conflicts []models.TicketScan
+	if err := query.Preload("Ticket").Order("scanned_at DESC").Find(&conflicts).Error; err != nil {
+		return nil, err
+	}
+	return conflicts, nil
+}
+
+// ResolveScanConflict отмечает конфликт разобранным персоналом
+func (s *BookingService) ResolveScanConflict(scanID, staffID uuid.UUID, note string) (*models.TicketScan, error) {
+	var scan models.TicketScan
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("id = ? AND deleted_at IS NULL", scanID).First(&scan).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrScanNotFound
+			}
+			return err
+		}
+		if scan.Outcome != models.TicketScanConflict || scan.ResolvedAt != nil {
+			return ErrScanNotConflict
+		}
+
+		now := time.Now()
+		scan.ResolvedAt = &now
+		scan.ResolvedBy = &staffID
+		if note = strings.TrimSpace(note); note != "" {
+			scan.ResolutionNote = &note
+		}
+		return tx.Model(&scan).
+			Select("resolved_at", "resolved_by", "resolution_note").
+			Updates(&scan).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &scan, nil
+}
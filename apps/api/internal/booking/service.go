@@ -1,54 +1,329 @@
-﻿package booking
+package booking
 
 import (
-"fmt"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
 
-"gorm.io/gorm"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 
-"skypark/internal/models"
+	"skypark/internal/models"
+	"skypark/internal/ticketsign"
 )
 
+var (
+	timeSlotPattern = regexp.MustCompile(`^([0-1][0-9]|2[0-3]):[0-5][0-9]$`)
+	phonePattern    = regexp.MustCompile(`^\+996[0-9]{9}$`)
+)
+
+// CreateBookingRequest описывает запрос клиента на бронирование.
+// Цены в запросе отсутствуют намеренно — они рассчитываются на сервере.
+type CreateBookingRequest struct {
+	ParkID              uuid.UUID            `json:"parkId" binding:"required"`
+	VisitDate           string               `json:"visitDate" binding:"required"` // YYYY-MM-DD
+	TimeSlot            string               `json:"timeSlot" binding:"required"`  // HH:MM
+	ContactInfo         models.ContactInfo   `json:"contactInfo" binding:"required"`
+	Guests              []models.GuestInfo   `json:"guests" binding:"required"`
+	Source              models.BookingSource `json:"source,omitempty"`
+	SpecialRequirements []string             `json:"specialRequirements,omitempty"`
+	Notes               *string              `json:"notes,omitempty"`
+	SaveAsDraft         bool                 `json:"saveAsDraft,omitempty"`
+}
+
+// SMSSender отправляет SMS клиентам, например auth.SMSService
+type SMSSender interface {
+	SendMessage(phone, message string) error
+	GenerateCode() string
+}
+
 type BookingService struct {
-db *gorm.DB
+	db      *gorm.DB
+	config  Config
+	sms     SMSSender
+	signer  *ticketsign.Keyring
+	wallets *WalletIssuers
+	lookups *attemptLimiter
+}
+
+func NewBookingService(db *gorm.DB, config Config, sms SMSSender, signer *ticketsign.Keyring, wallets *WalletIssuers) *BookingService {
+	return &BookingService{
+		db:      db,
+		config:  config,
+		sms:     sms,
+		signer:  signer,
+		wallets: wallets,
+		lookups: newAttemptLimiter(config.LookupWindow),
+	}
+}
+
+// CreateBooking создаёт бронирование с ценами, рассчитанными по тарифам парка
+func (s *BookingService) CreateBooking(userID uuid.UUID, req CreateBookingRequest) (*models.Booking, error) {
+	return s.createBooking(userID, req, createOptions{})
+}
+
+// createOptions позволяет особым видам бронирований (групповым, из листа
+// ожидания) вмешаться в создание бронирования внутри его транзакции
+type createOptions struct {
+	// prepare вызывается до проверки вместимости слота и может изменить
+	// бронирование, например пересчитать цены или задать удержание
+	prepare func(tx *gorm.DB, park *models.Park, booking *models.Booking) error
+	// created вызывается после сохранения бронирования
+	created func(tx *gorm.DB, park *models.Park, booking *models.Booking) error
+}
+
+// createBooking создаёт бронирование по запросу клиента
+func (s *BookingService) createBooking(userID uuid.UUID, req CreateBookingRequest, opts createOptions) (*models.Booking, error) {
+	if err := validateGuests(req.Guests); err != nil {
+		return nil, err
+	}
+	if !timeSlotPattern.MatchString(req.TimeSlot) {
+		return nil, ErrInvalidTimeSlot
+	}
+	if err := validateContactInfo(&req.ContactInfo); err != nil {
+		return nil, err
+	}
+
+	visitDate, err := parseVisitDate(req.VisitDate)
+	if err != nil {
+		return nil, err
+	}
+
+	var park models.Park
+	if err := s.db.Where("id = ? AND deleted_at IS NULL", req.ParkID).First(&park).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrParkNotFound
+		}
+		return nil, err
+	}
+	if park.Status != models.ParkStatusActive {
+		return nil, ErrParkUnavailable
+	}
+
+	schedule, err := s.buildSchedule(s.db, &park, visitDate, len(req.Guests), uuid.Nil)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.IsClosed {
+		return nil, ErrParkClosed
+	}
+	slot, ok := schedule.findSlot(req.TimeSlot)
+	if !ok || slot.IsPast {
+		return nil, ErrInvalidTimeSlot
+	}
+
+	status := models.BookingStatusPendingPayment
+	if req.SaveAsDraft {
+		status = models.BookingStatusDraft
+	}
+
+	source := req.Source
+	switch source {
+	case models.BookingSourceWeb, models.BookingSourceMobile:
+	default:
+		source = models.BookingSourceWeb
+	}
+
+	timeSlot := req.TimeSlot
+	booking := models.Booking{
+		BaseModel:           models.BaseModel{ID: models.GenerateUUID()},
+		UserID:              userID,
+		ParkID:              park.ID,
+		Status:              status,
+		PaymentStatus:       models.PaymentStatusPending,
+		Source:              source,
+		BookingType:         models.BookingTypeStandard,
+		VisitDate:           visitDate,
+		TimeSlot:            &timeSlot,
+		Duration:            slot.Duration,
+		Items:               PriceItems(&park, req.Guests, slot.PricePercent),
+		Currency:            "KGS",
+		Discounts:           []models.DiscountInfo{},
+		ContactInfo:         req.ContactInfo,
+		SpecialRequirements: models.StringArray(req.SpecialRequirements),
+		Notes:               req.Notes,
+		BookedAt:            time.Now(),
+		Metadata:            models.JSONB{},
+	}
+	if booking.SpecialRequirements == nil {
+		booking.SpecialRequirements = models.StringArray{}
+	}
+	applyTotals(&booking)
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := applyPasses(tx, &booking); err != nil {
+			return err
+		}
+		applyTotals(&booking)
+
+		if opts.prepare != nil {
+			if err := opts.prepare(tx, &park, &booking); err != nil {
+				return err
+			}
+		}
+
+		// Черновик не удерживает места, но бронировать заведомо
+		// переполненный слот бессмысленно, поэтому проверка выполняется всегда
+		if err := reserveCapacity(tx, &park, visitDate, timeSlot, booking.TotalGuests, uuid.Nil); err != nil {
+			return err
+		}
+
+		if booking.Status == models.BookingStatusPendingPayment && booking.HoldExpiresAt == nil {
+			holdExpiresAt := time.Now().Add(s.config.HoldTTL)
+			booking.HoldExpiresAt = &holdExpiresAt
+		}
+
+		if err := models.CreateBooking(tx, &booking); err != nil {
+			return fmt.Errorf("failed to create booking: %w", err)
+		}
+		if opts.created != nil {
+			if err := opts.created(tx, &park, &booking); err != nil {
+				return err
+			}
+		}
+
+		// Оплачивать нечего (например, все гости по абонементам) — подтверждаем сразу
+		if booking.Status == models.BookingStatusPendingPayment && booking.TotalAmount == 0 {
+			if _, err := s.transition(tx, &booking, models.BookingStatusConfirmed, systemActor, "nothing to pay"); err != nil {
+				return err
+			}
+		}
+		return reloadVersion(tx, &booking)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.RefreshParkCapacity(park.ID); err != nil {
+		log.Printf("⚠️ Failed to refresh capacity for park %s: %v", park.ID, err)
+	}
+
+	return &booking, nil
+}
+
+// parseVisitDate разбирает дату визита в часовом поясе парков и
+// запрещает бронирование на прошедшие дни
+func parseVisitDate(value string) (time.Time, error) {
+	loc := parkLocation()
+	visitDate, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return time.Time{}, ErrInvalidVisitDate
+	}
+
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if visitDate.Before(today) {
+		return time.Time{}, ErrInvalidVisitDate
+	}
+	return visitDate, nil
 }
 
-func NewBookingService(db *gorm.DB) *BookingService {
-return &BookingService{
-db: db,
+func validateGuests(guests []models.GuestInfo) error {
+	if len(guests) == 0 {
+		return ErrNoGuests
+	}
+	for _, guest := range guests {
+		if strings.TrimSpace(guest.Name) == "" {
+			return ErrInvalidGuest
+		}
+	}
+	return nil
 }
+
+func validateContactInfo(contact *models.ContactInfo) error {
+	contact.FirstName = strings.TrimSpace(contact.FirstName)
+	contact.LastName = strings.TrimSpace(contact.LastName)
+	contact.PhoneNumber = strings.TrimSpace(contact.PhoneNumber)
+
+	if contact.FirstName == "" || contact.LastName == "" {
+		return ErrInvalidContact
+	}
+	if !phonePattern.MatchString(contact.PhoneNumber) {
+		return ErrInvalidContact
+	}
+	if contact.EmergencyContact != nil && !phonePattern.MatchString(*contact.EmergencyContact) {
+		return ErrInvalidContact
+	}
+	return nil
 }
 
-func (s *BookingService) GetBookingStats() (interface{}, error) {
-return map[string]interface{}{
-"total_bookings": 0,
-"message": "Statistics not implemented yet",
-}, nil
+// parkLocation возвращает часовой пояс, в котором работают парки
+func parkLocation() *time.Location {
+	loc, err := time.LoadLocation("Asia/Bishkek")
+	if err != nil {
+		return time.FixedZone("Asia/Bishkek", 6*60*60)
+	}
+	return loc
 }
 
-func (s *BookingService) ConfirmBooking(bookingID string) error {
-fmt.Printf("Confirm booking %s - not implemented\n", bookingID)
-return nil
+// ConfirmBooking подтверждает бронирование
+func (s *BookingService) ConfirmBooking(bookingID, actorID uuid.UUID, version *int64) (*models.Booking, error) {
+	return s.TransitionBooking(bookingID, models.BookingStatusConfirmed, actorID, "", version)
 }
 
-func (s *BookingService) CompleteBooking(bookingID string) error {
-fmt.Printf("Complete booking %s - not implemented\n", bookingID)
-return nil
+// CheckInBooking отмечает приход гостей в парк
+func (s *BookingService) CheckInBooking(bookingID, actorID uuid.UUID, version *int64) (*models.Booking, error) {
+	return s.TransitionBooking(bookingID, models.BookingStatusCheckedIn, actorID, "", version)
 }
 
-func (s *BookingService) RejectBooking(bookingID, reason string) error {
-fmt.Printf("Reject booking %s: %s - not implemented\n", bookingID, reason)
-return nil
+// CompleteBooking завершает визит
+func (s *BookingService) CompleteBooking(bookingID, actorID uuid.UUID, version *int64) (*models.Booking, error) {
+	return s.TransitionBooking(bookingID, models.BookingStatusCompleted, actorID, "", version)
 }
 
-func (s *BookingService) SeedSampleBookings() error {
-var count int64
-s.db.Model(&models.Booking{}).Where("deleted_at IS NULL").Count(&count)
-if count > 0 {
-return nil
+// MarkNoShow отмечает, что гости не пришли
+func (s *BookingService) MarkNoShow(bookingID, actorID uuid.UUID, version *int64) (*models.Booking, error) {
+	return s.TransitionBooking(bookingID, models.BookingStatusNoShow, actorID, "", version)
 }
 
-fmt.Println("Sample bookings seeded successfully")
-return nil
+// RejectBooking отклоняет ещё не оплаченное бронирование с указанием причины
+func (s *BookingService) RejectBooking(bookingID, actorID uuid.UUID, reason string, version *int64) (*models.Booking, error) {
+	var booking *models.Booking
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		booking, err = loadBookingForUpdate(tx, bookingID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(booking, version); err != nil {
+			return err
+		}
+
+		switch booking.Status {
+		case models.BookingStatusDraft, models.BookingStatusPendingPayment, models.BookingStatusCancelled:
+		default:
+			return &TransitionError{BookingID: booking.ID, From: booking.Status, To: models.BookingStatusCancelled}
+		}
+
+		if booking.Metadata["approvalStatus"] == approvalPending {
+			booking.Metadata["approvalStatus"] = approvalRejected
+		}
+		if _, err = s.transition(tx, booking, models.BookingStatusCancelled, actorID, reason); err != nil {
+			return err
+		}
+		if err := cancelOpenInvoices(tx, booking.ID); err != nil {
+			return err
+		}
+		return reloadVersion(tx, booking)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.CapacityReleased(booking.ParkID)
+	return booking, nil
 }
 
- 
\ No newline at end of file
+func (s *BookingService) SeedSampleBookings() error {
+	var count int64
+	s.db.Model(&models.Booking{}).Where("deleted_at IS NULL").Count(&count)
+	if count > 0 {
+		return nil
+	}
+
+	fmt.Println("Sample bookings seeded successfully")
+	return nil
+}
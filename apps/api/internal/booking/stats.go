@@ -0,0 +1,281 @@
+package booking
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"skypark/internal/models"
+)
+
+// Группировка статистики по периодам
+const (
+	StatsGroupByDay   = "day"
+	StatsGroupByWeek  = "week"
+	StatsGroupByMonth = "month"
+)
+
+// maxStatsRangeDays ограничивает период статистики, чтобы отчёт по дням
+// оставался обозримым
+const maxStatsRangeDays = 731
+
+// StatsFilter задаёт период, парк и группировку статистики. Даты — даты
+// визита включительно.
+type StatsFilter struct {
+	From    time.Time
+	To      time.Time
+	ParkID  *uuid.UUID
+	GroupBy string
+}
+
+// StatsBucket — показатели бронирований за период
+type StatsBucket struct {
+	Period              string                         `json:"period,omitempty"` // начало периода, YYYY-MM-DD
+	TotalBookings       int64                          `json:"totalBookings"`
+	ByStatus            map[models.BookingStatus]int64 `json:"byStatus"`
+	Revenue             float64                        `json:"revenue"`
+	PaidBookings        int64                          `json:"paidBookings"`
+	AverageBookingValue float64                        `json:"averageBookingValue"`
+	UniqueCustomers     int64                          `json:"uniqueCustomers"`
+	CancellationRate    float64                        `json:"cancellationRate"` // доля отменённых, %
+	NoShowRate          float64                        `json:"noShowRate"`       // доля неявок среди состоявшихся визитов, %
+	TotalGuests         int64                          `json:"totalGuests"`
+	GuestsByAge         map[models.AgeCategory]int64   `json:"guestsByAge"`
+}
+
+// BookingStats — статистика бронирований за период с разбивкой по дням,
+// неделям или месяцам
+type BookingStats struct {
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	ParkID   *uuid.UUID    `json:"parkId,omitempty"`
+	GroupBy  string        `json:"groupBy"`
+	Currency string        `json:"currency"`
+	Totals   StatsBucket   `json:"totals"`
+	Periods  []StatsBucket `json:"periods"`
+}
+
+// statsPeriods — допустимые группировки и их шаг
+var statsPeriods = map[string]func(time.Time) time.Time{
+	StatsGroupByDay:   func(t time.Time) time.Time { return t.AddDate(0, 0, 1) },
+	StatsGroupByWeek:  func(t time.Time) time.Time { return t.AddDate(0, 0, 7) },
+	StatsGroupByMonth: func(t time.Time) time.Time { return t.AddDate(0, 1, 0) },
+}
+
+// Статусы, по которым считаются показатели
+var (
+	// Платежи, деньги по которым поступили (возвраты вычитаются отдельно)
+	statsPaidStatuses = []models.PaymentStatus{
+		models.PaymentStatusCompleted,
+		models.PaymentStatusPartiallyRefunded,
+		models.PaymentStatusRefunded,
+	}
+	statsCancelledStatuses = []models.BookingStatus{
+		models.BookingStatusCancelled,
+		models.BookingStatusRefunded,
+	}
+	// Визиты, дата которых наступила и гость мог прийти
+	statsDueStatuses = []models.BookingStatus{
+		models.BookingStatusCheckedIn,
+		models.BookingStatusCompleted,
+		models.BookingStatusNoShow,
+	}
+	// Проданные визиты, гости которых учитываются в статистике
+	statsSoldStatuses = []models.BookingStatus{
+		models.BookingStatusConfirmed,
+		models.BookingStatusCheckedIn,
+		models.BookingStatusCompleted,
+		models.BookingStatusNoShow,
+	}
+)
+
+// statsTotalsRow — строка агрегатов за период; Period пуст для итога
+type statsTotalsRow struct {
+	Period          *time.Time
+	TotalBookings   int64
+	Cancelled       int64
+	NoShows         int64
+	DueVisits       int64
+	Revenue         float64
+	PaidBookings    int64
+	UniqueCustomers int64
+}
+
+type statsStatusRow struct {
+	Period *time.Time
+	Status models.BookingStatus
+	Count  int64
+}
+
+type statsGuestRow struct {
+	Period      *time.Time
+	AgeCategory models.AgeCategory
+	Count       int64
+}
+
+// GetBookingStats считает статистику бронирований по дате визита. Черновики
+// не учитываются. Выручка — проведённые платежи бронирований за вычетом
+// возвратов, средний чек — выручка на бронирование, за которым после
+// возвратов осталась оплата. Итоги и разбивка по периодам
+// считаются одним запросом через GROUPING SETS, поэтому уникальные клиенты
+// в итоге не суммируются по периодам.
+func (s *BookingService) GetBookingStats(filter StatsFilter) (*BookingStats, error) {
+	if filter.GroupBy == "" {
+		filter.GroupBy = StatsGroupByDay
+	}
+	step, ok := statsPeriods[filter.GroupBy]
+	if !ok {
+		return nil, ErrInvalidStatsGroupBy
+	}
+	if filter.To.Before(filter.From) || filter.To.Sub(filter.From) > maxStatsRangeDays*24*time.Hour {
+		return nil, ErrInvalidStatsRange
+	}
+
+	// Группировка подставляется в запрос только из statsPeriods
+	period := fmt.Sprintf("DATE_TRUNC('%s', b.visit_date)::date", filter.GroupBy)
+	base := func() *gorm.DB {
+		query := s.db.Table("bookings AS b").
+			Where("b.deleted_at IS NULL AND b.status <> ?", models.BookingStatusDraft).
+			Where("b.visit_date BETWEEN ? AND ?", filter.From.Format("2006-01-02"), filter.To.Format("2006-01-02"))
+		if filter.ParkID != nil {
+			query = query.Where("b.park_id = ?", *filter.ParkID)
+		}
+		return query
+	}
+
+	// Выручка берётся из платежей: payment_status бронирования не отражает
+	// подтверждение администратором, неоплаченную доплату и депозит праздника
+	var totals []statsTotalsRow
+	err := base().
+		Joins(`LEFT JOIN LATERAL (
+			SELECT SUM(p.amount - p.total_refunded) AS net
+			FROM payments p
+			WHERE p.booking_id = b.id AND p.status IN ? AND p.deleted_at IS NULL
+		) AS paid ON true`, statsPaidStatuses).
+		Select(fmt.Sprintf(`%s AS period,
+			COUNT(*) AS total_bookings,
+			COUNT(*) FILTER (WHERE b.status IN ?) AS cancelled,
+			COUNT(*) FILTER (WHERE b.status = ?) AS no_shows,
+			COUNT(*) FILTER (WHERE b.status IN ?) AS due_visits,
+			COALESCE(SUM(paid.net), 0) AS revenue,
+			COUNT(*) FILTER (WHERE paid.net > 0) AS paid_bookings,
+			COUNT(DISTINCT b.user_id) AS unique_customers`, period),
+			statsCancelledStatuses, models.BookingStatusNoShow, statsDueStatuses).
+		Group(fmt.Sprintf("GROUPING SETS ((%s), ())", period)).
+		Scan(&totals).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking totals: %w", err)
+	}
+
+	var statuses []statsStatusRow
+	err = base().
+		Select(fmt.Sprintf("%s AS period, b.status AS status, COUNT(*) AS count", period)).
+		Group(fmt.Sprintf("GROUPING SETS ((%s, b.status), (b.status))", period)).
+		Scan(&statuses).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking status counts: %w", err)
+	}
+
+	// Покупка абонемента — не визит, её держатель не считается гостем
+	var guests []statsGuestRow
+	err = base().
+		Joins("CROSS JOIN LATERAL jsonb_array_elements(b.items) AS item").
+		Where("b.status IN ? AND b.booking_type <> ?", statsSoldStatuses, models.BookingTypePass).
+		Select(fmt.Sprintf("%s AS period, item->'guestInfo'->>'ageCategory' AS age_category, COUNT(*) AS count", period)).
+		Group(fmt.Sprintf("GROUPING SETS ((%s, item->'guestInfo'->>'ageCategory'), (item->'guestInfo'->>'ageCategory'))", period)).
+		Scan(&guests).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guest counts: %w", err)
+	}
+
+	stats := &BookingStats{
+		From:     filter.From.Format("2006-01-02"),
+		To:       filter.To.Format("2006-01-02"),
+		ParkID:   filter.ParkID,
+		GroupBy:  filter.GroupBy,
+		Currency: "KGS",
+		Totals:   newStatsBucket(""),
+	}
+
+	// Периоды без бронирований тоже попадают в отчёт, чтобы на графике не было дыр
+	buckets := map[string]*StatsBucket{"": &stats.Totals}
+	var keys []string
+	for start := truncateStatsPeriod(filter.From, filter.GroupBy); !start.After(filter.To); start = step(start) {
+		key := start.Format("2006-01-02")
+		bucket := newStatsBucket(key)
+		buckets[key] = &bucket
+		keys = append(keys, key)
+	}
+	bucketFor := func(period *time.Time) *StatsBucket {
+		if period == nil {
+			return &stats.Totals
+		}
+		return buckets[period.Format("2006-01-02")]
+	}
+
+	for _, row := range totals {
+		bucket := bucketFor(row.Period)
+		if bucket == nil {
+			continue
+		}
+		bucket.TotalBookings = row.TotalBookings
+		bucket.Revenue = roundMoney(row.Revenue)
+		bucket.PaidBookings = row.PaidBookings
+		bucket.UniqueCustomers = row.UniqueCustomers
+		if row.PaidBookings > 0 {
+			bucket.AverageBookingValue = roundMoney(row.Revenue / float64(row.PaidBookings))
+		}
+		if row.TotalBookings > 0 {
+			bucket.CancellationRate = statsRate(row.Cancelled, row.TotalBookings)
+		}
+		if row.DueVisits > 0 {
+			bucket.NoShowRate = statsRate(row.NoShows, row.DueVisits)
+		}
+	}
+	for _, row := range statuses {
+		if bucket := bucketFor(row.Period); bucket != nil {
+			bucket.ByStatus[row.Status] = row.Count
+		}
+	}
+	for _, row := range guests {
+		if bucket := bucketFor(row.Period); bucket != nil {
+			bucket.GuestsByAge[row.AgeCategory] += row.Count
+			bucket.TotalGuests += row.Count
+		}
+	}
+
+	stats.Periods = make([]StatsBucket, 0, len(keys))
+	for _, key := range keys {
+		stats.Periods = append(stats.Periods, *buckets[key])
+	}
+	return stats, nil
+}
+
+func newStatsBucket(period string) StatsBucket {
+	return StatsBucket{
+		Period:      period,
+		ByStatus:    map[models.BookingStatus]int64{},
+		GuestsByAge: map[models.AgeCategory]int64{},
+	}
+}
+
+// truncateStatsPeriod возвращает начало периода так же, как DATE_TRUNC:
+// неделя начинается в понедельник
+func truncateStatsPeriod(t time.Time, groupBy string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch groupBy {
+	case StatsGroupByWeek:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case StatsGroupByMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+	}
+	return day
+}
+
+// statsRate возвращает долю part от total в процентах с точностью до сотых
+func statsRate(part, total int64) float64 {
+	return roundMoney(float64(part) * 100 / float64(total))
+}
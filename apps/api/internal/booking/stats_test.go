@@ -0,0 +1,37 @@
+package booking
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTruncateStatsPeriod(t *testing.T) {
+	bishkek := time.FixedZone("Asia/Bishkek", 6*60*60)
+	// Среда, 12 июня 2024
+	wednesday := time.Date(2024, 6, 12, 17, 45, 30, 0, bishkek)
+
+	tests := []struct {
+		name    string
+		t       time.Time
+		groupBy string
+		want    time.Time
+	}{
+		{"day", wednesday, StatsGroupByDay, time.Date(2024, 6, 12, 0, 0, 0, 0, bishkek)},
+		{"unknown grouping falls back to day", wednesday, "year", time.Date(2024, 6, 12, 0, 0, 0, 0, bishkek)},
+		{"week starts on monday", wednesday, StatsGroupByWeek, time.Date(2024, 6, 10, 0, 0, 0, 0, bishkek)},
+		{"monday stays in its week", time.Date(2024, 6, 10, 0, 0, 0, 0, bishkek), StatsGroupByWeek, time.Date(2024, 6, 10, 0, 0, 0, 0, bishkek)},
+		{"sunday belongs to the previous monday", time.Date(2024, 6, 16, 23, 59, 0, 0, bishkek), StatsGroupByWeek, time.Date(2024, 6, 10, 0, 0, 0, 0, bishkek)},
+		{"week across a month boundary", time.Date(2024, 7, 2, 9, 0, 0, 0, bishkek), StatsGroupByWeek, time.Date(2024, 7, 1, 0, 0, 0, 0, bishkek)},
+		{"week across a year boundary", time.Date(2025, 1, 1, 9, 0, 0, 0, bishkek), StatsGroupByWeek, time.Date(2024, 12, 30, 0, 0, 0, 0, bishkek)},
+		{"month", wednesday, StatsGroupByMonth, time.Date(2024, 6, 1, 0, 0, 0, 0, bishkek)},
+		{"last day of a leap february", time.Date(2024, 2, 29, 12, 0, 0, 0, bishkek), StatsGroupByMonth, time.Date(2024, 2, 1, 0, 0, 0, 0, bishkek)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateStatsPeriod(tt.t, tt.groupBy)
+			if !got.Equal(tt.want) || got.Location() != tt.want.Location() {
+				t.Errorf("truncateStatsPeriod() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"skypark/internal/models"
+)
+
+// sweeperLockKey — ключ advisory-блокировки, благодаря которой уборку
+// одновременно выполняет только одна реплика API
+const sweeperLockKey = "booking:sweeper"
+
+// SweepRun — запись о выполненной уборке бронирований
+type SweepRun struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	Instance       string     `json:"instance"`
+	Status         string     `json:"status"` // completed, failed
+	StartedAt      time.Time  `json:"startedAt"`
+	FinishedAt     *time.Time `json:"finishedAt,omitempty"`
+	NoShows        int        `json:"noShows"`
+	ExpiredDrafts  int        `json:"expiredDrafts"`
+	ExpiredPending int        `json:"expiredPending"`
+	// Билеты, статус которых уборка привела в соответствие с бронированием
+	// или сроком действия
+	ActivatedTickets int     `json:"activatedTickets"`
+	ExpiredTickets   int     `json:"expiredTickets"`
+	VoidedTickets    int     `json:"voidedTickets"`
+	Error            *string `json:"error,omitempty"`
+}
+
+func (SweepRun) TableName() string {
+	return "booking_sweeper_runs"
+}
+
+// Sweep отмечает неявки по подтверждённым бронированиям, чей слот закончился
+// без прихода гостей, и отменяет устаревшие черновики и неоплаченные
+// бронирования, освобождая их места. Затем догоняет статусы билетов: истекшие
+// билеты отмечаются expired, а билеты отменённых и оплаченных бронирований
+// аннулируются или активируются. Каждый запуск записывается в
+// booking_sweeper_runs.
+func (s *BookingService) Sweep() (*SweepRun, error) {
+	now := time.Now()
+	run := &SweepRun{
+		Instance:  instanceName(),
+		Status:    "completed",
+		StartedAt: now,
+	}
+	affectedParks := map[uuid.UUID]bool{}
+
+	locked := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(hashtext(?))", sweeperLockKey).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+
+		var err error
+		if run.NoShows, err = s.sweepNoShows(tx, now, affectedParks); err != nil {
+			return err
+		}
+
+		// Групповые бронирования, ждущие согласования администратора, не истекают
+		drafts := tx.Where("status = ? AND updated_at < ?", models.BookingStatusDraft, now.Add(-s.config.DraftTTL)).
+			Where("COALESCE(metadata->>'approvalStatus', '') <> ?", approvalPending)
+		if run.ExpiredDrafts, err = s.expireBookings(tx, drafts, models.BookingStatusDraft, affectedParks); err != nil {
+			return err
+		}
+
+		// Бронирования с действующим удержанием не трогаем, даже если они старые
+		pending := tx.Where("status = ? AND booked_at < ? AND (hold_expires_at IS NULL OR hold_expires_at <= ?)",
+			models.BookingStatusPendingPayment, now.Add(-s.config.PendingPaymentTTL), now)
+		if run.ExpiredPending, err = s.expireBookings(tx, pending, models.BookingStatusPendingPayment, affectedParks); err != nil {
+			return err
+		}
+
+		tickets, err := sweepTickets(tx, now)
+		if err != nil {
+			return err
+		}
+		run.ActivatedTickets, run.ExpiredTickets, run.VoidedTickets = tickets.Activated, tickets.Expired, tickets.Voided
+		return nil
+	})
+	if err == nil && !locked {
+		return nil, ErrSweepInProgress
+	}
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	if err != nil {
+		// Транзакция откатилась, поэтому ничего не изменено
+		message := err.Error()
+		run.Status = "failed"
+		run.Error = &message
+		run.NoShows, run.ExpiredDrafts, run.ExpiredPending = 0, 0, 0
+		run.ActivatedTickets, run.ExpiredTickets, run.VoidedTickets = 0, 0, 0
+		affectedParks = nil
+	}
+
+	if createErr := s.db.Create(run).Error; createErr != nil {
+		log.Printf("⚠️ Failed to record booking sweep run: %v", createErr)
+	}
+
+	for parkID := range affectedParks {
+		s.CapacityReleased(parkID)
+	}
+
+	return run, err
+}
+
+// sweepNoShows переводит в no_show подтверждённые бронирования, слот которых
+// закончился более NoShowGrace назад
+func (s *BookingService) sweepNoShows(tx *gorm.DB, now time.Time, affectedParks map[uuid.UUID]bool) (int, error) {
+	today := now.In(parkLocation()).Format("2006-01-02")
+
+	var bookings []models.Booking
+	if err := tx.Where("status = ? AND visit_date <= ? AND booking_type <> ? AND deleted_at IS NULL",
+		models.BookingStatusConfirmed, today, models.BookingTypePass).
+		Find(&bookings).Error; err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := range bookings {
+		duration := time.Duration(bookings[i].Duration) * time.Minute
+		if duration <= 0 {
+			duration = s.config.SlotLength
+		}
+		slotEnd := visitStart(&bookings[i]).Add(duration)
+		if now.Before(slotEnd.Add(s.config.NoShowGrace)) {
+			continue
+		}
+
+		changed, err := s.sweepTransition(tx, bookings[i].ID, models.BookingStatusConfirmed, models.BookingStatusNoShow, "slot ended without check-in")
+		if err != nil {
+			log.Printf("⚠️ Failed to mark booking %s as no-show: %v", bookings[i].ID, err)
+			continue
+		}
+		if changed {
+			affectedParks[bookings[i].ParkID] = true
+			count++
+		}
+	}
+	return count, nil
+}
+
+// expireBookings отменяет бронирования в статусе from, отобранные query
+func (s *BookingService) expireBookings(tx *gorm.DB, query *gorm.DB, from models.BookingStatus, affectedParks map[uuid.UUID]bool) (int, error) {
+	var bookings []models.Booking
+	if err := query.Where("deleted_at IS NULL").Select("id", "park_id").Find(&bookings).Error; err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, booking := range bookings {
+		changed, err := s.sweepTransition(tx, booking.ID, from, models.BookingStatusCancelled, "expired")
+		if err != nil {
+			log.Printf("⚠️ Failed to expire booking %s: %v", booking.ID, err)
+			continue
+		}
+		if changed {
+			affectedParks[booking.ParkID] = true
+			count++
+		}
+	}
+	return count, nil
+}
+
+// sweepTransition переводит бронирование from → to в точке сохранения, чтобы
+// ошибка по одному бронированию не откатывала всю уборку. Если статус успел
+// измениться, бронирование пропускается.
+func (s *BookingService) sweepTransition(tx *gorm.DB, bookingID uuid.UUID, from, to models.BookingStatus, reason string) (bool, error) {
+	changed := false
+	err := tx.Transaction(func(sp *gorm.DB) error {
+		booking, err := loadBookingForUpdate(sp, bookingID)
+		if err != nil {
+			return err
+		}
+		if booking.Status != from {
+			return nil
+		}
+		changed, err = s.transition(sp, booking, to, systemActor, reason)
+		return err
+	})
+	return changed, err
+}
+
+// ListSweepRuns возвращает последние запуски уборки
+func (s *BookingService) ListSweepRuns(limit int) ([]SweepRun, error) {
+	var runs []SweepRun
+	if err := s.db.Order("started_at DESC").Limit(limit).Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// StartSweeper периодически запускает уборку бронирований до отмены ctx
+func (s *BookingService) StartSweeper(ctx context.Context) {
+	ticker := time.NewTicker(s.config.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			run, err := s.Sweep()
+			if errors.Is(err, ErrSweepInProgress) {
+				continue
+			}
+			if err != nil {
+				log.Printf("⚠️ Booking sweep failed: %v", err)
+				continue
+			}
+			if run.NoShows+run.ExpiredDrafts+run.ExpiredPending > 0 {
+				log.Printf("🧹 Booking sweep: %d no-shows, %d expired drafts, %d expired unpaid bookings",
+					run.NoShows, run.ExpiredDrafts, run.ExpiredPending)
+			}
+			if run.ActivatedTickets+run.ExpiredTickets+run.VoidedTickets > 0 {
+				log.Printf("🎟️ Ticket sweep: %d activated, %d expired, %d voided",
+					run.ActivatedTickets, run.ExpiredTickets, run.VoidedTickets)
+			}
+		}
+	}
+}
+
+// instanceName возвращает имя реплики для журнала запусков
+func instanceName() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return hostname
+}
@@ -0,0 +1,180 @@
+package booking
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"skypark/internal/models"
+)
+
+// Статус билета следует за бронированием: билеты активируются после оплаты,
+// аннулируются при отмене и возврате, а после ValidTo истекают. Переходы
+// выполняются вместе со сменой статуса бронирования, а уборка догоняет
+// билеты, которые разошлись с бронированием или срок которых прошёл.
+
+// TicketStatusChange — запись истории статусов в Ticket.Metadata["statusHistory"]
+type TicketStatusChange struct {
+	From   models.TicketStatus `json:"from"`
+	To     models.TicketStatus `json:"to"`
+	At     time.Time           `json:"at"`
+	By     uuid.UUID           `json:"by"`
+	Reason string              `json:"reason,omitempty"`
+}
+
+// ticketStatusSources — из каких статусов билет переходит в статус при
+// смене статуса его бронирования
+var ticketStatusSources = map[models.TicketStatus][]models.TicketStatus{
+	models.TicketStatusActive:    {models.TicketStatusPending},
+	models.TicketStatusCancelled: {models.TicketStatusPending, models.TicketStatusActive},
+	models.TicketStatusRefunded:  {models.TicketStatusPending, models.TicketStatusActive, models.TicketStatusCancelled},
+}
+
+// bookingTicketStatus — статус билетов для статуса бронирования
+var bookingTicketStatus = map[models.BookingStatus]models.TicketStatus{
+	models.BookingStatusConfirmed: models.TicketStatusActive,
+	models.BookingStatusCancelled: models.TicketStatusCancelled,
+	models.BookingStatusRefunded:  models.TicketStatusRefunded,
+}
+
+// setTicketStatus переводит билет в статус to и записывает переход в историю.
+// Сохранить status и metadata должен вызывающий код.
+func setTicketStatus(ticket *models.Ticket, to models.TicketStatus, actorID uuid.UUID, reason string, now time.Time) bool {
+	if ticket.Status == to {
+		return false
+	}
+	ticket.Metadata = appendStatusHistory(ticket.Metadata, TicketStatusChange{
+		From:   ticket.Status,
+		To:     to,
+		At:     now,
+		By:     actorID,
+		Reason: reason,
+	})
+	ticket.Status = to
+	return true
+}
+
+// updateTicketStatuses переводит в статус to билеты, отобранные query
+func updateTicketStatuses(tx *gorm.DB, query *gorm.DB, to models.TicketStatus, actorID uuid.UUID, reason string, now time.Time) (int, error) {
+	var tickets []models.Ticket
+	err := query.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("deleted_at IS NULL").
+		Find(&tickets).Error
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := range tickets {
+		if !setTicketStatus(&tickets[i], to, actorID, reason, now) {
+			continue
+		}
+		if err := tx.Model(&tickets[i]).Select("status", "metadata").Updates(&tickets[i]).Error; err != nil {
+			return count, fmt.Errorf("failed to update ticket status: %w", err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// syncBookingTickets приводит статус билетов бронирования в соответствие с
+// его новым статусом to
+func syncBookingTickets(tx *gorm.DB, booking *models.Booking, to models.BookingStatus, actorID uuid.UUID, now time.Time) error {
+	status, ok := bookingTicketStatus[to]
+	if !ok {
+		return nil
+	}
+	query := tx.Model(&models.Ticket{}).
+		Where("booking_id = ? AND status IN ?", booking.ID, ticketStatusSources[status])
+	if _, err := updateTicketStatuses(tx, query, status, actorID, "booking "+string(to), now); err != nil {
+		return err
+	}
+	return nil
+}
+
+// TicketSweepResult — сколько билетов изменила уборка
+type TicketSweepResult struct {
+	Activated int
+	Expired   int
+	Voided    int
+}
+
+// sweepTickets догоняет билеты, разошедшиеся с бронированиями, и отмечает
+// истёкшими неиспользованные билеты, срок которых прошёл. Билет
+// обрабатывается в точке сохранения, чтобы ошибка по одному не откатывала
+// всю уборку.
+func sweepTickets(tx *gorm.DB, now time.Time) (TicketSweepResult, error) {
+	var result TicketSweepResult
+
+	steps := []struct {
+		to      models.TicketStatus
+		query   func(*gorm.DB) *gorm.DB
+		counter *int
+		reason  string
+	}{
+		{
+			to: models.TicketStatusRefunded,
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where("status IN ? AND booking_id IN (SELECT id FROM bookings WHERE status = ?)",
+					ticketStatusSources[models.TicketStatusRefunded], models.BookingStatusRefunded)
+			},
+			counter: &result.Voided,
+			reason:  "booking refunded",
+		},
+		{
+			to: models.TicketStatusCancelled,
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where("status IN ? AND booking_id IN (SELECT id FROM bookings WHERE status = ?)",
+					ticketStatusSources[models.TicketStatusCancelled], models.BookingStatusCancelled)
+			},
+			counter: &result.Voided,
+			reason:  "booking cancelled",
+		},
+		{
+			// Оплаченные бронирования, билеты которых ещё ждут активации
+			to: models.TicketStatusActive,
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where("status = ? AND valid_to > ? AND booking_id IN (SELECT id FROM bookings WHERE status IN ?)",
+					models.TicketStatusPending, now,
+					[]models.BookingStatus{models.BookingStatusConfirmed, models.BookingStatusCheckedIn})
+			},
+			counter: &result.Activated,
+			reason:  "booking paid",
+		},
+		{
+			to: models.TicketStatusExpired,
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where("status IN ? AND valid_to <= ?",
+					[]models.TicketStatus{models.TicketStatusPending, models.TicketStatusActive}, now)
+			},
+			counter: &result.Expired,
+			reason:  "validity ended",
+		},
+	}
+
+	for _, step := range steps {
+		var ids []uuid.UUID
+		if err := step.query(tx.Model(&models.Ticket{})).Where("deleted_at IS NULL").Pluck("id", &ids).Error; err != nil {
+			return result, err
+		}
+		for _, id := range ids {
+			count := 0
+			err := tx.Transaction(func(sp *gorm.DB) error {
+				var err error
+				count, err = updateTicketStatuses(sp, step.query(sp.Model(&models.Ticket{})).Where("id = ?", id),
+					step.to, systemActor, step.reason, now)
+				return err
+			})
+			if err != nil {
+				log.Printf("⚠️ Failed to move ticket %s to %s: %v", id, step.to, err)
+				continue
+			}
+			*step.counter += count
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,221 @@
+package booking
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"skypark/internal/models"
+	"skypark/internal/ticketsign"
+)
+
+// issueTickets выпускает по билету на каждого гостя подтверждённого
+// бронирования. Билеты действуют с начала слота до его окончания. Гости, на
+// которых билет уже выпущен или за которых не внесена доплата, пропускаются,
+// поэтому повторный вызов безопасен.
+// Покупка абонемента билетов на вход не получает: входом служит сам абонемент.
+func (s *BookingService) issueTickets(tx *gorm.DB, booking *models.Booking, now time.Time) error {
+	if booking.BookingType == models.BookingTypePass || len(booking.Items) == 0 {
+		return nil
+	}
+
+	var park models.Park
+	if err := tx.Select("id", "name").Where("id = ?", booking.ParkID).First(&park).Error; err != nil {
+		return err
+	}
+
+	var issued []string
+	err := tx.Model(&models.Ticket{}).
+		Where("booking_id = ? AND status NOT IN ? AND deleted_at IS NULL", booking.ID, voidTicketStatuses).
+		Pluck("metadata->>'bookingItemId'", &issued).Error
+	if err != nil {
+		return err
+	}
+	issuedItems := make(map[string]bool, len(issued))
+	for _, id := range issued {
+		issuedItems[id] = true
+	}
+	// Добавленные при изменении гости получают билеты после оплаты доплаты
+	for id := range unpaidItems(booking) {
+		issuedItems[id] = true
+	}
+
+	validFrom := visitStart(booking)
+	validTo := validFrom.Add(time.Duration(booking.Duration) * time.Minute)
+	description := fmt.Sprintf("Визит %s", validFrom.Format("02.01.2006 15:04"))
+
+	for _, item := range booking.Items {
+		if issuedItems[item.ID.String()] {
+			continue
+		}
+
+		ticketID := models.GenerateUUID()
+		qrCode, err := s.newQRCode(ticketID, booking.ParkID, validFrom, validTo, now)
+		if err != nil {
+			return err
+		}
+		ticketType := item.GuestInfo.TicketType
+		if ticketType == "" {
+			ticketType = models.TicketTypeSingle
+		}
+		discount := 0.0
+		if item.BasePrice > 0 {
+			discount = roundMoney(item.DiscountAmount * 100 / item.BasePrice)
+		}
+		metadata := models.JSONB{
+			"bookingItemId": item.ID,
+		}
+		if item.GuestInfo.PassTicketID != nil {
+			metadata["passTicketId"] = *item.GuestInfo.PassTicketID
+		}
+
+		ticket := models.Ticket{
+			BaseModel:           models.BaseModel{ID: ticketID},
+			BookingID:           booking.ID,
+			ParkID:              booking.ParkID,
+			UserID:              booking.UserID,
+			Type:                ticketType,
+			AgeCategory:         item.GuestInfo.AgeCategory,
+			Status:              models.TicketStatusActive,
+			Title:               park.Name,
+			Description:         &description,
+			Price:               item.FinalPrice,
+			OriginalPrice:       item.BasePrice,
+			Currency:            booking.Currency,
+			Discount:            discount,
+			ValidFrom:           validFrom,
+			ValidTo:             validTo,
+			MaxUsages:           1,
+			QRCode:              qrCode,
+			Validations:         []models.TicketValidation{},
+			HolderName:          strings.TrimSpace(item.GuestInfo.Name),
+			HolderAge:           item.GuestInfo.Age,
+			SpecialRequirements: item.GuestInfo.SpecialRequirements,
+			Metadata:            metadata,
+		}
+		if err := models.CreateTicket(tx, &ticket); err != nil {
+			return fmt.Errorf("failed to issue ticket: %w", err)
+		}
+		booking.Tickets = append(booking.Tickets, ticket)
+	}
+	return nil
+}
+
+// GetUserTickets возвращает билеты пользователя, ближайшие визиты первыми
+func (s *BookingService) GetUserTickets(userID uuid.UUID) ([]models.Ticket, error) {
+	var tickets []models.Ticket
+	err := s.db.Where("user_id = ? AND deleted_at IS NULL", userID).
+		Preload("Park").
+		Order("valid_from DESC").
+		Find(&tickets).Error
+	if err != nil {
+		return nil, err
+	}
+	return tickets, nil
+}
+
+// TicketSigningKeys возвращает открытые ключи для офлайн-проверки QR-кодов
+func (s *BookingService) TicketSigningKeys() []ticketsign.PublicKey {
+	return s.signer.PublicKeys()
+}
+
+// voidTicketStatuses — статусы билетов, по которым уже нельзя пройти
+var voidTicketStatuses = []models.TicketStatus{
+	models.TicketStatusCancelled,
+	models.TicketStatusRefunded,
+	models.TicketStatusExpired,
+}
+
+// reissueTickets заменяет неиспользованные билеты подтверждённого
+// бронирования после переноса или смены состава гостей: прежние билеты
+// аннулируются, а на новых гостей и время выпускаются новые. Билеты,
+// переданные другим людям, остаются у получателей.
+func (s *BookingService) reissueTickets(tx *gorm.DB, booking *models.Booking, now time.Time) error {
+	if booking.Status != models.BookingStatusConfirmed {
+		return nil
+	}
+
+	replacedStatuses := []models.TicketStatus{models.TicketStatusPending, models.TicketStatusActive}
+	var transferred []models.Ticket
+	err := tx.Select("user_id", "holder_name", "metadata").
+		Where("booking_id = ? AND status IN ? AND usage_count = 0 AND user_id <> ? AND deleted_at IS NULL",
+			booking.ID, replacedStatuses, booking.UserID).
+		Find(&transferred).Error
+	if err != nil {
+		return err
+	}
+
+	replaced := tx.Model(&models.Ticket{}).
+		Where("booking_id = ? AND status IN ? AND usage_count = 0", booking.ID, replacedStatuses)
+	if _, err := updateTicketStatuses(tx, replaced, models.TicketStatusCancelled, systemActor, "replaced after booking change", now); err != nil {
+		return fmt.Errorf("failed to cancel replaced tickets: %w", err)
+	}
+	booking.Tickets = nil
+	if err := s.issueTickets(tx, booking, now); err != nil {
+		return err
+	}
+
+	holders := make(map[string]models.Ticket, len(transferred))
+	for _, ticket := range transferred {
+		if itemID, ok := ticket.Metadata["bookingItemId"].(string); ok {
+			holders[itemID] = ticket
+		}
+	}
+	for i := range booking.Tickets {
+		ticket := &booking.Tickets[i]
+		itemID, _ := ticket.Metadata["bookingItemId"].(uuid.UUID)
+		holder, ok := holders[itemID.String()]
+		if !ok {
+			continue
+		}
+		ticket.UserID = holder.UserID
+		ticket.HolderName = holder.HolderName
+		if err := tx.Model(ticket).Select("user_id", "holder_name").Updates(ticket).Error; err != nil {
+			return fmt.Errorf("failed to keep transferred ticket holder: %w", err)
+		}
+	}
+	hideTransferredQRCodes(booking)
+	return nil
+}
+
+// newQRCode создаёт QR-код билета. Data — подписанный токен, который сканер
+// проверяет без связи с сервером (формат описан в пакете ticketsign), Code —
+// короткий случайный код для поиска билета.
+func (s *BookingService) newQRCode(ticketID, parkID uuid.UUID, validFrom, validTo, now time.Time) (models.QRCode, error) {
+	code, err := newTicketCode()
+	if err != nil {
+		return models.QRCode{}, err
+	}
+	token, err := s.signer.Sign(ticketsign.Claims{
+		TicketID:  ticketID,
+		ParkID:    parkID,
+		ValidFrom: validFrom,
+		ValidTo:   validTo,
+	})
+	if err != nil {
+		return models.QRCode{}, fmt.Errorf("failed to sign ticket: %w", err)
+	}
+	return models.QRCode{
+		Code:                 code,
+		Data:                 token,
+		Format:               "png",
+		Size:                 256,
+		ErrorCorrectionLevel: "M",
+		GeneratedAt:          now,
+		ExpiresAt:            &validTo,
+	}, nil
+}
+
+// newTicketCode генерирует случайный код билета для QR-кода
+func newTicketCode() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate ticket code: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
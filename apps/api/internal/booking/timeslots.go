@@ -0,0 +1,278 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"skypark/internal/models"
+)
+
+// minSlotMinutes — минимальная длительность визита (ограничение bookings.duration)
+const minSlotMinutes = 30
+
+// TimeSlot описывает слот посещения парка с оставшейся вместимостью
+type TimeSlot struct {
+	Start          string                 `json:"start"`
+	End            string                 `json:"end"`
+	Duration       int                    `json:"duration"` // minutes
+	Capacity       int                    `json:"capacity"`
+	Booked         int                    `json:"booked"`
+	Available      int                    `json:"available"`
+	IsAvailable    bool                   `json:"isAvailable"`
+	IsPast         bool                   `json:"isPast"`
+	PriceModifiers []models.PriceModifier `json:"priceModifiers"`
+	PricePercent   float64                `json:"pricePercent"`
+}
+
+// DaySchedule описывает расписание парка на конкретную дату
+type DaySchedule struct {
+	ParkID    uuid.UUID        `json:"parkId"`
+	Date      string           `json:"date"`
+	DayOfWeek models.DayOfWeek `json:"dayOfWeek"`
+	IsClosed  bool             `json:"isClosed"`
+	OpenTime  string           `json:"openTime,omitempty"`
+	CloseTime string           `json:"closeTime,omitempty"`
+	Slots     []TimeSlot       `json:"slots"`
+}
+
+// findSlot ищет слот по времени начала
+func (d *DaySchedule) findSlot(start string) (*TimeSlot, bool) {
+	for i := range d.Slots {
+		if d.Slots[i].Start == start {
+			return &d.Slots[i], true
+		}
+	}
+	return nil, false
+}
+
+// GetTimeSlots возвращает слоты парка на дату с учётом часов работы и занятости.
+// Слот считается доступным, если в нём есть места минимум для guests гостей.
+func (s *BookingService) GetTimeSlots(parkID uuid.UUID, date string, guests int) (*DaySchedule, error) {
+	visitDate, err := parseVisitDate(date)
+	if err != nil {
+		return nil, err
+	}
+
+	var park models.Park
+	if err := s.db.Where("id = ? AND deleted_at IS NULL", parkID).First(&park).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrParkNotFound
+		}
+		return nil, err
+	}
+
+	return s.buildSchedule(s.db, &park, visitDate, guests, uuid.Nil)
+}
+
+// buildSchedule строит слоты парка на дату. Бронирование excludeID не
+// учитывается при подсчёте занятых мест (нужно при переносе бронирования).
+func (s *BookingService) buildSchedule(tx *gorm.DB, park *models.Park, visitDate time.Time, guests int, excludeID uuid.UUID) (*DaySchedule, error) {
+	if guests < 1 {
+		guests = 1
+	}
+
+	day := dayOfWeek(visitDate.Weekday())
+	schedule := &DaySchedule{
+		ParkID:    park.ID,
+		Date:      visitDate.Format("2006-01-02"),
+		DayOfWeek: day,
+		IsClosed:  true,
+		Slots:     []TimeSlot{},
+	}
+
+	if park.Status != models.ParkStatusActive {
+		return schedule, nil
+	}
+
+	hours, ok := operatingHoursFor(park, day)
+	if !ok || hours.IsClosed {
+		return schedule, nil
+	}
+
+	openAt, err := parseClock(hours.OpenTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid open time for %s: %w", day, err)
+	}
+	closeAt, err := parseClock(hours.CloseTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid close time for %s: %w", day, err)
+	}
+	if closeAt <= openAt {
+		return schedule, nil
+	}
+
+	schedule.IsClosed = false
+	schedule.OpenTime = hours.OpenTime
+	schedule.CloseTime = hours.CloseTime
+
+	occupied, err := occupiedBySlot(tx, park.ID, visitDate, excludeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count occupied spots: %w", err)
+	}
+
+	loc := parkLocation()
+	now := time.Now().In(loc)
+	slotLength := int(s.config.SlotLength.Minutes())
+	if slotLength < minSlotMinutes {
+		slotLength = minSlotMinutes
+	}
+
+	for start := openAt; start < closeAt; start += slotLength {
+		end := start + slotLength
+		if end > closeAt {
+			end = closeAt
+		}
+		if end-start < minSlotMinutes {
+			break
+		}
+
+		startLabel := formatClock(start)
+		slotStart := visitDate.Add(time.Duration(start) * time.Minute)
+		modifiers := matchingPriceModifiers(park.Settings.PriceModifiers, day, start)
+
+		slot := TimeSlot{
+			Start:          startLabel,
+			End:            formatClock(end),
+			Duration:       end - start,
+			Capacity:       park.Capacity.Total,
+			Booked:         occupied[startLabel],
+			IsPast:         !slotStart.After(now),
+			PriceModifiers: modifiers,
+			PricePercent:   totalPricePercent(modifiers),
+		}
+		slot.Available = slot.Capacity - slot.Booked
+		if slot.Available < 0 {
+			slot.Available = 0
+		}
+		slot.IsAvailable = !slot.IsPast && slot.Available >= guests
+
+		schedule.Slots = append(schedule.Slots, slot)
+	}
+
+	return schedule, nil
+}
+
+// occupiedBySlot возвращает количество занятых мест по слотам на дату,
+// включая места, предложенные из листа ожидания
+func occupiedBySlot(tx *gorm.DB, parkID uuid.UUID, visitDate time.Time, excludeID uuid.UUID) (map[string]int, error) {
+	var rows []struct {
+		TimeSlot string
+		Guests   int
+	}
+	err := tx.Model(&models.Booking{}).
+		Select("time_slot, COALESCE(SUM(total_guests), 0) AS guests").
+		Where("park_id = ? AND visit_date = ? AND time_slot IS NOT NULL AND deleted_at IS NULL", parkID, visitDate.Format("2006-01-02")).
+		Where("id <> ?", excludeID).
+		Scopes(occupying).
+		Group("time_slot").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	offered, err := offeredBySlot(tx, parkID, visitDate)
+	if err != nil {
+		return nil, err
+	}
+
+	occupied := make(map[string]int, len(rows))
+	for _, row := range rows {
+		occupied[row.TimeSlot] = row.Guests
+	}
+	for timeSlot, guests := range offered {
+		occupied[timeSlot] += guests
+	}
+	return occupied, nil
+}
+
+// operatingHoursFor возвращает часы работы парка в указанный день недели
+func operatingHoursFor(park *models.Park, day models.DayOfWeek) (models.OperatingHours, bool) {
+	for _, hours := range park.OperatingHours {
+		if hours.Day == day {
+			return hours, true
+		}
+	}
+	return models.OperatingHours{}, false
+}
+
+// matchingPriceModifiers отбирает модификаторы цены, действующие для слота
+func matchingPriceModifiers(modifiers []models.PriceModifier, day models.DayOfWeek, start int) []models.PriceModifier {
+	matched := []models.PriceModifier{}
+	for _, modifier := range modifiers {
+		if len(modifier.Days) > 0 && !containsDay(modifier.Days, day) {
+			continue
+		}
+		from, err := parseClock(modifier.StartTime)
+		if err != nil {
+			continue
+		}
+		to, err := parseClock(modifier.EndTime)
+		if err != nil {
+			continue
+		}
+		if start >= from && start < to {
+			matched = append(matched, modifier)
+		}
+	}
+	return matched
+}
+
+func totalPricePercent(modifiers []models.PriceModifier) float64 {
+	var percent float64
+	for _, modifier := range modifiers {
+		percent += modifier.Percent
+	}
+	if percent < -100 {
+		percent = -100
+	}
+	return percent
+}
+
+func containsDay(days []models.DayOfWeek, day models.DayOfWeek) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+func dayOfWeek(weekday time.Weekday) models.DayOfWeek {
+	switch weekday {
+	case time.Monday:
+		return models.DayMonday
+	case time.Tuesday:
+		return models.DayTuesday
+	case time.Wednesday:
+		return models.DayWednesday
+	case time.Thursday:
+		return models.DayThursday
+	case time.Friday:
+		return models.DayFriday
+	case time.Saturday:
+		return models.DaySaturday
+	default:
+		return models.DaySunday
+	}
+}
+
+// parseClock переводит время "HH:MM" в минуты от начала суток.
+// "24:00" допускается как время закрытия в полночь.
+func parseClock(value string) (int, error) {
+	var hours, minutes int
+	if _, err := fmt.Sscanf(value, "%d:%d", &hours, &minutes); err != nil {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	if hours < 0 || minutes < 0 || minutes > 59 || hours > 24 || (hours == 24 && minutes > 0) {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	return hours*60 + minutes, nil
+}
+
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
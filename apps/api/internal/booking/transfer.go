@@ -0,0 +1,323 @@
+package booking
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"skypark/internal/models"
+)
+
+// Передача билета: владелец указывает телефон получателя, получатель
+// подтверждает передачу кодом из SMS, и билет переходит к нему вместе с новым
+// QR-кодом, а прежний код перестаёт действовать.
+
+const (
+	// transferCodeTTL — сколько действует код подтверждения передачи
+	transferCodeTTL = 15 * time.Minute
+	// maxTransferAttempts — сколько раз можно ошибиться в коде, как и при входе
+	maxTransferAttempts = 3
+)
+
+// TransferTicketRequest — запрос владельца на передачу билета
+type TransferTicketRequest struct {
+	RecipientPhone string  `json:"recipientPhone" binding:"required"`
+	RecipientName  *string `json:"recipientName,omitempty"`
+}
+
+// AcceptTransferRequest — подтверждение передачи получателем
+type AcceptTransferRequest struct {
+	Code       string  `json:"code" binding:"required"`
+	HolderName *string `json:"holderName,omitempty"`
+}
+
+// TicketTransferRecord — запись истории передач в Ticket.Metadata["transfers"]
+type TicketTransferRecord struct {
+	TransferID uuid.UUID `json:"transferId"`
+	From       uuid.UUID `json:"from"`
+	To         uuid.UUID `json:"to"`
+	At         time.Time `json:"at"`
+}
+
+// transfersEnabled сообщает, разрешена ли в парке передача билетов
+func transfersEnabled(park *models.Park) bool {
+	return park.Settings.TicketTransfer == nil || park.Settings.TicketTransfer.Enabled
+}
+
+// checkTransferable проверяет, можно ли сейчас передать билет
+func checkTransferable(tx *gorm.DB, ticket *models.Ticket, now time.Time) error {
+	if ticket.UsageCount > 0 || len(ticket.Validations) > 0 {
+		return ErrTicketAlreadyValidated
+	}
+	if ticket.Status != models.TicketStatusActive && ticket.Status != models.TicketStatusPending {
+		return ErrTicketNotTransferable
+	}
+	if !now.Before(ticket.ValidTo) {
+		return ErrTicketNotTransferable
+	}
+
+	var park models.Park
+	if err := tx.Select("id", "settings").Where("id = ?", ticket.ParkID).First(&park).Error; err != nil {
+		return err
+	}
+	if !transfersEnabled(&park) {
+		return ErrTransfersDisabled
+	}
+	return nil
+}
+
+// StartTicketTransfer начинает передачу билета владельцем userID и отправляет
+// получателю код подтверждения. Незавершённая передача того же билета
+// отменяется.
+func (s *BookingService) StartTicketTransfer(userID, ticketID uuid.UUID, req TransferTicketRequest) (*models.TicketTransfer, error) {
+	if s.sms == nil {
+		return nil, fmt.Errorf("SMS sender is not configured")
+	}
+	phone := normalizePhone(req.RecipientPhone)
+	if len(phone) != len("+996XXXXXXXXX") {
+		return nil, ErrInvalidTransferRecipient
+	}
+	var recipientName *string
+	if req.RecipientName != nil {
+		if name := strings.TrimSpace(*req.RecipientName); name != "" {
+			recipientName = &name
+		}
+	}
+
+	var transfer *models.TicketTransfer
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		ticket, err := loadTicketForUpdate(tx, ticketID)
+		if err != nil {
+			return err
+		}
+		if ticket.UserID != userID {
+			return ErrTicketNotFound
+		}
+		now := time.Now()
+		if err := checkTransferable(tx, ticket, now); err != nil {
+			return err
+		}
+
+		var owner models.User
+		if err := tx.Select("id", "phone_number").Where("id = ?", userID).First(&owner).Error; err != nil {
+			return err
+		}
+		if normalizePhone(owner.PhoneNumber) == phone {
+			return ErrInvalidTransferRecipient
+		}
+
+		err = tx.Model(&models.TicketTransfer{}).
+			Where("ticket_id = ? AND status = ?", ticket.ID, models.TicketTransferPending).
+			Update("status", models.TicketTransferCancelled).Error
+		if err != nil {
+			return err
+		}
+
+		code := s.sms.GenerateCode()
+		transfer = &models.TicketTransfer{
+			TicketID:       ticket.ID,
+			ParkID:         ticket.ParkID,
+			FromUserID:     userID,
+			RecipientPhone: phone,
+			RecipientName:  recipientName,
+			Status:         models.TicketTransferPending,
+			CodeHash:       hashTransferCode(code),
+			ExpiresAt:      now.Add(transferCodeTTL),
+		}
+		if err := tx.Create(transfer).Error; err != nil {
+			return fmt.Errorf("failed to create ticket transfer: %w", err)
+		}
+
+		// SMS отправляется последним: если оно не ушло, передача не создаётся
+		message := fmt.Sprintf("Sky Park: вам передают билет «%s» на %s. Код подтверждения: %s. Примите билет в приложении.",
+			ticket.Title, ticket.ValidFrom.In(parkLocation()).Format("02.01 15:04"), code)
+		if err := s.sms.SendMessage(phone, message); err != nil {
+			return fmt.Errorf("failed to send transfer code: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// CancelTicketTransfer отменяет незавершённую передачу билета его владельцем
+func (s *BookingService) CancelTicketTransfer(userID, ticketID uuid.UUID) (*models.TicketTransfer, error) {
+	var transfer models.TicketTransfer
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("ticket_id = ? AND from_user_id = ? AND status = ? AND deleted_at IS NULL",
+				ticketID, userID, models.TicketTransferPending).
+			First(&transfer).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTransferNotFound
+			}
+			return err
+		}
+		transfer.Status = models.TicketTransferCancelled
+		return tx.Model(&transfer).Update("status", transfer.Status).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// GetIncomingTransfers возвращает ожидающие подтверждения передачи билетов на
+// телефон пользователя. QR-код билета до подтверждения не раскрывается.
+func (s *BookingService) GetIncomingTransfers(userID uuid.UUID) ([]models.TicketTransfer, error) {
+	var user models.User
+	if err := s.db.Select("id", "phone_number").Where("id = ?", userID).First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	var transfers []models.TicketTransfer
+	err := s.db.Where("recipient_phone = ? AND status = ? AND expires_at > ? AND deleted_at IS NULL",
+		normalizePhone(user.PhoneNumber), models.TicketTransferPending, time.Now()).
+		Preload("Ticket", func(db *gorm.DB) *gorm.DB {
+			return db.Omit("qr_code", "validations")
+		}).
+		Order("created_at DESC").
+		Find(&transfers).Error
+	if err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// AcceptTicketTransfer принимает передачу билета пользователем userID по коду
+// из SMS. Билет переходит к получателю, получает новый QR-код, а старый код
+// на входе отклоняется как QR_REPLACED.
+func (s *BookingService) AcceptTicketTransfer(userID, transferID uuid.UUID, req AcceptTransferRequest) (*models.Ticket, error) {
+	var user models.User
+	if err := s.db.Select("id", "phone_number", "first_name", "last_name").Where("id = ?", userID).First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	var ticketID uuid.UUID
+	err := s.db.Model(&models.TicketTransfer{}).
+		Select("ticket_id").
+		Where("id = ? AND recipient_phone = ? AND deleted_at IS NULL", transferID, normalizePhone(user.PhoneNumber)).
+		Scan(&ticketID).Error
+	if err != nil {
+		return nil, err
+	}
+	if ticketID == uuid.Nil {
+		return nil, ErrTransferNotFound
+	}
+
+	var ticket *models.Ticket
+	wrongCode := false
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Билет блокируется раньше передачи, как и при её создании
+		var err error
+		ticket, err = loadTicketForUpdate(tx, ticketID)
+		if err != nil {
+			return err
+		}
+
+		var transfer models.TicketTransfer
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", transferID).
+			First(&transfer).Error
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if transfer.Status != models.TicketTransferPending {
+			return ErrTransferNotFound
+		}
+		if !now.Before(transfer.ExpiresAt) || transfer.Attempts >= maxTransferAttempts {
+			return ErrTransferExpired
+		}
+
+		if subtle.ConstantTimeCompare([]byte(hashTransferCode(strings.TrimSpace(req.Code))), []byte(transfer.CodeHash)) != 1 {
+			// Неудачная попытка сохраняется, поэтому транзакция не откатывается
+			wrongCode = true
+			return tx.Model(&transfer).Update("attempts", gorm.Expr("attempts + 1")).Error
+		}
+
+		if ticket.UserID != transfer.FromUserID {
+			return ErrTicketNotTransferable
+		}
+		if err := checkTransferable(tx, ticket, now); err != nil {
+			return err
+		}
+
+		holderName := strings.TrimSpace(user.FirstName + " " + user.LastName)
+		if transfer.RecipientName != nil {
+			holderName = *transfer.RecipientName
+		}
+		if req.HolderName != nil && strings.TrimSpace(*req.HolderName) != "" {
+			holderName = strings.TrimSpace(*req.HolderName)
+		}
+
+		qrCode, err := s.newQRCode(ticket.ID, ticket.ParkID, ticket.ValidFrom, ticket.ValidTo, now)
+		if err != nil {
+			return err
+		}
+		if ticket.Metadata == nil {
+			ticket.Metadata = models.JSONB{}
+		}
+		history, _ := ticket.Metadata["transfers"].([]interface{})
+		ticket.Metadata["transfers"] = append(history, TicketTransferRecord{
+			TransferID: transfer.ID,
+			From:       ticket.UserID,
+			To:         userID,
+			At:         now,
+		})
+		ticket.UserID = userID
+		if holderName != "" {
+			ticket.HolderName = holderName
+		}
+		ticket.QRCode = qrCode
+		if err := tx.Model(ticket).
+			Select("user_id", "holder_name", "qr_code", "metadata").
+			Updates(ticket).Error; err != nil {
+			return fmt.Errorf("failed to transfer ticket: %w", err)
+		}
+
+		transfer.Status = models.TicketTransferAccepted
+		transfer.ToUserID = &userID
+		transfer.AcceptedAt = &now
+		if err := tx.Model(&transfer).
+			Select("status", "to_user_id", "accepted_at").
+			Updates(&transfer).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Ticket{}).Select("version").Where("id = ?", ticket.ID).Scan(&ticket.Version).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if wrongCode {
+		return nil, ErrInvalidTransferCode
+	}
+	return ticket, nil
+}
+
+// hideTransferredQRCodes скрывает QR-коды билетов, переданных другим людям,
+// чтобы покупатель не мог пройти по ним из своего бронирования
+func hideTransferredQRCodes(booking *models.Booking) {
+	for i := range booking.Tickets {
+		if booking.Tickets[i].UserID != booking.UserID {
+			booking.Tickets[i].QRCode = models.QRCode{}
+		}
+	}
+}
+
+// hashTransferCode хранит код подтверждения только в виде хэша
+func hashTransferCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
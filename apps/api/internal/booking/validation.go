@@ -0,0 +1,239 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"skypark/internal/models"
+)
+
+// AdmissionReason — причина отказа в проходе, которую показывает турникет
+type AdmissionReason string
+
+const (
+	AdmissionInvalidQR      AdmissionReason = "INVALID_QR"
+	AdmissionQRReplaced     AdmissionReason = "QR_REPLACED"
+	AdmissionTicketNotFound AdmissionReason = "TICKET_NOT_FOUND"
+	AdmissionWrongPark      AdmissionReason = "WRONG_PARK"
+	AdmissionNotActive      AdmissionReason = "NOT_ACTIVE"
+	AdmissionCancelled      AdmissionReason = "CANCELLED"
+	AdmissionTooEarly       AdmissionReason = "TOO_EARLY"
+	AdmissionExpired        AdmissionReason = "EXPIRED"
+	AdmissionAlreadyUsed    AdmissionReason = "ALREADY_USED"
+)
+
+var admissionMessages = map[AdmissionReason]string{
+	AdmissionInvalidQR:      "QR code is not a valid Sky Park ticket",
+	AdmissionQRReplaced:     "QR code was replaced by a newer one",
+	AdmissionTicketNotFound: "Ticket not found",
+	AdmissionWrongPark:      "Ticket is for a different park",
+	AdmissionNotActive:      "Ticket is not active yet",
+	AdmissionCancelled:      "Ticket was cancelled",
+	AdmissionTooEarly:       "Ticket is not valid yet",
+	AdmissionExpired:        "Ticket has expired",
+	AdmissionAlreadyUsed:    "Ticket has already been used",
+}
+
+// AdmissionDeniedError возвращается, если по билету нельзя пройти.
+// Ticket заполнен, если билет удалось найти.
+type AdmissionDeniedError struct {
+	Reason AdmissionReason
+	Ticket *models.Ticket
+}
+
+func (e *AdmissionDeniedError) Error() string {
+	return admissionMessages[e.Reason]
+}
+
+// ValidateTicketRequest — отсканированный на входе QR-код
+type ValidateTicketRequest struct {
+	QRData   string    `json:"qrData" binding:"required"`
+	ParkID   uuid.UUID `json:"parkId" binding:"required"`
+	DeviceID string    `json:"deviceId" binding:"required"`
+	Location *string   `json:"location,omitempty"`
+}
+
+// ValidationResult — результат успешного прохода
+type ValidationResult struct {
+	Ticket          *models.Ticket       `json:"ticket"`
+	BookingNumber   string               `json:"bookingNumber"`
+	BookingStatus   models.BookingStatus `json:"bookingStatus"`
+	RemainingUsages int                  `json:"remainingUsages"`
+}
+
+// ValidateTicket пропускает посетителя по отсканированному QR-коду: проверяет
+// подпись, парк, срок и статус билета, списывает проход и отмечает
+// бронирование как checked_in при первом проходе по нему.
+func (s *BookingService) ValidateTicket(staffID uuid.UUID, req ValidateTicketRequest) (*ValidationResult, error) {
+	claims, err := s.signer.Verify(strings.TrimSpace(req.QRData))
+	if err != nil {
+		return nil, &AdmissionDeniedError{Reason: AdmissionInvalidQR}
+	}
+	if claims.ParkID != req.ParkID {
+		return nil, &AdmissionDeniedError{Reason: AdmissionWrongPark}
+	}
+
+	var result *ValidationResult
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var bookingID uuid.UUID
+		err := tx.Model(&models.Ticket{}).
+			Select("booking_id").
+			Where("id = ? AND deleted_at IS NULL", claims.TicketID).
+			Scan(&bookingID).Error
+		if err != nil {
+			return err
+		}
+		if bookingID == uuid.Nil {
+			return &AdmissionDeniedError{Reason: AdmissionTicketNotFound}
+		}
+
+		// Бронирование блокируется раньше билета, как и при смене его статуса
+		booking, err := loadBookingForUpdate(tx, bookingID)
+		if err != nil {
+			return err
+		}
+		ticket, err := loadTicketForUpdate(tx, claims.TicketID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if ticket.QRCode.Data != strings.TrimSpace(req.QRData) {
+			return &AdmissionDeniedError{Reason: AdmissionQRReplaced, Ticket: ticket}
+		}
+		if reason := s.checkAdmission(ticket, booking, req.ParkID, now); reason != "" {
+			return &AdmissionDeniedError{Reason: reason, Ticket: ticket}
+		}
+
+		scan, err := recordOnlineScan(tx, ticket, req, staffID, now)
+		if err != nil {
+			return err
+		}
+		if useTicket(ticket, models.TicketValidation{
+			TicketID:    ticket.ID,
+			ParkID:      req.ParkID,
+			ValidatedAt: now,
+			ValidatedBy: staffID,
+			DeviceID:    &scan.DeviceID,
+			Location:    req.Location,
+			Metadata: models.JSONB{
+				"scanId": scan.ID,
+				"source": scan.Source,
+			},
+		}) {
+			setTicketStatus(ticket, models.TicketStatusUsed, staffID, "all usages spent", now)
+		}
+		if err := tx.Model(ticket).
+			Select("usage_count", "validations", "status", "metadata").
+			Updates(ticket).Error; err != nil {
+			return fmt.Errorf("failed to record ticket validation: %w", err)
+		}
+
+		// Покупка абонемента не визит: проход по абонементу её не меняет
+		if booking.BookingType != models.BookingTypePass {
+			if _, err := s.transition(tx, booking, models.BookingStatusCheckedIn, staffID, "ticket validated"); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.Ticket{}).Select("version").Where("id = ?", ticket.ID).Scan(&ticket.Version).Error; err != nil {
+			return err
+		}
+
+		result = &ValidationResult{
+			Ticket:          ticket,
+			BookingNumber:   booking.BookingNumber,
+			BookingStatus:   booking.Status,
+			RemainingUsages: ticket.MaxUsages - ticket.UsageCount,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// loadTicketForUpdate загружает билет с блокировкой строки
+func loadTicketForUpdate(tx *gorm.DB, ticketID uuid.UUID) (*models.Ticket, error) {
+	var ticket models.Ticket
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND deleted_at IS NULL", ticketID).
+		First(&ticket).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTicketNotFound
+		}
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// checkAdmission решает, можно ли пройти по билету в парк parkID в момент
+// now. Возвращает пустую причину, если проход разрешён.
+func (s *BookingService) checkAdmission(ticket *models.Ticket, booking *models.Booking, parkID uuid.UUID, now time.Time) AdmissionReason {
+	if ticket.ParkID != parkID {
+		return AdmissionWrongPark
+	}
+
+	switch ticket.Status {
+	case models.TicketStatusCancelled, models.TicketStatusRefunded:
+		return AdmissionCancelled
+	case models.TicketStatusExpired:
+		return AdmissionExpired
+	case models.TicketStatusUsed:
+		return AdmissionAlreadyUsed
+	case models.TicketStatusPending:
+		return AdmissionNotActive
+	}
+
+	switch booking.Status {
+	case models.BookingStatusConfirmed, models.BookingStatusCheckedIn:
+	case models.BookingStatusCancelled, models.BookingStatusRefunded:
+		return AdmissionCancelled
+	case models.BookingStatusCompleted, models.BookingStatusNoShow:
+		return AdmissionExpired
+	default:
+		return AdmissionNotActive
+	}
+
+	if now.Before(ticket.ValidFrom.Add(-s.config.EarlyEntryWindow)) {
+		return AdmissionTooEarly
+	}
+	if !now.Before(ticket.ValidTo) {
+		return AdmissionExpired
+	}
+	if ticket.UsageCount >= ticket.MaxUsages {
+		return AdmissionAlreadyUsed
+	}
+	if ticket.Type == models.TicketTypeUnlimited && usedOnDay(ticket, now) {
+		return AdmissionAlreadyUsed
+	}
+	return ""
+}
+
+// usedOnDay сообщает, проходили ли по билету в тот же день, что и now
+func usedOnDay(ticket *models.Ticket, now time.Time) bool {
+	loc := parkLocation()
+	today := now.In(loc).Format("2006-01-02")
+	for _, validation := range ticket.Validations {
+		if validation.ValidatedAt.In(loc).Format("2006-01-02") == today {
+			return true
+		}
+	}
+	return false
+}
+
+// useTicket списывает проход с билета и записывает проверку. Возвращает
+// true, если проходов больше не осталось и билет пора отметить
+// использованным.
+func useTicket(ticket *models.Ticket, validation models.TicketValidation) bool {
+	ticket.UsageCount++
+	ticket.Validations = append(ticket.Validations, validation)
+	return ticket.UsageCount >= ticket.MaxUsages
+}
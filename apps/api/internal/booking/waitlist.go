@@ -0,0 +1,395 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"skypark/internal/models"
+)
+
+// JoinWaitlistRequest описывает запрос на место в листе ожидания слота.
+// Гости и контакты сохраняются, чтобы по предложению создать бронирование.
+type JoinWaitlistRequest struct {
+	ParkID      uuid.UUID          `json:"parkId" binding:"required"`
+	VisitDate   string             `json:"visitDate" binding:"required"` // YYYY-MM-DD
+	TimeSlot    string             `json:"timeSlot" binding:"required"`  // HH:MM
+	ContactInfo models.ContactInfo `json:"contactInfo" binding:"required"`
+	Guests      []models.GuestInfo `json:"guests" binding:"required"`
+}
+
+// JoinWaitlist ставит пользователя в лист ожидания заполненного слота
+func (s *BookingService) JoinWaitlist(userID uuid.UUID, req JoinWaitlistRequest) (*models.WaitlistEntry, error) {
+	if err := validateGuests(req.Guests); err != nil {
+		return nil, err
+	}
+	if !timeSlotPattern.MatchString(req.TimeSlot) {
+		return nil, ErrInvalidTimeSlot
+	}
+	if err := validateContactInfo(&req.ContactInfo); err != nil {
+		return nil, err
+	}
+
+	visitDate, err := parseVisitDate(req.VisitDate)
+	if err != nil {
+		return nil, err
+	}
+
+	var park models.Park
+	if err := s.db.Where("id = ? AND deleted_at IS NULL", req.ParkID).First(&park).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrParkNotFound
+		}
+		return nil, err
+	}
+	if park.Status != models.ParkStatusActive {
+		return nil, ErrParkUnavailable
+	}
+	if len(req.Guests) > park.Capacity.Total {
+		return nil, fmt.Errorf("%w: %d spots in the park", ErrInsufficientCapacity, park.Capacity.Total)
+	}
+
+	schedule, err := s.buildSchedule(s.db, &park, visitDate, len(req.Guests), uuid.Nil)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.IsClosed {
+		return nil, ErrParkClosed
+	}
+	slot, ok := schedule.findSlot(req.TimeSlot)
+	if !ok || slot.IsPast {
+		return nil, ErrInvalidTimeSlot
+	}
+	if slot.IsAvailable {
+		return nil, ErrSlotAvailable
+	}
+
+	var existing int64
+	if err := s.db.Model(&models.WaitlistEntry{}).
+		Where("user_id = ? AND park_id = ? AND visit_date = ? AND time_slot = ? AND status IN ? AND deleted_at IS NULL",
+			userID, park.ID, visitDate.Format("2006-01-02"), req.TimeSlot,
+			[]models.WaitlistStatus{models.WaitlistStatusWaiting, models.WaitlistStatusOffered}).
+		Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, ErrAlreadyWaitlisted
+	}
+
+	entry := models.WaitlistEntry{
+		UserID:      userID,
+		ParkID:      park.ID,
+		Status:      models.WaitlistStatusWaiting,
+		VisitDate:   visitDate,
+		TimeSlot:    req.TimeSlot,
+		GuestCount:  len(req.Guests),
+		Guests:      req.Guests,
+		ContactInfo: req.ContactInfo,
+	}
+	if err := s.db.Create(&entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to join waitlist: %w", err)
+	}
+	return &entry, nil
+}
+
+// GetUserWaitlist возвращает записи пользователя в листах ожидания
+func (s *BookingService) GetUserWaitlist(userID uuid.UUID) ([]models.WaitlistEntry, error) {
+	var entries []models.WaitlistEntry
+	err := s.db.Where("user_id = ? AND deleted_at IS NULL", userID).
+		Preload("Park").
+		Order("created_at DESC").
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// LeaveWaitlist снимает запись пользователя из листа ожидания. Если места
+// уже были предложены, они передаются следующим в очереди.
+func (s *BookingService) LeaveWaitlist(entryID, userID uuid.UUID) (*models.WaitlistEntry, error) {
+	var entry *models.WaitlistEntry
+	wasOffered := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		entry, err = loadWaitlistEntryForUpdate(tx, entryID, userID)
+		if err != nil {
+			return err
+		}
+		switch entry.Status {
+		case models.WaitlistStatusWaiting, models.WaitlistStatusOffered:
+		default:
+			return ErrOfferNotActive
+		}
+
+		wasOffered = entry.Status == models.WaitlistStatusOffered
+		entry.Status = models.WaitlistStatusCancelled
+		return tx.Model(entry).Update("status", entry.Status).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if wasOffered {
+		s.CapacityReleased(entry.ParkID)
+	}
+	return entry, nil
+}
+
+// ClaimWaitlistOffer превращает действующее предложение в бронирование
+// pending_payment с обычным удержанием мест на время оплаты
+func (s *BookingService) ClaimWaitlistOffer(entryID, userID uuid.UUID) (*models.Booking, error) {
+	var entry models.WaitlistEntry
+	if err := s.db.Where("id = ? AND user_id = ? AND deleted_at IS NULL", entryID, userID).First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWaitlistEntryNotFound
+		}
+		return nil, err
+	}
+
+	req := CreateBookingRequest{
+		ParkID:      entry.ParkID,
+		VisitDate:   entry.VisitDate.Format("2006-01-02"),
+		TimeSlot:    entry.TimeSlot,
+		ContactInfo: entry.ContactInfo,
+		Guests:      entry.Guests,
+	}
+
+	// Запись помечается принятой до проверки вместимости, чтобы её
+	// предложение не учитывалось среди занятых мест
+	return s.createBooking(userID, req, createOptions{prepare: func(tx *gorm.DB, park *models.Park, booking *models.Booking) error {
+		locked, err := loadWaitlistEntryForUpdate(tx, entryID, userID)
+		if err != nil {
+			return err
+		}
+		if locked.Status != models.WaitlistStatusOffered ||
+			locked.OfferExpiresAt == nil || !locked.OfferExpiresAt.After(time.Now()) {
+			return ErrOfferNotActive
+		}
+
+		return tx.Model(locked).Updates(map[string]interface{}{
+			"status":     models.WaitlistStatusClaimed,
+			"booking_id": booking.ID,
+		}).Error
+	}})
+}
+
+// loadWaitlistEntryForUpdate загружает запись пользователя с блокировкой строки
+func loadWaitlistEntryForUpdate(tx *gorm.DB, entryID, userID uuid.UUID) (*models.WaitlistEntry, error) {
+	var entry models.WaitlistEntry
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND user_id = ? AND deleted_at IS NULL", entryID, userID).
+		First(&entry).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWaitlistEntryNotFound
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// ProcessWaitlist предлагает освободившиеся места парка следующим в листах
+// ожидания. Места достаются первой по очереди записи, которой их хватает.
+// Возвращает количество отправленных предложений.
+func (s *BookingService) ProcessWaitlist(parkID uuid.UUID) (int, error) {
+	today := time.Now().In(parkLocation()).Format("2006-01-02")
+
+	var slots []struct {
+		VisitDate time.Time
+		TimeSlot  string
+	}
+	err := s.db.Model(&models.WaitlistEntry{}).
+		Distinct("visit_date", "time_slot").
+		Where("park_id = ? AND status = ? AND visit_date >= ? AND deleted_at IS NULL",
+			parkID, models.WaitlistStatusWaiting, today).
+		Scan(&slots).Error
+	if err != nil || len(slots) == 0 {
+		return 0, err
+	}
+
+	var park models.Park
+	if err := s.db.Where("id = ? AND deleted_at IS NULL", parkID).First(&park).Error; err != nil {
+		return 0, err
+	}
+	if park.Status != models.ParkStatusActive {
+		return 0, nil
+	}
+
+	offered := 0
+	for _, slot := range slots {
+		visitDate := time.Date(slot.VisitDate.Year(), slot.VisitDate.Month(), slot.VisitDate.Day(), 0, 0, 0, 0, parkLocation())
+		offers, err := s.offerFreedSpots(&park, visitDate, slot.TimeSlot)
+		if err != nil {
+			log.Printf("⚠️ Failed to process waitlist for park %s at %s %s: %v",
+				parkID, visitDate.Format("2006-01-02"), slot.TimeSlot, err)
+			continue
+		}
+		for i := range offers {
+			s.sendWaitlistOffer(&park, &offers[i])
+		}
+		offered += len(offers)
+	}
+	return offered, nil
+}
+
+// offerFreedSpots распределяет свободные места слота между записями листа
+// ожидания под блокировкой слота
+func (s *BookingService) offerFreedSpots(park *models.Park, visitDate time.Time, timeSlot string) ([]models.WaitlistEntry, error) {
+	now := time.Now()
+	minutes, err := parseClock(timeSlot)
+	if err != nil {
+		return nil, err
+	}
+
+	var offers []models.WaitlistEntry
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		slotQuery := tx.Model(&models.WaitlistEntry{}).
+			Where("park_id = ? AND visit_date = ? AND time_slot = ? AND status = ? AND deleted_at IS NULL",
+				park.ID, visitDate.Format("2006-01-02"), timeSlot, models.WaitlistStatusWaiting)
+
+		// Слот уже начался — ждать больше нечего
+		if !visitDate.Add(time.Duration(minutes) * time.Minute).After(now) {
+			return slotQuery.Update("status", models.WaitlistStatusExpired).Error
+		}
+
+		if err := lockSlot(tx, park.ID, visitDate, timeSlot); err != nil {
+			return fmt.Errorf("failed to lock time slot: %w", err)
+		}
+		occupied, err := occupiedSpots(tx, park.ID, visitDate, timeSlot, uuid.Nil)
+		if err != nil {
+			return err
+		}
+		free := park.Capacity.Total - occupied
+		if free <= 0 {
+			return nil
+		}
+
+		var entries []models.WaitlistEntry
+		if err := slotQuery.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Order("created_at").
+			Find(&entries).Error; err != nil {
+			return err
+		}
+
+		expiresAt := now.Add(s.config.WaitlistClaimWindow)
+		for i := range entries {
+			if entries[i].GuestCount > free {
+				continue
+			}
+			entries[i].Status = models.WaitlistStatusOffered
+			entries[i].OfferedAt = &now
+			entries[i].OfferExpiresAt = &expiresAt
+			if err := tx.Model(&entries[i]).
+				Select("status", "offered_at", "offer_expires_at").
+				Updates(&entries[i]).Error; err != nil {
+				return err
+			}
+			offers = append(offers, entries[i])
+
+			free -= entries[i].GuestCount
+			if free == 0 {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return offers, nil
+}
+
+// sendWaitlistOffer сообщает пользователю по SMS о предложенных местах
+func (s *BookingService) sendWaitlistOffer(park *models.Park, entry *models.WaitlistEntry) {
+	if s.sms == nil {
+		return
+	}
+
+	loc := parkLocation()
+	message := fmt.Sprintf("Sky Park: в «%s» освободились места на %s в %s для %d гостей. Подтвердите бронирование в приложении до %s.",
+		park.Name,
+		entry.VisitDate.Format("02.01"),
+		entry.TimeSlot,
+		entry.GuestCount,
+		entry.OfferExpiresAt.In(loc).Format("15:04"))
+	if err := s.sms.SendMessage(entry.ContactInfo.PhoneNumber, message); err != nil {
+		log.Printf("⚠️ Failed to send waitlist offer %s: %v", entry.ID, err)
+	}
+}
+
+// ExpireWaitlistOffers закрывает непринятые предложения и записи на
+// прошедшие даты. Возвращает количество истёкших предложений.
+func (s *BookingService) ExpireWaitlistOffers() (int64, error) {
+	now := time.Now()
+	result := s.db.Model(&models.WaitlistEntry{}).
+		Where("status = ? AND offer_expires_at <= ?", models.WaitlistStatusOffered, now).
+		Update("status", models.WaitlistStatusExpired)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	today := now.In(parkLocation()).Format("2006-01-02")
+	if err := s.db.Model(&models.WaitlistEntry{}).
+		Where("status = ? AND visit_date < ?", models.WaitlistStatusWaiting, today).
+		Update("status", models.WaitlistStatusExpired).Error; err != nil {
+		return result.RowsAffected, err
+	}
+	return result.RowsAffected, nil
+}
+
+// offeredSpots считает места слота, предложенные из листа ожидания
+// и ещё не истёкшие
+func offeredSpots(tx *gorm.DB, parkID uuid.UUID, visitDate time.Time, timeSlot string) (int, error) {
+	var offered int64
+	err := tx.Model(&models.WaitlistEntry{}).
+		Select("COALESCE(SUM(guest_count), 0)").
+		Where("park_id = ? AND visit_date = ? AND time_slot = ? AND deleted_at IS NULL", parkID, visitDate.Format("2006-01-02"), timeSlot).
+		Where("status = ? AND offer_expires_at > ?", models.WaitlistStatusOffered, time.Now()).
+		Scan(&offered).Error
+	if err != nil {
+		return 0, err
+	}
+	return int(offered), nil
+}
+
+// offeredBySlot возвращает места, предложенные из листа ожидания, по слотам на дату
+func offeredBySlot(tx *gorm.DB, parkID uuid.UUID, visitDate time.Time) (map[string]int, error) {
+	var rows []struct {
+		TimeSlot string
+		Guests   int
+	}
+	err := tx.Model(&models.WaitlistEntry{}).
+		Select("time_slot, COALESCE(SUM(guest_count), 0) AS guests").
+		Where("park_id = ? AND visit_date = ? AND deleted_at IS NULL", parkID, visitDate.Format("2006-01-02")).
+		Where("status = ? AND offer_expires_at > ?", models.WaitlistStatusOffered, time.Now()).
+		Group("time_slot").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	offered := make(map[string]int, len(rows))
+	for _, row := range rows {
+		offered[row.TimeSlot] = row.Guests
+	}
+	return offered, nil
+}
+
+// CapacityReleased обновляет снимок вместимости парка и предлагает
+// освободившиеся места листу ожидания
+func (s *BookingService) CapacityReleased(parkID uuid.UUID) {
+	if err := s.RefreshParkCapacity(parkID); err != nil {
+		log.Printf("⚠️ Failed to refresh capacity for park %s: %v", parkID, err)
+	}
+	if offered, err := s.ProcessWaitlist(parkID); err != nil {
+		log.Printf("⚠️ Failed to process waitlist for park %s: %v", parkID, err)
+	} else if offered > 0 {
+		log.Printf("📨 Offered freed spots to %d waitlisted users in park %s", offered, parkID)
+	}
+}
@@ -0,0 +1,625 @@
+package booking
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"skypark/internal/models"
+	"skypark/internal/wallet"
+)
+
+// Пассы билетов для Apple Wallet и Google Wallet. Пасс выпускается на
+// владельца билета: серийный номер пасса (у Google — идентификатор объекта)
+// состоит из ID билета и ID владельца. Если билет передан другому человеку,
+// пасс прежнего владельца гаснет, а получатель добавляет в кошелёк свой пасс
+// с новым QR-кодом.
+//
+// Каждая регистрация пасса помнит версию билета, о которой кошелёк уже знает.
+// Любое изменение билета (отмена, проход, передача) увеличивает его версию, и
+// фоновая задача сообщает кошелькам о новых версиях.
+
+const (
+	// walletPushBatch — сколько регистраций обновляется за один проход
+	walletPushBatch = 500
+	// walletAuthTokenLength — длина токена веб-сервиса Apple (не меньше 16)
+	walletAuthTokenLength = 32
+)
+
+// WalletIssuers — выпуск пассов в кошельки; незаданный кошелёк отключён
+type WalletIssuers struct {
+	Apple  *wallet.AppleSigner
+	Google *wallet.GoogleIssuer
+	// AuthSecret — ключ HMAC для токенов, которыми пассы Apple обращаются к
+	// веб-сервису пассов
+	AuthSecret []byte
+}
+
+// enabled сообщает, что настроен хотя бы один кошелёк
+func (w *WalletIssuers) enabled() bool {
+	return w != nil && (w.Apple != nil || w.Google != nil)
+}
+
+// authToken возвращает токен веб-сервиса для пасса с серийным номером serial
+func (w *WalletIssuers) authToken(serial string) string {
+	mac := hmac.New(sha256.New, w.AuthSecret)
+	mac.Write([]byte(serial))
+	return hex.EncodeToString(mac.Sum(nil))[:walletAuthTokenLength]
+}
+
+// walletSerial — серийный номер пасса билета ticketID, выпущенного владельцу ownerID
+func walletSerial(ticketID, ownerID uuid.UUID) string {
+	return ticketID.String() + "_" + ownerID.String()
+}
+
+// parseWalletSerial разбирает серийный номер пасса
+func parseWalletSerial(serial string) (ticketID, ownerID uuid.UUID, ok bool) {
+	ticketPart, ownerPart, found := strings.Cut(serial, "_")
+	if !found {
+		return uuid.Nil, uuid.Nil, false
+	}
+	ticketID, err := uuid.Parse(ticketPart)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, false
+	}
+	ownerID, err = uuid.Parse(ownerPart)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, false
+	}
+	return ticketID, ownerID, true
+}
+
+// walletTicketStatuses — подписи статусов билета на пассе
+var walletTicketStatuses = map[models.TicketStatus]string{
+	models.TicketStatusPending:   "Действует",
+	models.TicketStatusActive:    "Действует",
+	models.TicketStatusUsed:      "Использован",
+	models.TicketStatusExpired:   "Истёк",
+	models.TicketStatusCancelled: "Отменён",
+	models.TicketStatusRefunded:  "Возвращён",
+}
+
+// walletPassActive сообщает, что по пассу владельца ownerID можно пройти
+func walletPassActive(ticket *models.Ticket, ownerID uuid.UUID) bool {
+	return ticket.UserID == ownerID && ticket.QRCode.Data != "" &&
+		ticket.Status != models.TicketStatusUsed && !isVoidTicketStatus(ticket.Status)
+}
+
+// walletStatusLabel — подпись статуса билета для владельца пасса
+func walletStatusLabel(ticket *models.Ticket, ownerID uuid.UUID) string {
+	if ticket.UserID != ownerID {
+		return "Передан другому человеку"
+	}
+	return pdfLabel(walletTicketStatuses, ticket.Status)
+}
+
+// parkAddress собирает адрес парка в одну строку
+func parkAddress(park *models.Park) string {
+	parts := []string{}
+	for _, part := range []string{park.Address.Street, park.Address.City, park.Address.Region} {
+		if strings.TrimSpace(part) != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// walletParkName — название парка билета
+func walletParkName(ticket *models.Ticket) string {
+	if ticket.Park != nil {
+		return ticket.Park.Name
+	}
+	return ticket.Title
+}
+
+// walletPassImages — значки пасса Apple: синий квадрат с белым кругом
+var walletPassImages = map[string][]byte{
+	"icon.png":    renderWalletIcon(29),
+	"icon@2x.png": renderWalletIcon(58),
+	"icon@3x.png": renderWalletIcon(87),
+}
+
+// renderWalletIcon рисует значок пасса размером size×size
+func renderWalletIcon(size int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	background := color.NRGBA{R: 41, G: 98, B: 255, A: 255}
+	center := float64(size) / 2
+	radius := float64(size) * 0.3
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := float64(x)+0.5-center, float64(y)+0.5-center
+			if dx*dx+dy*dy <= radius*radius {
+				img.Set(x, y, color.White)
+			} else {
+				img.Set(x, y, background)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+// applePass описывает пасс Apple Wallet билета для владельца ownerID
+func (s *BookingService) applePass(ticket *models.Ticket, ownerID uuid.UUID) wallet.ApplePass {
+	loc := parkLocation()
+	t := pdfTemplates["ru"]
+	serial := walletSerial(ticket.ID, ownerID)
+	parkName := walletParkName(ticket)
+	validFrom := ticket.ValidFrom.In(loc).Format(time.RFC3339)
+	validTo := ticket.ValidTo.In(loc).Format(time.RFC3339)
+
+	backFields := []wallet.AppleField{
+		{Key: "number", Label: t.Ticket, Value: ticket.TicketNumber},
+	}
+	pass := wallet.ApplePass{
+		SerialNumber:        serial,
+		Description:         "Билет: " + parkName,
+		LogoText:            parkName,
+		ForegroundColor:     "rgb(255, 255, 255)",
+		BackgroundColor:     "rgb(41, 98, 255)",
+		LabelColor:          "rgb(210, 225, 255)",
+		RelevantDate:        validFrom,
+		ExpirationDate:      validTo,
+		AuthenticationToken: s.wallets.authToken(serial),
+		EventTicket: wallet.ApplePassFields{
+			HeaderFields: []wallet.AppleField{
+				{Key: "date", Label: t.VisitDate, Value: validFrom, DateStyle: "PKDateStyleShort", TimeStyle: "PKDateStyleNone"},
+			},
+			PrimaryFields: []wallet.AppleField{
+				{Key: "park", Label: t.Park, Value: parkName},
+			},
+			SecondaryFields: []wallet.AppleField{
+				{Key: "holder", Label: t.Holder, Value: ticket.HolderName},
+				{Key: "category", Label: t.AgeCategory, Value: pdfLabel(t.AgeCategories, ticket.AgeCategory)},
+			},
+			AuxiliaryFields: []wallet.AppleField{
+				{Key: "validFrom", Label: "Вход с", Value: validFrom, DateStyle: "PKDateStyleNone", TimeStyle: "PKDateStyleShort"},
+				{Key: "validTo", Label: "До", Value: validTo, DateStyle: "PKDateStyleNone", TimeStyle: "PKDateStyleShort"},
+				// Смена статуса показывается уведомлением на устройстве
+				{Key: "status", Label: t.Status, Value: walletStatusLabel(ticket, ownerID), ChangeMessage: "Билет: %@"},
+			},
+		},
+	}
+	if park := ticket.Park; park != nil {
+		if address := parkAddress(park); address != "" {
+			backFields = append(backFields, wallet.AppleField{Key: "address", Label: t.Address, Value: address})
+		}
+		if park.PhoneNumber != nil {
+			backFields = append(backFields, wallet.AppleField{Key: "phone", Label: t.Phone, Value: *park.PhoneNumber})
+		}
+		if park.Coordinates.Latitude != 0 || park.Coordinates.Longitude != 0 {
+			pass.Locations = []wallet.AppleLocation{{
+				Latitude:     park.Coordinates.Latitude,
+				Longitude:    park.Coordinates.Longitude,
+				RelevantText: parkName + ": покажите QR-код на входе",
+			}}
+		}
+	}
+	pass.EventTicket.BackFields = append(backFields, wallet.AppleField{Key: "info", Value: t.Footer})
+
+	if walletPassActive(ticket, ownerID) {
+		pass.Barcodes = []wallet.AppleBarcode{{
+			Format:          "PKBarcodeFormatQR",
+			Message:         ticket.QRCode.Data,
+			MessageEncoding: "iso-8859-1",
+			AltText:         ticket.TicketNumber,
+		}}
+	} else {
+		pass.Voided = true
+	}
+	return pass
+}
+
+// googleClass описывает класс пассов Google Wallet для парка билета
+func (s *BookingService) googleClass(ticket *models.Ticket) wallet.EventTicketClass {
+	parkName := walletParkName(ticket)
+	class := wallet.EventTicketClass{
+		ID:           s.wallets.Google.ResourceID("park_" + ticket.ParkID.String()),
+		IssuerName:   "Sky Park",
+		EventName:    walletString(parkName),
+		ReviewStatus: "UNDER_REVIEW",
+		HexColor:     "#2962ff",
+		Message:      &wallet.LocalizedString{DefaultValue: wallet.TranslatedString{Language: "ru", Value: pdfTemplates["ru"].Footer}},
+	}
+	if park := ticket.Park; park != nil {
+		if address := parkAddress(park); address != "" {
+			class.Venue = &wallet.EventVenue{Name: walletString(parkName), Address: walletString(address)}
+		}
+		if park.Coordinates.Latitude != 0 || park.Coordinates.Longitude != 0 {
+			class.Locations = []wallet.LatLongPoint{{Latitude: park.Coordinates.Latitude, Longitude: park.Coordinates.Longitude}}
+		}
+		if park.Website != nil {
+			class.Homepage = &wallet.Uri{URI: *park.Website}
+		}
+	}
+	return class
+}
+
+// googleObject описывает пасс Google Wallet билета для владельца ownerID
+func (s *BookingService) googleObject(ticket *models.Ticket, ownerID uuid.UUID) wallet.EventTicketObject {
+	loc := parkLocation()
+	t := pdfTemplates["ru"]
+	object := wallet.EventTicketObject{
+		ID:               s.wallets.Google.ResourceID(walletSerial(ticket.ID, ownerID)),
+		ClassID:          s.wallets.Google.ResourceID("park_" + ticket.ParkID.String()),
+		TicketHolderName: ticket.HolderName,
+		TicketNumber:     ticket.TicketNumber,
+		ValidTimeInterval: &wallet.TimeInterval{
+			Start: wallet.NewDateTime(ticket.ValidFrom.In(loc)),
+			End:   wallet.NewDateTime(ticket.ValidTo.In(loc)),
+		},
+		TextModules: []wallet.TextModule{
+			{ID: "category", Header: t.AgeCategory, Body: pdfLabel(t.AgeCategories, ticket.AgeCategory)},
+			{ID: "status", Header: t.Status, Body: walletStatusLabel(ticket, ownerID)},
+		},
+	}
+	switch {
+	case walletPassActive(ticket, ownerID):
+		object.State = "ACTIVE"
+		object.Barcode = &wallet.Barcode{Type: "QR_CODE", Value: ticket.QRCode.Data, AlternateText: ticket.TicketNumber}
+	case ticket.UserID == ownerID && ticket.Status == models.TicketStatusUsed:
+		object.State = "COMPLETED"
+	case ticket.UserID == ownerID && ticket.Status == models.TicketStatusExpired:
+		object.State = "EXPIRED"
+	default:
+		object.State = "INACTIVE"
+	}
+	return object
+}
+
+// walletString — строка Google Wallet на русском
+func walletString(value string) wallet.LocalizedString {
+	return wallet.LocalizedString{DefaultValue: wallet.TranslatedString{Language: "ru", Value: value}}
+}
+
+// loadWalletTicket загружает билет вместе с парком
+func loadWalletTicket(db *gorm.DB, ticketID uuid.UUID) (*models.Ticket, error) {
+	var ticket models.Ticket
+	err := db.Where("id = ? AND deleted_at IS NULL", ticketID).Preload("Park").First(&ticket).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTicketNotFound
+		}
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// loadOwnWalletTicket загружает билет владельца userID, который можно добавить в кошелёк
+func (s *BookingService) loadOwnWalletTicket(userID, ticketID uuid.UUID) (*models.Ticket, error) {
+	ticket, err := loadWalletTicket(s.db, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	if ticket.UserID != userID {
+		return nil, ErrTicketNotFound
+	}
+	if !walletPassActive(ticket, userID) {
+		return nil, ErrWalletPassUnavailable
+	}
+	return ticket, nil
+}
+
+// AppleWalletPass собирает пасс Apple Wallet (.pkpass) билета его владельцу
+func (s *BookingService) AppleWalletPass(userID, ticketID uuid.UUID) ([]byte, *models.Ticket, error) {
+	if s.wallets == nil || s.wallets.Apple == nil {
+		return nil, nil, ErrWalletNotConfigured
+	}
+	ticket, err := s.loadOwnWalletTicket(userID, ticketID)
+	if err != nil {
+		return nil, nil, err
+	}
+	pass, err := s.wallets.Apple.Package(s.applePass(ticket, userID), walletPassImages)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pass, ticket, nil
+}
+
+// GoogleWalletSaveURL возвращает владельцу билета ссылку «Добавить в Google
+// Кошелёк» и регистрирует пасс для обновлений
+func (s *BookingService) GoogleWalletSaveURL(userID, ticketID uuid.UUID) (string, error) {
+	if s.wallets == nil || s.wallets.Google == nil {
+		return "", ErrWalletNotConfigured
+	}
+	ticket, err := s.loadOwnWalletTicket(userID, ticketID)
+	if err != nil {
+		return "", err
+	}
+	object := s.googleObject(ticket, userID)
+	saveURL, err := s.wallets.Google.SaveURL(
+		[]wallet.EventTicketClass{s.googleClass(ticket)},
+		[]wallet.EventTicketObject{object},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	// Сохранён ли пасс, Google не сообщает, поэтому объект регистрируется
+	// сразу; обновление ещё не сохранённого объекта просто пропускается
+	registration := models.WalletRegistration{
+		TicketID:      ticket.ID,
+		UserID:        userID,
+		Platform:      models.WalletPlatformGoogle,
+		SerialNumber:  object.ID,
+		PushedVersion: ticket.Version,
+	}
+	err = s.db.Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "serial_number"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "platform = 'google' AND deleted_at IS NULL"}}},
+		DoNothing:   true,
+	}).Create(&registration).Error
+	if err != nil {
+		return "", err
+	}
+	return saveURL, nil
+}
+
+// authorizeApplePass проверяет тип пасса и токен, с которым Wallet
+// обращается к веб-сервису, и разбирает серийный номер
+func (s *BookingService) authorizeApplePass(passTypeID, serial, authToken string) (ticketID, ownerID uuid.UUID, err error) {
+	if s.wallets == nil || s.wallets.Apple == nil {
+		return uuid.Nil, uuid.Nil, ErrWalletNotConfigured
+	}
+	if passTypeID != s.wallets.Apple.PassTypeID() {
+		return uuid.Nil, uuid.Nil, ErrWalletPassNotFound
+	}
+	ticketID, ownerID, ok := parseWalletSerial(serial)
+	if !ok {
+		return uuid.Nil, uuid.Nil, ErrWalletPassNotFound
+	}
+	expected := s.wallets.authToken(serial)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(authToken)) != 1 {
+		return uuid.Nil, uuid.Nil, ErrInvalidWalletToken
+	}
+	return ticketID, ownerID, nil
+}
+
+// ApplePassBySerial собирает актуальную версию пасса по запросу Wallet
+func (s *BookingService) ApplePassBySerial(passTypeID, serial, authToken string) ([]byte, *models.Ticket, error) {
+	ticketID, ownerID, err := s.authorizeApplePass(passTypeID, serial, authToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	ticket, err := loadWalletTicket(s.db, ticketID)
+	if err != nil {
+		return nil, nil, err
+	}
+	pass, err := s.wallets.Apple.Package(s.applePass(ticket, ownerID), walletPassImages)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pass, ticket, nil
+}
+
+// RegisterAppleDevice подписывает устройство на обновления пасса. Возвращает
+// false, если устройство уже было подписано; push-токен при этом обновляется.
+func (s *BookingService) RegisterAppleDevice(deviceID, passTypeID, serial, authToken, pushToken string) (bool, error) {
+	ticketID, ownerID, err := s.authorizeApplePass(passTypeID, serial, authToken)
+	if err != nil {
+		return false, err
+	}
+
+	created := false
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var ticket models.Ticket
+		if err := tx.Select("id", "version").Where("id = ? AND deleted_at IS NULL", ticketID).First(&ticket).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrWalletPassNotFound
+			}
+			return err
+		}
+
+		var registration models.WalletRegistration
+		err := tx.Where("platform = ? AND device_library_id = ? AND serial_number = ? AND deleted_at IS NULL",
+			models.WalletPlatformApple, deviceID, serial).
+			First(&registration).Error
+		if err == nil {
+			return tx.Model(&registration).Update("push_token", pushToken).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		registration = models.WalletRegistration{
+			TicketID:        ticketID,
+			UserID:          ownerID,
+			Platform:        models.WalletPlatformApple,
+			SerialNumber:    serial,
+			DeviceLibraryID: &deviceID,
+			PushToken:       &pushToken,
+			PushedVersion:   ticket.Version,
+		}
+		if err := tx.Create(&registration).Error; err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+// UnregisterAppleDevice отписывает устройство от обновлений пасса, например
+// после его удаления из Wallet
+func (s *BookingService) UnregisterAppleDevice(deviceID, passTypeID, serial, authToken string) error {
+	if _, _, err := s.authorizeApplePass(passTypeID, serial, authToken); err != nil {
+		return err
+	}
+	return s.db.Model(&models.WalletRegistration{}).
+		Where("platform = ? AND device_library_id = ? AND serial_number = ? AND deleted_at IS NULL",
+			models.WalletPlatformApple, deviceID, serial).
+		Update("deleted_at", time.Now()).Error
+}
+
+// UpdatedApplePasses возвращает серийные номера пассов устройства, билеты
+// которых изменились после метки since, и новую метку. Метка — время
+// последнего изменения в микросекундах; пустая метка означает «все пассы».
+func (s *BookingService) UpdatedApplePasses(deviceID, passTypeID, since string) ([]string, string, error) {
+	if s.wallets == nil || s.wallets.Apple == nil {
+		return nil, "", ErrWalletNotConfigured
+	}
+	if passTypeID != s.wallets.Apple.PassTypeID() {
+		return nil, "", ErrWalletPassNotFound
+	}
+
+	query := s.db.Table("wallet_registrations").
+		Select("wallet_registrations.serial_number, tickets.updated_at").
+		Joins("JOIN tickets ON tickets.id = wallet_registrations.ticket_id").
+		Where("wallet_registrations.platform = ? AND wallet_registrations.device_library_id = ? AND wallet_registrations.deleted_at IS NULL",
+			models.WalletPlatformApple, deviceID)
+	if micros, err := strconv.ParseInt(since, 10, 64); err == nil {
+		query = query.Where("tickets.updated_at > ?", time.UnixMicro(micros))
+	}
+
+	var rows []struct {
+		SerialNumber string
+		UpdatedAt    time.Time
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, "", err
+	}
+
+	serials := make([]string, 0, len(rows))
+	var lastUpdated time.Time
+	for _, row := range rows {
+		serials = append(serials, row.SerialNumber)
+		if row.UpdatedAt.After(lastUpdated) {
+			lastUpdated = row.UpdatedAt
+		}
+	}
+	return serials, strconv.FormatInt(lastUpdated.UnixMicro(), 10), nil
+}
+
+// pendingWalletUpdate — регистрация пасса, билет которого изменился
+type pendingWalletUpdate struct {
+	models.WalletRegistration
+	TicketVersion int64
+}
+
+// PushWalletUpdates сообщает кошелькам об изменившихся билетах и возвращает
+// число обновлённых регистраций. Устройства Apple получают один push на
+// токен, после чего сами запрашивают пассы; объекты Google заменяются
+// целиком. Неудачные обновления повторяются при следующем проходе.
+func (s *BookingService) PushWalletUpdates(ctx context.Context) (int, error) {
+	if !s.wallets.enabled() {
+		return 0, nil
+	}
+	platforms := []models.WalletPlatform{}
+	if s.wallets.Apple != nil {
+		platforms = append(platforms, models.WalletPlatformApple)
+	}
+	if s.wallets.Google != nil {
+		platforms = append(platforms, models.WalletPlatformGoogle)
+	}
+
+	var pending []pendingWalletUpdate
+	err := s.db.WithContext(ctx).Table("wallet_registrations").
+		Select("wallet_registrations.*, tickets.version AS ticket_version").
+		Joins("JOIN tickets ON tickets.id = wallet_registrations.ticket_id").
+		Where("wallet_registrations.deleted_at IS NULL AND wallet_registrations.platform IN ?", platforms).
+		Where("tickets.version > wallet_registrations.pushed_version").
+		Order("wallet_registrations.created_at").
+		Limit(walletPushBatch).
+		Scan(&pending).Error
+	if err != nil {
+		return 0, err
+	}
+
+	pushed := 0
+	applePushes := map[string]error{}
+	for _, update := range pending {
+		var err error
+		switch update.Platform {
+		case models.WalletPlatformApple:
+			token := *update.PushToken
+			result, done := applePushes[token]
+			if !done {
+				result = s.wallets.Apple.Push(ctx, token)
+				applePushes[token] = result
+			}
+			if errors.Is(result, wallet.ErrPushTokenGone) {
+				// Пасс удалён с устройства, а отписаться оно не успело
+				dropErr := s.db.Model(&models.WalletRegistration{}).
+					Where("platform = ? AND push_token = ? AND deleted_at IS NULL", models.WalletPlatformApple, token).
+					Update("deleted_at", time.Now()).Error
+				if dropErr != nil {
+					log.Printf("⚠️ Failed to drop wallet registrations of a stale push token: %v", dropErr)
+				}
+				continue
+			}
+			err = result
+		case models.WalletPlatformGoogle:
+			err = s.pushGoogleObject(ctx, &update.WalletRegistration)
+			if errors.Is(err, wallet.ErrObjectNotFound) {
+				// Пользователь так и не сохранил пасс
+				err = nil
+			}
+		}
+		if err != nil {
+			log.Printf("⚠️ Wallet pass update for ticket %s failed: %v", update.TicketID, err)
+			continue
+		}
+
+		err = s.db.Model(&models.WalletRegistration{}).
+			Where("id = ? AND pushed_version < ?", update.ID, update.TicketVersion).
+			Update("pushed_version", update.TicketVersion).Error
+		if err != nil {
+			return pushed, err
+		}
+		pushed++
+	}
+	return pushed, nil
+}
+
+// pushGoogleObject заменяет объект Google Wallet актуальной версией пасса
+func (s *BookingService) pushGoogleObject(ctx context.Context, registration *models.WalletRegistration) error {
+	ticket, err := loadWalletTicket(s.db.WithContext(ctx), registration.TicketID)
+	if err != nil {
+		return err
+	}
+	return s.wallets.Google.UpdateObject(ctx, s.googleObject(ticket, registration.UserID))
+}
+
+// StartWalletPusher периодически сообщает кошелькам об изменившихся билетах.
+// Если ни один кошелёк не настроен, сразу возвращается.
+func (s *BookingService) StartWalletPusher(ctx context.Context) {
+	if !s.wallets.enabled() {
+		return
+	}
+	ticker := time.NewTicker(s.config.WalletPushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pushed, err := s.PushWalletUpdates(ctx)
+			if err != nil {
+				log.Printf("⚠️ Wallet pass updates failed: %v", err)
+				continue
+			}
+			if pushed > 0 {
+				log.Printf("📲 Wallet passes updated: %d", pushed)
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Package etag связывает версии сущностей с заголовками ETag и If-Match
+package etag
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrInvalidIfMatch возвращается, если If-Match не содержит версию сущности
+var ErrInvalidIfMatch = errors.New("invalid If-Match header: expected an entity tag returned by the API")
+
+// Format возвращает ETag для версии сущности
+func Format(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// Set выставляет заголовок ETag ответа
+func Set(c *gin.Context, version int64) {
+	c.Header("ETag", Format(version))
+}
+
+// NoneMatch сообщает, что заголовок If-None-Match совпадает с tag и клиент
+// может использовать сохранённую копию ответа
+func NoneMatch(c *gin.Context, tag string) bool {
+	header := strings.TrimSpace(c.GetHeader("If-None-Match"))
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == strings.TrimPrefix(tag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// IfMatch возвращает версию из заголовка If-Match. Без заголовка и для "*"
+// возвращается nil: изменение выполняется без проверки версии. Слабые теги
+// (W/"3") принимаются, так как версия меняется при любом изменении сущности.
+func IfMatch(c *gin.Context) (*int64, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+	if strings.Contains(header, ",") {
+		return nil, ErrInvalidIfMatch
+	}
+
+	tag := strings.TrimPrefix(header, "W/")
+	if len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+		return nil, ErrInvalidIfMatch
+	}
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version < 1 {
+		return nil, ErrInvalidIfMatch
+	}
+	return &version, nil
+}
+
+// ParseIfMatch разбирает If-Match и при ошибке отвечает 400
+func ParseIfMatch(c *gin.Context) (*int64, bool) {
+	version, err := IfMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_IF_MATCH",
+				"message": err.Error(),
+			},
+		})
+		return nil, false
+	}
+	return version, true
+}
+
+// RespondConflict отвечает 409 с текущим состоянием сущности и её ETag,
+// чтобы клиент мог показать изменения и повторить запрос
+func RespondConflict(c *gin.Context, current interface{}, version int64) {
+	Set(c, version)
+	c.JSON(http.StatusConflict, gin.H{
+		"success": false,
+		"error": map[string]interface{}{
+			"code":    "VERSION_CONFLICT",
+			"message": "The resource was changed by someone else; reload it and try again",
+		},
+		"data": current,
+	})
+}
@@ -0,0 +1,227 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// HeaderKey — заголовок, в котором клиент передаёт ключ идемпотентности
+	HeaderKey = "Idempotency-Key"
+	// HeaderReplayed выставляется в ответах, повторённых из хранилища
+	HeaderReplayed = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// Config содержит настройки идемпотентности
+type Config struct {
+	// TTL — сколько хранится ключ и ответ на запрос с ним
+	TTL time.Duration
+	// LockTimeout — через сколько незавершённый запрос считается брошенным
+	// и ключ можно использовать снова
+	LockTimeout time.Duration
+	// CleanupInterval — как часто удаляются истёкшие ключи
+	CleanupInterval time.Duration
+}
+
+// DefaultConfig возвращает настройки по умолчанию
+func DefaultConfig() Config {
+	return Config{
+		TTL:             24 * time.Hour,
+		LockTimeout:     time.Minute,
+		CleanupInterval: time.Hour,
+	}
+}
+
+// Middleware повторяет сохранённый ответ на запрос, повторённый клиентом
+// с тем же заголовком Idempotency-Key
+type Middleware struct {
+	store  Store
+	config Config
+}
+
+// NewMiddleware создаёт middleware поверх store
+func NewMiddleware(store Store, config Config) *Middleware {
+	return &Middleware{
+		store:  store,
+		config: config,
+	}
+}
+
+// Idempotent выполняет запрос с ключом идемпотентности не более одного раза.
+// Повтор с тем же ключом и телом получает сохранённый ответ, тот же ключ с
+// другим запросом отклоняется. Запросы без заголовка выполняются как обычно.
+// Ключи принадлежат пользователю, поэтому middleware ставится после AuthRequired.
+func (m *Middleware) Idempotent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(HeaderKey)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": map[string]interface{}{
+					"code":    "INVALID_IDEMPOTENCY_KEY",
+					"message": fmt.Sprintf("Idempotency key must be at most %d characters", maxKeyLength),
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": map[string]interface{}{
+					"code":    "INVALID_REQUEST",
+					"message": "Failed to read request body",
+				},
+			})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := requestScope(c)
+		fingerprint := requestFingerprint(c.Request, body)
+
+		existing, err := m.store.Reserve(scope, key, fingerprint, m.config.TTL, m.config.LockTimeout)
+		if err != nil {
+			log.Printf("⚠️ Idempotency key reservation failed: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error": map[string]interface{}{
+					"code":    "DATABASE_ERROR",
+					"message": "Failed to process idempotency key",
+				},
+			})
+			c.Abort()
+			return
+		}
+		if existing != nil {
+			m.respondExisting(c, existing, fingerprint)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		completed := false
+		defer func() {
+			if !completed {
+				if err := m.store.Release(scope, key); err != nil {
+					log.Printf("⚠️ Failed to release idempotency key: %v", err)
+				}
+			}
+		}()
+
+		c.Next()
+
+		// Ошибки сервера не сохраняем: повтор должен выполнить запрос заново
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		err = m.store.Complete(scope, key, Response{
+			Status:      status,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+		if err != nil {
+			log.Printf("⚠️ Failed to store idempotent response: %v", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// respondExisting отвечает на запрос с уже использованным ключом
+func (m *Middleware) respondExisting(c *gin.Context, record *Record, fingerprint string) {
+	switch {
+	case record.Fingerprint != fingerprint:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "IDEMPOTENCY_KEY_REUSED",
+				"message": "Idempotency key was already used with a different request",
+			},
+		})
+	case record.Response == nil:
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "IDEMPOTENCY_KEY_IN_USE",
+				"message": "A request with this idempotency key is still being processed",
+			},
+		})
+	default:
+		c.Header(HeaderReplayed, "true")
+		c.Data(record.Response.Status, record.Response.ContentType, record.Response.Body)
+	}
+	c.Abort()
+}
+
+// StartCleanup периодически удаляет истёкшие ключи до отмены ctx
+func (m *Middleware) StartCleanup(ctx context.Context) {
+	ticker := time.NewTicker(m.config.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.store.DeleteExpired(); err != nil {
+				log.Printf("⚠️ Failed to delete expired idempotency keys: %v", err)
+			}
+		}
+	}
+}
+
+// requestScope возвращает владельца ключа: пользователя, если он известен
+func requestScope(c *gin.Context) string {
+	if userID, exists := c.Get("user_id"); exists {
+		return fmt.Sprintf("user:%v", userID)
+	}
+	return "anonymous"
+}
+
+// requestFingerprint вычисляет отпечаток запроса по методу, пути и телу
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(r.URL.RequestURI()))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder копирует тело ответа, чтобы его можно было сохранить
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
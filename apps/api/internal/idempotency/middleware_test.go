@@ -0,0 +1,235 @@
+package idempotency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryStore — Store в памяти с теми же правилами резервирования, что у PostgresStore
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]*Record
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{records: map[string]*Record{}}
+}
+
+func (s *memoryStore) Reserve(scope, key, fingerprint string, ttl, lockTimeout time.Duration) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if existing, ok := s.records[scope+"\x00"+key]; ok && now.Before(existing.ExpiresAt) {
+		abandoned := existing.Status == StatusProcessing && now.Sub(existing.CreatedAt) > lockTimeout
+		if !abandoned {
+			record := *existing
+			return &record, nil
+		}
+	}
+	s.records[scope+"\x00"+key] = &Record{
+		Scope:       scope,
+		Key:         key,
+		Fingerprint: fingerprint,
+		Status:      StatusProcessing,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}
+	return nil, nil
+}
+
+func (s *memoryStore) Complete(scope, key string, response Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[scope+"\x00"+key]
+	if !ok || record.Status != StatusProcessing {
+		return ErrKeyNotReserved
+	}
+	record.Status = StatusCompleted
+	record.Response = &response
+	return nil
+}
+
+func (s *memoryStore) Release(scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[scope+"\x00"+key]
+	if !ok || record.Status != StatusProcessing {
+		return ErrKeyNotReserved
+	}
+	delete(s.records, scope+"\x00"+key)
+	return nil
+}
+
+func (s *memoryStore) DeleteExpired() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for id, record := range s.records {
+		if !time.Now().Before(record.ExpiresAt) {
+			delete(s.records, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// testRouter возвращает маршрут POST /bookings с middleware поверх handler.
+// Пользователь берётся из заголовка X-User, как его выставил бы AuthRequired.
+func testRouter(store Store, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	auth := func(c *gin.Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			c.Set("user_id", user)
+		}
+	}
+	router.POST("/bookings", auth, NewMiddleware(store, DefaultConfig()).Idempotent(), handler)
+	return router
+}
+
+func doRequest(router http.Handler, user, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/bookings", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	if key != "" {
+		req.Header.Set(HeaderKey, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// countingHandler отвечает 201 с номером вызова, чтобы повтор был отличим от нового выполнения
+func countingHandler(calls *int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		*calls++
+		c.JSON(http.StatusCreated, gin.H{"call": *calls})
+	}
+}
+
+func TestIdempotentReplaysStoredResponse(t *testing.T) {
+	var calls int
+	router := testRouter(newMemoryStore(), countingHandler(&calls))
+
+	first := doRequest(router, "u1", "key-1", `{"guests":2}`)
+	second := doRequest(router, "u1", "key-1", `{"guests":2}`)
+
+	if calls != 1 {
+		t.Fatalf("handler called %d times, want 1", calls)
+	}
+	if second.Code != first.Code || second.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %s, want %d %s", second.Code, second.Body, first.Code, first.Body)
+	}
+	if got := second.Header().Get(HeaderReplayed); got != "true" {
+		t.Errorf("replay %s header = %q, want %q", HeaderReplayed, got, "true")
+	}
+	if got := first.Header().Get(HeaderReplayed); got != "" {
+		t.Errorf("first response %s header = %q, want none", HeaderReplayed, got)
+	}
+	if got := second.Header().Get("Content-Type"); got != first.Header().Get("Content-Type") {
+		t.Errorf("replay Content-Type = %q, want %q", got, first.Header().Get("Content-Type"))
+	}
+}
+
+func TestIdempotentRejectsKeyReuseWithDifferentBody(t *testing.T) {
+	var calls int
+	router := testRouter(newMemoryStore(), countingHandler(&calls))
+
+	doRequest(router, "u1", "key-1", `{"guests":2}`)
+	w := doRequest(router, "u1", "key-1", `{"guests":3}`)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+}
+
+func TestIdempotentRejectsKeyInProgress(t *testing.T) {
+	var router *gin.Engine
+	var nested *httptest.ResponseRecorder
+	router = testRouter(newMemoryStore(), func(c *gin.Context) {
+		// Повтор приходит, пока первый запрос ещё выполняется
+		if nested == nil {
+			nested = doRequest(router, "u1", "key-1", `{"guests":2}`)
+		}
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+
+	first := doRequest(router, "u1", "key-1", `{"guests":2}`)
+
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first status = %d, want %d", first.Code, http.StatusCreated)
+	}
+	if nested.Code != http.StatusConflict {
+		t.Errorf("concurrent retry status = %d, want %d", nested.Code, http.StatusConflict)
+	}
+}
+
+func TestIdempotentReleasesKeyAfterServerError(t *testing.T) {
+	var calls int
+	router := testRouter(newMemoryStore(), func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"success": true})
+	})
+
+	first := doRequest(router, "u1", "key-1", `{"guests":2}`)
+	retry := doRequest(router, "u1", "key-1", `{"guests":2}`)
+
+	if first.Code != http.StatusInternalServerError {
+		t.Fatalf("first status = %d, want %d", first.Code, http.StatusInternalServerError)
+	}
+	if retry.Code != http.StatusCreated || calls != 2 {
+		t.Errorf("retry status = %d after %d calls, want %d after 2", retry.Code, calls, http.StatusCreated)
+	}
+	if got := retry.Header().Get(HeaderReplayed); got != "" {
+		t.Errorf("retry %s header = %q, want none", HeaderReplayed, got)
+	}
+}
+
+func TestIdempotentScopesKeysPerUser(t *testing.T) {
+	var calls int
+	router := testRouter(newMemoryStore(), countingHandler(&calls))
+
+	first := doRequest(router, "u1", "key-1", `{"guests":2}`)
+	other := doRequest(router, "u2", "key-1", `{"guests":3}`)
+
+	if other.Code != http.StatusCreated || calls != 2 {
+		t.Fatalf("other user's request status = %d after %d calls, want %d after 2", other.Code, calls, http.StatusCreated)
+	}
+	if other.Body.String() == first.Body.String() {
+		t.Errorf("other user got the first user's response %s", first.Body)
+	}
+	if got := other.Header().Get(HeaderReplayed); got != "" {
+		t.Errorf("other user's %s header = %q, want none", HeaderReplayed, got)
+	}
+}
+
+func TestIdempotentWithoutKeyAlwaysRuns(t *testing.T) {
+	var calls int
+	router := testRouter(newMemoryStore(), countingHandler(&calls))
+
+	doRequest(router, "u1", "", `{"guests":2}`)
+	doRequest(router, "u1", "", `{"guests":2}`)
+
+	if calls != 2 {
+		t.Errorf("handler called %d times, want 2", calls)
+	}
+}
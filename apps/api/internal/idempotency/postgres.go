@@ -0,0 +1,141 @@
+package idempotency
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PostgresStore хранит ключи идемпотентности в таблице idempotency_keys
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+// NewPostgresStore создаёт хранилище ключей поверх db
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// keyRow — строка таблицы idempotency_keys
+type keyRow struct {
+	Scope               string
+	Key                 string
+	Fingerprint         string
+	Status              string
+	ResponseStatus      *int
+	ResponseContentType *string
+	ResponseBody        []byte
+	CreatedAt           time.Time
+	CompletedAt         *time.Time
+	ExpiresAt           time.Time
+}
+
+func (keyRow) TableName() string {
+	return "idempotency_keys"
+}
+
+// Reserve резервирует ключ одним запросом: новая строка вставляется, а
+// истёкшая или брошенная перезаписывается. Одновременные запросы с одним
+// ключом упираются в уникальный индекс, и резервирует ключ только один из них.
+func (s *PostgresStore) Reserve(scope, key, fingerprint string, ttl, lockTimeout time.Duration) (*Record, error) {
+	now := time.Now()
+
+	var reserved []string
+	err := s.db.Raw(`
+		INSERT INTO idempotency_keys (scope, key, fingerprint, status, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (scope, key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint,
+			status = EXCLUDED.status,
+			response_status = NULL,
+			response_content_type = NULL,
+			response_body = NULL,
+			created_at = EXCLUDED.created_at,
+			completed_at = NULL,
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= ?
+			OR (idempotency_keys.status = ? AND idempotency_keys.created_at <= ?)
+		RETURNING key`,
+		scope, key, fingerprint, StatusProcessing, now, now.Add(ttl),
+		now, StatusProcessing, now.Add(-lockTimeout)).
+		Scan(&reserved).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if len(reserved) > 0 {
+		return nil, nil
+	}
+
+	var row keyRow
+	if err := s.db.Where("scope = ? AND key = ?", scope, key).First(&row).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Ключ удалили между вставкой и чтением — пусть клиент повторит
+			return nil, fmt.Errorf("idempotency key %q disappeared: %w", key, err)
+		}
+		return nil, err
+	}
+	return row.record(), nil
+}
+
+// Complete сохраняет ответ для зарезервированного ключа
+func (s *PostgresStore) Complete(scope, key string, response Response) error {
+	now := time.Now()
+	result := s.db.Model(&keyRow{}).
+		Where("scope = ? AND key = ? AND status = ?", scope, key, StatusProcessing).
+		Updates(map[string]interface{}{
+			"status":                StatusCompleted,
+			"response_status":       response.Status,
+			"response_content_type": response.ContentType,
+			"response_body":         response.Body,
+			"completed_at":          now,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrKeyNotReserved
+	}
+	return nil
+}
+
+// Release удаляет зарезервированный ключ
+func (s *PostgresStore) Release(scope, key string) error {
+	err := s.db.Where("scope = ? AND key = ? AND status = ?", scope, key, StatusProcessing).
+		Delete(&keyRow{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired удаляет истёкшие ключи
+func (s *PostgresStore) DeleteExpired() (int64, error) {
+	result := s.db.Where("expires_at <= ?", time.Now()).Delete(&keyRow{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+func (r keyRow) record() *Record {
+	record := &Record{
+		Scope:       r.Scope,
+		Key:         r.Key,
+		Fingerprint: r.Fingerprint,
+		Status:      r.Status,
+		CreatedAt:   r.CreatedAt,
+		ExpiresAt:   r.ExpiresAt,
+	}
+	if r.Status == StatusCompleted && r.ResponseStatus != nil {
+		record.Response = &Response{
+			Status: *r.ResponseStatus,
+			Body:   r.ResponseBody,
+		}
+		if r.ResponseContentType != nil {
+			record.Response.ContentType = *r.ResponseContentType
+		}
+	}
+	return record
+}
@@ -0,0 +1,49 @@
+package idempotency
+
+import (
+	"errors"
+	"time"
+)
+
+// Статусы ключа идемпотентности
+const (
+	StatusProcessing = "processing"
+	StatusCompleted  = "completed"
+)
+
+// ErrKeyNotReserved возвращается, если ключ не зарезервирован этим запросом
+var ErrKeyNotReserved = errors.New("idempotency key is not reserved")
+
+// Record — сохранённый ключ идемпотентности и ответ на первый запрос с ним
+type Record struct {
+	Scope       string
+	Key         string
+	Fingerprint string
+	Status      string
+	Response    *Response
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// Response — ответ, который повторяется клиенту при повторе запроса
+type Response struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// Store хранит ключи идемпотентности вместе с отпечатком запроса и ответом
+type Store interface {
+	// Reserve резервирует ключ за запросом с отпечатком fingerprint на ttl.
+	// Если ключ уже занят действующей записью, резервирования не происходит
+	// и возвращается эта запись; при успешном резервировании запись nil.
+	// Ключ в статусе processing старше lockTimeout считается брошенным и
+	// резервируется заново.
+	Reserve(scope, key, fingerprint string, ttl, lockTimeout time.Duration) (*Record, error)
+	// Complete сохраняет ответ для зарезервированного ключа
+	Complete(scope, key string, response Response) error
+	// Release снимает резервирование, чтобы запрос можно было повторить
+	Release(scope, key string) error
+	// DeleteExpired удаляет истёкшие ключи и возвращает их количество
+	DeleteExpired() (int64, error)
+}
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// Human-readable numbers look like SP-24-7F3K9Q: a prefix, the two-digit
+// year and five random Crockford base32 symbols followed by a check symbol.
+// The check symbol is a Luhn mod 32 digit over the year and random part, so
+// a single mistyped symbol or swapped neighbours are caught before lookup.
+const (
+	BookingNumberPrefix = "SP"
+	TicketNumberPrefix  = "TK"
+
+	numberAlphabet    = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	numberRandomLen   = 5
+	numberMaxAttempts = 10
+)
+
+// ErrInvalidNumber is returned when a booking or ticket number is malformed
+// or its check symbol does not match
+var ErrInvalidNumber = errors.New("invalid number")
+
+// GenerateBookingNumber returns a new random booking number
+func GenerateBookingNumber() (string, error) {
+	return generateNumber(BookingNumberPrefix, time.Now())
+}
+
+// GenerateTicketNumber returns a new random ticket number
+func GenerateTicketNumber() (string, error) {
+	return generateNumber(TicketNumberPrefix, time.Now())
+}
+
+// ParseBookingNumber validates a booking number typed by a person and
+// returns it in canonical form
+func ParseBookingNumber(raw string) (string, error) {
+	return parseNumber(BookingNumberPrefix, raw)
+}
+
+// ParseTicketNumber validates a ticket number typed by a person and
+// returns it in canonical form
+func ParseTicketNumber(raw string) (string, error) {
+	return parseNumber(TicketNumberPrefix, raw)
+}
+
+// Unique indexes guarding the number columns (migration 004)
+const (
+	bookingNumberIndex = "idx_bookings_booking_number"
+	ticketNumberIndex  = "idx_tickets_ticket_number"
+)
+
+// BeforeCreate draws a booking number for bookings inserted directly. Use
+// CreateBooking to retry when the number is already taken.
+func (b *Booking) BeforeCreate(tx *gorm.DB) error {
+	if b.BookingNumber != "" {
+		return nil
+	}
+	number, err := GenerateBookingNumber()
+	if err != nil {
+		return err
+	}
+	b.BookingNumber = number
+	return nil
+}
+
+// BeforeCreate draws a ticket number for tickets inserted directly. Use
+// CreateTicket to retry when the number is already taken.
+func (t *Ticket) BeforeCreate(tx *gorm.DB) error {
+	if t.TicketNumber != "" {
+		return nil
+	}
+	number, err := GenerateTicketNumber()
+	if err != nil {
+		return err
+	}
+	t.TicketNumber = number
+	return nil
+}
+
+// CreateBooking inserts a booking with a newly allocated booking number
+func CreateBooking(tx *gorm.DB, b *Booking) error {
+	return AllocateNumber(tx, bookingNumberIndex, GenerateBookingNumber, func(tx *gorm.DB, number string) error {
+		b.BookingNumber = number
+		return tx.Create(b).Error
+	})
+}
+
+// CreateTicket inserts a ticket with a newly allocated ticket number
+func CreateTicket(tx *gorm.DB, t *Ticket) error {
+	return AllocateNumber(tx, ticketNumberIndex, GenerateTicketNumber, func(tx *gorm.DB, number string) error {
+		t.TicketNumber = number
+		return tx.Create(t).Error
+	})
+}
+
+// AssignBookingNumber gives a booking created before short numbers existed
+// a booking number. It reports false if the booking already has one.
+func AssignBookingNumber(tx *gorm.DB, id string) (bool, error) {
+	return assignNumber(tx, &Booking{}, id, "booking_number", bookingNumberIndex, GenerateBookingNumber)
+}
+
+// AssignTicketNumber gives a ticket created before short numbers existed
+// a ticket number. It reports false if the ticket already has one.
+func AssignTicketNumber(tx *gorm.DB, id string) (bool, error) {
+	return assignNumber(tx, &Ticket{}, id, "ticket_number", ticketNumberIndex, GenerateTicketNumber)
+}
+
+func assignNumber(tx *gorm.DB, model interface{}, id, column, index string, generate func() (string, error)) (bool, error) {
+	var assigned bool
+	err := AllocateNumber(tx, index, generate, func(tx *gorm.DB, number string) error {
+		result := tx.Model(model).Where("id = ? AND "+column+" IS NULL", id).Update(column, number)
+		assigned = result.RowsAffected > 0
+		return result.Error
+	})
+	return assigned, err
+}
+
+// AllocateNumber draws a number with generate and passes it to write, which
+// inserts or updates the row carrying it. The write runs in a savepoint (or
+// its own transaction), so when the unique index rejects a number taken by a
+// concurrent allocation, only the savepoint is rolled back and another number
+// is drawn. Numbers are not checked beforehand: a check and a later insert
+// would race.
+func AllocateNumber(tx *gorm.DB, index string, generate func() (string, error), write func(tx *gorm.DB, number string) error) error {
+	for attempt := 0; attempt < numberMaxAttempts; attempt++ {
+		number, err := generate()
+		if err != nil {
+			return err
+		}
+
+		err = tx.Transaction(func(tx *gorm.DB) error {
+			return write(tx, number)
+		})
+		if !isUniqueViolation(err, index) {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to allocate a number unique in %s after %d attempts", index, numberMaxAttempts)
+}
+
+// isUniqueViolation reports whether err is a unique violation of index
+func isUniqueViolation(err error, index string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == index
+}
+
+func generateNumber(prefix string, now time.Time) (string, error) {
+	random := make([]byte, numberRandomLen)
+	max := big.NewInt(int64(len(numberAlphabet)))
+	for i := range random {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate number: %w", err)
+		}
+		random[i] = numberAlphabet[n.Int64()]
+	}
+
+	year := fmt.Sprintf("%02d", now.Year()%100)
+	body := year + string(random)
+	return prefix + "-" + year + "-" + string(random) + string(checkSymbol(body)), nil
+}
+
+func parseNumber(prefix, raw string) (string, error) {
+	value := strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(raw))
+	if !strings.HasPrefix(value, prefix) {
+		return "", ErrInvalidNumber
+	}
+
+	// Crockford base32 treats O as 0 and I, L as 1
+	body := strings.NewReplacer("O", "0", "I", "1", "L", "1").Replace(value[len(prefix):])
+	if len(body) != 2+numberRandomLen+1 {
+		return "", ErrInvalidNumber
+	}
+	for i, r := range body {
+		if i < 2 && (r < '0' || r > '9') {
+			return "", ErrInvalidNumber
+		}
+		if !strings.ContainsRune(numberAlphabet, r) {
+			return "", ErrInvalidNumber
+		}
+	}
+
+	payload, check := body[:len(body)-1], body[len(body)-1]
+	if checkSymbol(payload) != check {
+		return "", ErrInvalidNumber
+	}
+	return prefix + "-" + body[:2] + "-" + body[2:], nil
+}
+
+// checkSymbol computes the Luhn mod 32 check symbol of value
+func checkSymbol(value string) byte {
+	n := len(numberAlphabet)
+	factor := 2
+	sum := 0
+	for i := len(value) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(numberAlphabet, value[i])
+		addend = addend/n + addend%n
+		sum += addend
+		if factor == 2 {
+			factor = 1
+		} else {
+			factor = 2
+		}
+	}
+	return numberAlphabet[(n-sum%n)%n]
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckSymbol(t *testing.T) {
+	tests := []struct {
+		value string
+		want  byte
+	}{
+		{"0000000", '0'},
+		// 1*2 = 2, check = 32 - 2 = 30
+		{"1", 'Y'},
+		// Z = 31, 31*2 = 62 folds to 1 + 30 = 31, check = 1
+		{"Z", '1'},
+		// 4*2 + 2 = 10, check = 22
+		{"24", 'P'},
+		// Doubling starts at the rightmost symbol: 2*2 + 4 = 8, check = 24
+		{"42", 'R'},
+	}
+	for _, tt := range tests {
+		if got := checkSymbol(tt.value); got != tt.want {
+			t.Errorf("checkSymbol(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	valid := "SP-24-7F3K9" + string(checkSymbol("247F3K9"))
+	withOnes := "SP-24-10001" + string(checkSymbol("2410001"))
+
+	tests := []struct {
+		name    string
+		prefix  string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{name: "canonical", prefix: BookingNumberPrefix, raw: valid, want: valid},
+		{name: "lower case", prefix: BookingNumberPrefix, raw: strings.ToLower(valid), want: valid},
+		{name: "no dashes", prefix: BookingNumberPrefix, raw: strings.ReplaceAll(valid, "-", ""), want: valid},
+		{name: "spaces", prefix: BookingNumberPrefix, raw: " SP 24 " + valid[6:], want: valid},
+		{name: "O, I and L read as digits", prefix: BookingNumberPrefix, raw: "SP-24-IOOOL" + withOnes[len(withOnes)-1:], want: withOnes},
+		{name: "ticket prefix", prefix: TicketNumberPrefix, raw: "TK" + valid[2:], want: "TK" + valid[2:]},
+		{name: "wrong prefix", prefix: TicketNumberPrefix, raw: valid, wantErr: true},
+		{name: "empty", prefix: BookingNumberPrefix, raw: "", wantErr: true},
+		{name: "too short", prefix: BookingNumberPrefix, raw: valid[:len(valid)-1], wantErr: true},
+		{name: "too long", prefix: BookingNumberPrefix, raw: valid + "0", wantErr: true},
+		{name: "letter in year", prefix: BookingNumberPrefix, raw: "SP-2A-7F3K9" + string(checkSymbol("2A7F3K9")), wantErr: true},
+		{name: "symbol outside alphabet", prefix: BookingNumberPrefix, raw: "SP-24-7F3U9" + string(checkSymbol("247F3K9")), wantErr: true},
+		{name: "wrong check symbol", prefix: BookingNumberPrefix, raw: valid[:len(valid)-1] + string(otherSymbol(valid[len(valid)-1])), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseNumber(tt.prefix, tt.raw)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidNumber) {
+					t.Fatalf("parseNumber(%q) error = %v, want ErrInvalidNumber", tt.raw, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseNumber(%q) error = %v", tt.raw, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseNumber(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeneratedNumbersParse(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 100; i++ {
+		number, err := generateNumber(BookingNumberPrefix, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(number, "SP-24-") {
+			t.Fatalf("generateNumber() = %q, want prefix SP-24-", number)
+		}
+		got, err := ParseBookingNumber(number)
+		if err != nil || got != number {
+			t.Fatalf("ParseBookingNumber(%q) = %q, %v", number, got, err)
+		}
+	}
+}
+
+// Every mistyped symbol must be caught by the check symbol
+func TestParseNumberDetectsSingleSubstitution(t *testing.T) {
+	number := "SP-24-7F3K9" + string(checkSymbol("247F3K9"))
+	for i := len("SP-24-"); i < len(number); i++ {
+		if number[i] == '-' {
+			continue
+		}
+		for j := 0; j < len(numberAlphabet); j++ {
+			symbol := numberAlphabet[j]
+			if symbol == number[i] {
+				continue
+			}
+			typo := number[:i] + string(symbol) + number[i+1:]
+			if _, err := parseNumber(BookingNumberPrefix, typo); err == nil {
+				t.Errorf("parseNumber(%q) accepted a typo of %q", typo, number)
+			}
+		}
+	}
+}
+
+// Luhn mod 32 catches swapped neighbours except the pair 0 and Z, whose
+// doubled values fold to the same sum
+func TestParseNumberDetectsTransposition(t *testing.T) {
+	for i := 0; i < len(numberAlphabet); i++ {
+		for j := 0; j < len(numberAlphabet); j++ {
+			a, b := numberAlphabet[i], numberAlphabet[j]
+			if a == b || (a == '0' && b == 'Z') || (a == 'Z' && b == '0') {
+				continue
+			}
+			payload := "24" + string(a) + string(b) + "7K9"
+			swapped := "24" + string(b) + string(a) + "7K9"
+			number := "SP-" + swapped + string(checkSymbol(payload))
+			if _, err := parseNumber(BookingNumberPrefix, number); err == nil {
+				t.Errorf("parseNumber(%q) accepted %c%c swapped", number, a, b)
+			}
+		}
+	}
+}
+
+// otherSymbol returns a symbol of the alphabet different from symbol
+func otherSymbol(symbol byte) byte {
+	if symbol == numberAlphabet[0] {
+		return numberAlphabet[1]
+	}
+	return numberAlphabet[0]
+}
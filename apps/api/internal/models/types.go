@@ -234,6 +234,52 @@ type Capacity struct {
 	LastUpdated time.Time `json:"lastUpdated"`
 }
 
+// PriceModifier adjusts ticket prices for matching days and time slots
+type PriceModifier struct {
+	Name      string      `json:"name" validate:"required,max=100"`
+	Days      []DayOfWeek `json:"days,omitempty"`
+	StartTime string      `json:"startTime" validate:"required"`
+	EndTime   string      `json:"endTime" validate:"required"`
+	Percent   float64     `json:"percent" validate:"min=-100,max=200"`
+}
+
+// CancellationRule refunds RefundPercent of the booking when it is
+// cancelled at least HoursBefore hours before the visit starts
+type CancellationRule struct {
+	HoursBefore   int     `json:"hoursBefore" validate:"min=0"`
+	RefundPercent float64 `json:"refundPercent" validate:"min=0,max=100"`
+}
+
+// CancellationPolicy defines how much is refunded on cancellation
+type CancellationPolicy struct {
+	Rules []CancellationRule `json:"rules"`
+}
+
+// GroupBookingSettings configures group (school, kindergarten) bookings
+type GroupBookingSettings struct {
+	MinGroupSize int `json:"minGroupSize"`
+	// One chaperone ticket is free for every ChildrenPerFreeChaperone children; 0 disables it
+	ChildrenPerFreeChaperone int  `json:"childrenPerFreeChaperone"`
+	RequiresApproval         bool `json:"requiresApproval"`
+	// Days the organisation has to pay the invoice by bank transfer
+	InvoiceDueDays int `json:"invoiceDueDays"`
+}
+
+// TicketTransferSettings controls whether customers may hand tickets over
+// to another person
+type TicketTransferSettings struct {
+	Enabled bool `json:"enabled"`
+}
+
+// ParkSettings holds per-park booking rules
+type ParkSettings struct {
+	PriceModifiers     []PriceModifier       `json:"priceModifiers,omitempty"`
+	CancellationPolicy *CancellationPolicy   `json:"cancellationPolicy,omitempty"`
+	Group              *GroupBookingSettings `json:"group,omitempty"`
+	// Transfers are allowed unless the park switches them off
+	TicketTransfer *TicketTransferSettings `json:"ticketTransfer,omitempty"`
+}
+
 // Park represents a children's entertainment park
 type Park struct {
 	BaseModel
@@ -243,7 +289,7 @@ type Park struct {
 	Status          ParkStatus       `json:"status" gorm:"default:active"`
 	
 	// Location
-	Address     Address     `json:"address" gorm:"type:jsonb"`
+	Address     Address     `json:"address" gorm:"type:jsonb;serializer:json"`
 	Coordinates Coordinates `json:"coordinates" gorm:"type:jsonb"`
 	
 	// Contact information
@@ -252,9 +298,9 @@ type Park struct {
 	Website     *string `json:"website,omitempty" validate:"omitempty,url"`
 	
 	// Operating information
-	OperatingHours []OperatingHours `json:"operatingHours" gorm:"type:jsonb"`
-	Amenities      []Amenity        `json:"amenities" gorm:"type:jsonb"`
-	Capacity       Capacity         `json:"capacity" gorm:"type:jsonb"`
+	OperatingHours []OperatingHours `json:"operatingHours" gorm:"type:jsonb;serializer:json"`
+	Amenities      []Amenity        `json:"amenities" gorm:"type:jsonb;serializer:json"`
+	Capacity       Capacity         `json:"capacity" gorm:"type:jsonb;serializer:json"`
 	
 	// Media
 	MainImage    *string     `json:"mainImage,omitempty"`
@@ -282,8 +328,12 @@ type Park struct {
 	TotalVisitors   int     `json:"totalVisitors" gorm:"default:0"`
 	MonthlyVisitors int     `json:"monthlyVisitors" gorm:"default:0"`
 	
+	// Booking rules
+	Settings ParkSettings `json:"settings" gorm:"type:jsonb;serializer:json"`
+	
 	// System fields
 	Metadata JSONB `json:"metadata" gorm:"type:jsonb"`
+	Version  int64 `json:"version" gorm:"default:1"`
 	
 	// Relationships
 	Bookings []Booking `json:"bookings,omitempty" gorm:"foreignKey:ParkID"`
@@ -313,6 +363,7 @@ const (
 	TicketTypeFamily    TicketType = "family"
 	TicketTypeVIP       TicketType = "vip"
 	TicketTypeUnlimited TicketType = "unlimited"
+	TicketTypePass      TicketType = "pass"
 )
 
 type AgeCategory string
@@ -350,6 +401,7 @@ type TicketValidation struct {
 // Ticket represents an admission ticket
 type Ticket struct {
 	BaseModel
+	TicketNumber string       `json:"ticketNumber" gorm:"uniqueIndex"`
 	BookingID   uuid.UUID     `json:"bookingId" gorm:"not null"`
 	ParkID      uuid.UUID     `json:"parkId" gorm:"not null"`
 	UserID      uuid.UUID     `json:"userId" gorm:"not null"`
@@ -372,10 +424,10 @@ type Ticket struct {
 	UsageCount  int       `json:"usageCount" gorm:"default:0"`
 	
 	// QR Code
-	QRCode QRCode `json:"qrCode" gorm:"type:jsonb"`
+	QRCode QRCode `json:"qrCode" gorm:"type:jsonb;serializer:json"`
 	
 	// Validation history
-	Validations []TicketValidation `json:"validations" gorm:"type:jsonb"`
+	Validations []TicketValidation `json:"validations" gorm:"type:jsonb;serializer:json"`
 	
 	// Additional info
 	HolderName           string      `json:"holderName" validate:"required,max=255"`
@@ -385,6 +437,7 @@ type Ticket struct {
 	
 	// System fields
 	Metadata JSONB `json:"metadata" gorm:"type:jsonb"`
+	Version  int64 `json:"version" gorm:"default:1"`
 	
 	// Relationships
 	Booking *Booking `json:"booking,omitempty" gorm:"foreignKey:BookingID"`
@@ -392,6 +445,119 @@ type Ticket struct {
 	User    *User    `json:"user,omitempty" gorm:"foreignKey:UserID"`
 }
 
+type TicketScanSource string
+
+const (
+	TicketScanSourceOnline  TicketScanSource = "online"
+	TicketScanSourceOffline TicketScanSource = "offline"
+)
+
+type TicketScanOutcome string
+
+const (
+	// TicketScanAccepted is an entry counted against the ticket
+	TicketScanAccepted TicketScanOutcome = "accepted"
+	// TicketScanConflict is an entry a device admitted that the server could
+	// not accept, e.g. a second entry on a single-use ticket
+	TicketScanConflict TicketScanOutcome = "conflict"
+	// TicketScanRefused is a scan the device refused, kept for audit
+	TicketScanRefused TicketScanOutcome = "refused"
+)
+
+// TicketScan is a gate scan reported by a scanner device, either live or
+// uploaded after the device worked offline
+type TicketScan struct {
+	BaseModel
+	TicketID       *uuid.UUID        `json:"ticketId,omitempty"`
+	ParkID         uuid.UUID         `json:"parkId" gorm:"not null"`
+	DeviceID       string            `json:"deviceId" gorm:"not null"`
+	ScanID         string            `json:"scanId" gorm:"not null"`
+	Source         TicketScanSource  `json:"source" gorm:"not null"`
+	QRData         string            `json:"-" gorm:"column:qr_data;not null"`
+	ScannedAt      time.Time         `json:"scannedAt" gorm:"not null"`
+	ScannedBy      *uuid.UUID        `json:"scannedBy,omitempty"`
+	Location       *string           `json:"location,omitempty"`
+	DeviceAdmitted bool              `json:"deviceAdmitted"`
+	DeviceReason   *string           `json:"deviceReason,omitempty"`
+	Outcome        TicketScanOutcome `json:"outcome" gorm:"not null"`
+	Reason         *string           `json:"reason,omitempty"`
+
+	// Staff review of conflicts
+	ResolvedAt     *time.Time `json:"resolvedAt,omitempty"`
+	ResolvedBy     *uuid.UUID `json:"resolvedBy,omitempty"`
+	ResolutionNote *string    `json:"resolutionNote,omitempty"`
+
+	// Relationships
+	Ticket *Ticket `json:"ticket,omitempty" gorm:"foreignKey:TicketID"`
+}
+
+type TicketTransferStatus string
+
+const (
+	TicketTransferPending   TicketTransferStatus = "pending"
+	TicketTransferAccepted  TicketTransferStatus = "accepted"
+	TicketTransferCancelled TicketTransferStatus = "cancelled"
+)
+
+// TicketTransfer hands a ticket over to the owner of RecipientPhone once
+// they confirm it with the code sent to that phone by SMS
+type TicketTransfer struct {
+	BaseModel
+	TicketID       uuid.UUID            `json:"ticketId" gorm:"not null"`
+	ParkID         uuid.UUID            `json:"parkId" gorm:"not null"`
+	FromUserID     uuid.UUID            `json:"fromUserId" gorm:"not null"`
+	RecipientPhone string               `json:"recipientPhone" gorm:"not null"`
+	RecipientName  *string              `json:"recipientName,omitempty"`
+	Status         TicketTransferStatus `json:"status" gorm:"default:pending"`
+	CodeHash       string               `json:"-" gorm:"not null"`
+	Attempts       int                  `json:"-" gorm:"default:0"`
+	ExpiresAt      time.Time            `json:"expiresAt" gorm:"not null"`
+	ToUserID       *uuid.UUID           `json:"toUserId,omitempty"`
+	AcceptedAt     *time.Time           `json:"acceptedAt,omitempty"`
+
+	// Relationships
+	Ticket *Ticket `json:"ticket,omitempty" gorm:"foreignKey:TicketID"`
+}
+
+type WalletPlatform string
+
+const (
+	WalletPlatformApple  WalletPlatform = "apple"
+	WalletPlatformGoogle WalletPlatform = "google"
+)
+
+// WalletRegistration records a ticket pass added to a mobile wallet so the
+// pass can be updated when the ticket changes. Apple passes are registered
+// per device by the Wallet app; Google passes once per saved object.
+type WalletRegistration struct {
+	BaseModel
+	TicketID        uuid.UUID      `json:"ticketId" gorm:"not null"`
+	UserID          uuid.UUID      `json:"userId" gorm:"not null"` // owner the pass was issued to
+	Platform        WalletPlatform `json:"platform" gorm:"not null"`
+	SerialNumber    string         `json:"serialNumber" gorm:"not null"` // Apple serial or Google object id
+	DeviceLibraryID *string        `json:"deviceLibraryId,omitempty"`
+	PushToken       *string        `json:"-"`
+	PushedVersion   int64          `json:"pushedVersion" gorm:"default:0"`
+}
+
+// PassProduct is a multi-visit product in a park's pass catalog.
+// A pass (TicketTypePass) allows Visits visits within ValidityDays; an
+// unlimited pass (TicketTypeUnlimited) allows one visit per day.
+type PassProduct struct {
+	BaseModel
+	ParkID       uuid.UUID  `json:"parkId" gorm:"not null"`
+	Name         string     `json:"name" validate:"required,max=255"`
+	Description  *string    `json:"description,omitempty" validate:"omitempty,max=1000"`
+	TicketType   TicketType `json:"ticketType" gorm:"not null"`
+	Visits       int        `json:"visits" validate:"min=0"`
+	ValidityDays int        `json:"validityDays" validate:"min=1"`
+	Price        float64    `json:"price" validate:"min=0"`
+	Currency     string     `json:"currency" gorm:"default:KGS"`
+	IsActive     bool       `json:"isActive" gorm:"default:true"`
+	
+	Park *Park `json:"park,omitempty" gorm:"foreignKey:ParkID"`
+}
+
 // ====================================
 // BOOKING TYPES
 // ====================================
@@ -421,6 +587,15 @@ const (
 	PaymentStatusPartiallyRefunded PaymentStatus = "partially_refunded"
 )
 
+type BookingType string
+
+const (
+	BookingTypeStandard BookingType = "standard"
+	BookingTypeGroup    BookingType = "group"
+	BookingTypePass     BookingType = "pass"
+	BookingTypeParty    BookingType = "party"
+)
+
 type BookingSource string
 
 const (
@@ -438,6 +613,7 @@ type GuestInfo struct {
 	AgeCategory         AgeCategory `json:"ageCategory"`
 	TicketType          TicketType  `json:"ticketType"`
 	SpecialRequirements StringArray `json:"specialRequirements" gorm:"type:text[]"`
+	PassTicketID        *uuid.UUID  `json:"passTicketId,omitempty"` // pass used instead of paying for the visit
 }
 
 // BookingItem represents an individual ticket in a booking
@@ -474,11 +650,13 @@ type ContactInfo struct {
 // Booking represents a booking/reservation
 type Booking struct {
 	BaseModel
+	BookingNumber string        `json:"bookingNumber" gorm:"uniqueIndex"`
 	UserID        uuid.UUID     `json:"userId" gorm:"not null"`
 	ParkID        uuid.UUID     `json:"parkId" gorm:"not null"`
 	Status        BookingStatus `json:"status" gorm:"default:draft"`
 	PaymentStatus PaymentStatus `json:"paymentStatus" gorm:"default:pending"`
 	Source        BookingSource `json:"source" gorm:"default:web"`
+	BookingType   BookingType   `json:"bookingType" gorm:"default:standard"`
 	
 	// Booking details
 	VisitDate   time.Time `json:"visitDate"`
@@ -486,7 +664,7 @@ type Booking struct {
 	Duration    int       `json:"duration" gorm:"default:180"` // minutes
 	
 	// Tickets
-	Items       []BookingItem `json:"items" gorm:"type:jsonb"`
+	Items       []BookingItem `json:"items" gorm:"type:jsonb;serializer:json"`
 	TotalGuests int           `json:"totalGuests" validate:"min=1"`
 	
 	// Pricing
@@ -497,13 +675,13 @@ type Booking struct {
 	Currency       string  `json:"currency" gorm:"default:KGS"`
 	
 	// Discounts and loyalty
-	Discounts           []DiscountInfo `json:"discounts" gorm:"type:jsonb"`
+	Discounts           []DiscountInfo `json:"discounts" gorm:"type:jsonb;serializer:json"`
 	LoyaltyPointsUsed   int            `json:"loyaltyPointsUsed" validate:"min=0"`
 	LoyaltyPointsEarned int            `json:"loyaltyPointsEarned" validate:"min=0"`
 	PromoCode           *string        `json:"promoCode,omitempty"`
 	
 	// Contact information
-	ContactInfo ContactInfo `json:"contactInfo" gorm:"type:jsonb"`
+	ContactInfo ContactInfo `json:"contactInfo" gorm:"type:jsonb;serializer:json"`
 	
 	// Special requirements
 	SpecialRequirements StringArray `json:"specialRequirements" gorm:"type:text[]"`
@@ -518,6 +696,13 @@ type Booking struct {
 	CancelledAt *time.Time `json:"cancelledAt,omitempty"`
 	RefundedAt  *time.Time `json:"refundedAt,omitempty"`
 	
+	// Capacity hold while awaiting payment
+	HoldExpiresAt *time.Time `json:"holdExpiresAt,omitempty"`
+	
+	// Party package details and deposit/balance schedule (party bookings)
+	Party           *PartyDetails      `json:"party,omitempty" gorm:"type:jsonb;serializer:json"`
+	PaymentSchedule []ScheduledPayment `json:"paymentSchedule,omitempty" gorm:"type:jsonb;serializer:json"`
+	
 	// Cancellation info
 	CancellationReason *string    `json:"cancellationReason,omitempty" validate:"omitempty,max=500"`
 	CancelledBy        *uuid.UUID `json:"cancelledBy,omitempty"`
@@ -526,6 +711,7 @@ type Booking struct {
 	
 	// System fields
 	Metadata JSONB `json:"metadata" gorm:"type:jsonb"`
+	Version  int64 `json:"version" gorm:"default:1"`
 	
 	// Relationships
 	User     *User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
@@ -534,6 +720,143 @@ type Booking struct {
 	Payments []Payment `json:"payments,omitempty" gorm:"foreignKey:BookingID"`
 }
 
+// ====================================
+// PARTY TYPES
+// ====================================
+
+// PartyRoom is a room or zone of a park that is booked for private parties
+type PartyRoom struct {
+	BaseModel
+	ParkID      uuid.UUID `json:"parkId" gorm:"not null"`
+	Name        string    `json:"name" validate:"required,max=255"`
+	Description *string   `json:"description,omitempty" validate:"omitempty,max=1000"`
+	MaxGuests   int       `json:"maxGuests" validate:"min=1"`
+	IsActive    bool      `json:"isActive" gorm:"default:true"`
+}
+
+// PartyAddOn is an optional extra of a party package, e.g. a cake or an animator
+type PartyAddOn struct {
+	Code  string  `json:"code" validate:"required,max=50"`
+	Name  string  `json:"name" validate:"required,max=255"`
+	Price float64 `json:"price" validate:"min=0"`
+}
+
+// PartyPackage is a birthday party or private event product: a room for a
+// fixed duration with a number of included guests
+type PartyPackage struct {
+	BaseModel
+	ParkID          uuid.UUID    `json:"parkId" gorm:"not null"`
+	RoomID          uuid.UUID    `json:"roomId" gorm:"not null"`
+	Name            string       `json:"name" validate:"required,max=255"`
+	Description     *string      `json:"description,omitempty" validate:"omitempty,max=1000"`
+	DurationMinutes int          `json:"durationMinutes" validate:"min=1"`
+	Price           float64      `json:"price" validate:"min=0"`
+	IncludedGuests  int          `json:"includedGuests" validate:"min=1"`
+	ExtraGuestPrice float64      `json:"extraGuestPrice" validate:"min=0"`
+	AddOns          []PartyAddOn `json:"addOns" gorm:"type:jsonb;serializer:json"`
+	DepositPercent  float64      `json:"depositPercent" validate:"min=0,max=100"`
+	BalanceDueHours int          `json:"balanceDueHours" validate:"min=0"` // balance is due this many hours before the party
+	Currency        string       `json:"currency" gorm:"default:KGS"`
+	IsActive        bool         `json:"isActive" gorm:"default:true"`
+	
+	Room *PartyRoom `json:"room,omitempty" gorm:"foreignKey:RoomID"`
+}
+
+// PartyRoomReservation occupies a party room for a booking. Active
+// reservations of the same room never overlap.
+type PartyRoomReservation struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	RoomID     uuid.UUID  `json:"roomId" gorm:"not null"`
+	BookingID  uuid.UUID  `json:"bookingId" gorm:"not null"`
+	StartsAt   time.Time  `json:"startsAt"`
+	EndsAt     time.Time  `json:"endsAt"`
+	ReleasedAt *time.Time `json:"releasedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// PartyAddOnLine is an add-on ordered with a party booking
+type PartyAddOnLine struct {
+	Code      string  `json:"code"`
+	Name      string  `json:"name"`
+	Quantity  int     `json:"quantity"`
+	UnitPrice float64 `json:"unitPrice"`
+	Total     float64 `json:"total"`
+}
+
+// PartyDetails describes the package booked by a party booking. Prices are
+// copied from the package at booking time.
+type PartyDetails struct {
+	PackageID       uuid.UUID        `json:"packageId"`
+	PackageName     string           `json:"packageName"`
+	RoomID          uuid.UUID        `json:"roomId"`
+	RoomName        string           `json:"roomName"`
+	StartsAt        time.Time        `json:"startsAt"`
+	EndsAt          time.Time        `json:"endsAt"`
+	PackagePrice    float64          `json:"packagePrice"`
+	IncludedGuests  int              `json:"includedGuests"`
+	ExtraGuests     int              `json:"extraGuests"`
+	ExtraGuestPrice float64          `json:"extraGuestPrice"`
+	AddOns          []PartyAddOnLine `json:"addOns"`
+	CelebrantName   *string          `json:"celebrantName,omitempty"`
+	CelebrantAge    *int             `json:"celebrantAge,omitempty"`
+}
+
+type ScheduledPaymentType string
+
+const (
+	ScheduledPaymentDeposit ScheduledPaymentType = "deposit"
+	ScheduledPaymentBalance ScheduledPaymentType = "balance"
+)
+
+// ScheduledPayment is an installment of a booking's payment schedule
+type ScheduledPayment struct {
+	Type      ScheduledPaymentType `json:"type"`
+	Amount    float64              `json:"amount"`
+	DueAt     time.Time            `json:"dueAt"`
+	Status    PaymentStatus        `json:"status"`
+	PaymentID *uuid.UUID           `json:"paymentId,omitempty"`
+	PaidAt    *time.Time           `json:"paidAt,omitempty"`
+}
+
+// ====================================
+// WAITLIST TYPES
+// ====================================
+
+type WaitlistStatus string
+
+const (
+	WaitlistStatusWaiting   WaitlistStatus = "waiting"
+	WaitlistStatusOffered   WaitlistStatus = "offered"
+	WaitlistStatusClaimed   WaitlistStatus = "claimed"
+	WaitlistStatusExpired   WaitlistStatus = "expired"
+	WaitlistStatusCancelled WaitlistStatus = "cancelled"
+)
+
+// WaitlistEntry is a request for spots in a sold-out time slot.
+// When spots free up the entry is offered to the user for a limited
+// claim window and can then be turned into a booking.
+type WaitlistEntry struct {
+	BaseModel
+	UserID     uuid.UUID      `json:"userId" gorm:"not null"`
+	ParkID     uuid.UUID      `json:"parkId" gorm:"not null"`
+	Status     WaitlistStatus `json:"status" gorm:"default:waiting"`
+	VisitDate  time.Time      `json:"visitDate"`
+	TimeSlot   string         `json:"timeSlot"`
+	GuestCount int            `json:"guestCount" validate:"min=1"`
+	
+	// Booking details used when the offer is claimed
+	Guests      []GuestInfo `json:"guests" gorm:"type:jsonb;serializer:json"`
+	ContactInfo ContactInfo `json:"contactInfo" gorm:"type:jsonb;serializer:json"`
+	
+	// Offer
+	OfferedAt      *time.Time `json:"offeredAt,omitempty"`
+	OfferExpiresAt *time.Time `json:"offerExpiresAt,omitempty"`
+	BookingID      *uuid.UUID `json:"bookingId,omitempty"`
+	
+	// Relationships
+	Park *Park `json:"park,omitempty" gorm:"foreignKey:ParkID"`
+}
+
 // ====================================
 // PAYMENT TYPES
 // ====================================
@@ -549,6 +872,7 @@ const (
 	PaymentMethodCash          PaymentMethod = "cash"
 	PaymentMethodLoyaltyPoints PaymentMethod = "loyalty_points"
 	PaymentMethodWallet        PaymentMethod = "wallet"
+	PaymentMethodBankTransfer  PaymentMethod = "bank_transfer"
 )
 
 type PaymentProvider string
@@ -631,10 +955,10 @@ type Payment struct {
 	OriginalCurrency *string  `json:"originalCurrency,omitempty"`
 	
 	// Provider details
-	Details PaymentDetails `json:"details" gorm:"type:jsonb"`
+	Details PaymentDetails `json:"details" gorm:"type:jsonb;serializer:json"`
 	
 	// Refunds
-	Refunds       []RefundDetails `json:"refunds" gorm:"type:jsonb"`
+	Refunds       []RefundDetails `json:"refunds" gorm:"type:jsonb;serializer:json"`
 	TotalRefunded float64         `json:"totalRefunded" validate:"min=0"`
 	
 	// Timestamps
@@ -652,6 +976,7 @@ type Payment struct {
 	
 	// System fields
 	Metadata JSONB `json:"metadata" gorm:"type:jsonb"`
+	Version  int64 `json:"version" gorm:"default:1"`
 	
 	// Relationships
 	Booking *Booking `json:"booking,omitempty" gorm:"foreignKey:BookingID"`
@@ -0,0 +1,126 @@
+package park
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"skypark/internal/etag"
+	"skypark/internal/models"
+)
+
+// UpdateCapacityRequest описывает изменение вместимости парка
+type UpdateCapacityRequest struct {
+	Total int `json:"total" binding:"required,min=1"`
+}
+
+// OnCapacityChange регистрирует обработчик, вызываемый после изменения
+// вместимости парка администратором
+func (h *ParkHandlers) OnCapacityChange(listener func(parkID uuid.UUID)) {
+	h.capacityListeners = append(h.capacityListeners, listener)
+}
+
+// UpdateCapacity меняет общую вместимость парка (администратор). С заголовком
+// If-Match изменение применяется, только если парк не менялся с этой версии.
+func (h *ParkHandlers) UpdateCapacity(c *gin.Context) {
+	parkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_PARK_ID",
+				"message": "Invalid park ID",
+			},
+		})
+		return
+	}
+	version, ok := etag.ParseIfMatch(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateCapacityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	var park models.Park
+	conflict := false
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND deleted_at IS NULL", parkID).
+			First(&park).Error; err != nil {
+			return err
+		}
+		if version != nil && *version != park.Version {
+			conflict = true
+			return nil
+		}
+
+		park.Capacity.Total = req.Total
+		park.Capacity.Available = req.Total - park.Capacity.Reserved - park.Capacity.Current
+		if park.Capacity.Available < 0 {
+			park.Capacity.Available = 0
+		}
+		park.Capacity.LastUpdated = time.Now()
+
+		raw, err := json.Marshal(park.Capacity)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Park{}).Where("id = ?", park.ID).Update("capacity", string(raw)).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Park{}).Select("version").Where("id = ?", park.ID).Scan(&park.Version).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error": map[string]interface{}{
+					"code":    "PARK_NOT_FOUND",
+					"message": "Park not found",
+				},
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "DATABASE_ERROR",
+				"message": "Failed to update park capacity",
+			},
+		})
+		return
+	}
+	if conflict {
+		etag.RespondConflict(c, park, park.Version)
+		return
+	}
+
+	for _, listener := range h.capacityListeners {
+		listener(park.ID)
+	}
+
+	etag.Set(c, park.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    park,
+		"message": "Park capacity updated successfully",
+	})
+}
@@ -4,13 +4,16 @@ import (
 "net/http"
 
 "github.com/gin-gonic/gin"
+"github.com/google/uuid"
 "gorm.io/gorm"
 
+"skypark/internal/etag"
 "skypark/internal/models"
 )
 
 type ParkHandlers struct {
 db *gorm.DB
+capacityListeners []func(parkID uuid.UUID)
 }
 
 func NewParkHandlers(db *gorm.DB) *ParkHandlers {
@@ -65,6 +68,7 @@ c.JSON(http.StatusInternalServerError, gin.H{
 return
 }
 
+etag.Set(c, park.Version)
 c.JSON(http.StatusOK, gin.H{
 "success": true,
 "data":    park,
@@ -91,16 +95,6 @@ c.JSON(http.StatusNotImplemented, gin.H{
 })
 }
 
-func (h *ParkHandlers) UpdatePark(c *gin.Context) {
-c.JSON(http.StatusNotImplemented, gin.H{
-"success": false,
-"error": map[string]interface{}{
-"code":    "NOT_IMPLEMENTED",
-"message": "Update park not implemented yet",
-},
-})
-}
-
 func (h *ParkHandlers) DeletePark(c *gin.Context) {
 c.JSON(http.StatusNotImplemented, gin.H{
 "success": false,
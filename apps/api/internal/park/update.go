@@ -0,0 +1,277 @@
+package park
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"skypark/internal/etag"
+	"skypark/internal/models"
+)
+
+// UpdateParkRequest описывает изменение парка. Не переданные поля остаются
+// прежними; вместимость меняется отдельно через UpdateCapacity.
+type UpdateParkRequest struct {
+	Name             *string                 `json:"name,omitempty"`
+	Description      *string                 `json:"description,omitempty"`
+	ShortDescription *string                 `json:"shortDescription,omitempty"`
+	Status           *models.ParkStatus      `json:"status,omitempty"`
+	Address          *models.Address         `json:"address,omitempty"`
+	PhoneNumber      *string                 `json:"phoneNumber,omitempty"`
+	Email            *string                 `json:"email,omitempty"`
+	Website          *string                 `json:"website,omitempty"`
+	OperatingHours   []models.OperatingHours `json:"operatingHours,omitempty"`
+	Amenities        []models.Amenity        `json:"amenities,omitempty"`
+	MainImage        *string                 `json:"mainImage,omitempty"`
+	Images           []string                `json:"images,omitempty"`
+	BasePrice        *float64                `json:"basePrice,omitempty" binding:"omitempty,min=0"`
+	ChildPrice       *float64                `json:"childPrice,omitempty" binding:"omitempty,min=0"`
+	AdultPrice       *float64                `json:"adultPrice,omitempty" binding:"omitempty,min=0"`
+	SeniorPrice      *float64                `json:"seniorPrice,omitempty" binding:"omitempty,min=0"`
+	GroupDiscount    *float64                `json:"groupDiscount,omitempty" binding:"omitempty,min=0,max=100"`
+	Settings         *models.ParkSettings    `json:"settings,omitempty"`
+}
+
+// parkStatuses — допустимые статусы парка
+var parkStatuses = map[models.ParkStatus]bool{
+	models.ParkStatusActive:      true,
+	models.ParkStatusInactive:    true,
+	models.ParkStatusMaintenance: true,
+	models.ParkStatusClosed:      true,
+}
+
+// validateSettings проверяет правила бронирования перед сохранением: теги
+// validate на моделях нигде не проверяются, а проценты и сроки из настроек
+// напрямую попадают в цены и возвраты
+func validateSettings(settings *models.ParkSettings) error {
+	for i, modifier := range settings.PriceModifiers {
+		if modifier.Percent < -100 || modifier.Percent > 200 {
+			return fmt.Errorf("priceModifiers[%d].percent must be between -100 and 200", i)
+		}
+		if !validClock(modifier.StartTime) || !validClock(modifier.EndTime) {
+			return fmt.Errorf("priceModifiers[%d] must have startTime and endTime in HH:MM format", i)
+		}
+	}
+	if settings.CancellationPolicy != nil {
+		for i, rule := range settings.CancellationPolicy.Rules {
+			if rule.HoursBefore < 0 {
+				return fmt.Errorf("cancellationPolicy.rules[%d].hoursBefore must not be negative", i)
+			}
+			if rule.RefundPercent < 0 || rule.RefundPercent > 100 {
+				return fmt.Errorf("cancellationPolicy.rules[%d].refundPercent must be between 0 and 100", i)
+			}
+		}
+	}
+	if group := settings.Group; group != nil {
+		if group.MinGroupSize < 0 || group.ChildrenPerFreeChaperone < 0 || group.InvoiceDueDays < 0 {
+			return errors.New("group settings must not be negative")
+		}
+	}
+	return nil
+}
+
+// validClock сообщает, что value — время вида HH:MM от 00:00 до 24:00
+func validClock(value string) bool {
+	var hours, minutes int
+	if _, err := fmt.Sscanf(value, "%d:%d", &hours, &minutes); err != nil {
+		return false
+	}
+	return hours >= 0 && minutes >= 0 && minutes <= 59 && (hours < 24 || (hours == 24 && minutes == 0))
+}
+
+// apply переносит переданные поля в park и возвращает изменённые поля
+func (req *UpdateParkRequest) apply(park *models.Park) []string {
+	var fields []string
+	if req.Name != nil {
+		park.Name = strings.TrimSpace(*req.Name)
+		fields = append(fields, "Name")
+	}
+	if req.Description != nil {
+		park.Description = *req.Description
+		fields = append(fields, "Description")
+	}
+	if req.ShortDescription != nil {
+		park.ShortDescription = req.ShortDescription
+		fields = append(fields, "ShortDescription")
+	}
+	if req.Status != nil {
+		park.Status = *req.Status
+		fields = append(fields, "Status")
+	}
+	if req.Address != nil {
+		park.Address = *req.Address
+		fields = append(fields, "Address")
+	}
+	if req.PhoneNumber != nil {
+		park.PhoneNumber = req.PhoneNumber
+		fields = append(fields, "PhoneNumber")
+	}
+	if req.Email != nil {
+		park.Email = req.Email
+		fields = append(fields, "Email")
+	}
+	if req.Website != nil {
+		park.Website = req.Website
+		fields = append(fields, "Website")
+	}
+	if req.OperatingHours != nil {
+		park.OperatingHours = req.OperatingHours
+		fields = append(fields, "OperatingHours")
+	}
+	if req.Amenities != nil {
+		park.Amenities = req.Amenities
+		fields = append(fields, "Amenities")
+	}
+	if req.MainImage != nil {
+		park.MainImage = req.MainImage
+		fields = append(fields, "MainImage")
+	}
+	if req.Images != nil {
+		park.Images = req.Images
+		fields = append(fields, "Images")
+	}
+	if req.BasePrice != nil {
+		park.BasePrice = *req.BasePrice
+		fields = append(fields, "BasePrice")
+	}
+	if req.ChildPrice != nil {
+		park.ChildPrice = *req.ChildPrice
+		fields = append(fields, "ChildPrice")
+	}
+	if req.AdultPrice != nil {
+		park.AdultPrice = *req.AdultPrice
+		fields = append(fields, "AdultPrice")
+	}
+	if req.SeniorPrice != nil {
+		park.SeniorPrice = *req.SeniorPrice
+		fields = append(fields, "SeniorPrice")
+	}
+	if req.GroupDiscount != nil {
+		park.GroupDiscount = *req.GroupDiscount
+		fields = append(fields, "GroupDiscount")
+	}
+	if req.Settings != nil {
+		park.Settings = *req.Settings
+		fields = append(fields, "Settings")
+	}
+	return fields
+}
+
+// UpdatePark изменяет описание, цены и правила парка (администратор). С
+// заголовком If-Match изменение применяется, только если парк не менялся с
+// этой версии; иначе возвращается 409 с текущим состоянием.
+func (h *ParkHandlers) UpdatePark(c *gin.Context) {
+	parkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_PARK_ID",
+				"message": "Invalid park ID",
+			},
+		})
+		return
+	}
+	version, ok := etag.ParseIfMatch(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateParkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Invalid request format",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+	if (req.Name != nil && strings.TrimSpace(*req.Name) == "") || (req.Status != nil && !parkStatuses[*req.Status]) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "INVALID_REQUEST",
+				"message": "Park name must not be empty and status must be active, inactive, maintenance or closed",
+			},
+		})
+		return
+	}
+
+	if req.Settings != nil {
+		if err := validateSettings(req.Settings); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": map[string]interface{}{
+					"code":    "INVALID_SETTINGS",
+					"message": "Invalid park settings",
+					"details": err.Error(),
+				},
+			})
+			return
+		}
+	}
+
+	var park models.Park
+	conflict := false
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND deleted_at IS NULL", parkID).
+			First(&park).Error; err != nil {
+			return err
+		}
+		if version != nil && *version != park.Version {
+			conflict = true
+			return nil
+		}
+
+		fields := req.apply(&park)
+		if len(fields) == 0 {
+			return nil
+		}
+		if err := tx.Model(&park).Select(fields).Updates(&park).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Park{}).Select("version").Where("id = ?", park.ID).Scan(&park.Version).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error": map[string]interface{}{
+					"code":    "PARK_NOT_FOUND",
+					"message": "Park not found",
+				},
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": map[string]interface{}{
+				"code":    "DATABASE_ERROR",
+				"message": "Failed to update park",
+			},
+		})
+		return
+	}
+	if conflict {
+		etag.RespondConflict(c, park, park.Version)
+		return
+	}
+
+	etag.Set(c, park.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    park,
+		"message": "Park updated successfully",
+	})
+}
@@ -0,0 +1,379 @@
+// Package ticketsign подписывает содержимое QR-кодов билетов ключами Ed25519,
+// чтобы турникеты и сканеры могли проверять билеты без связи с сервером.
+//
+// Токен версии 1 имеет вид
+//
+//	SP1.<payload>.<signature>
+//
+// где обе части закодированы base64url без выравнивания. Подпись Ed25519
+// ставится над строкой "SP1.<payload>". Payload — двоичная запись
+// (целые числа big-endian):
+//
+//	1 байт     длина идентификатора ключа N
+//	N байт     идентификатор ключа (ASCII)
+//	16 байт    ID билета
+//	16 байт    ID парка
+//	8 байт     начало действия, секунды Unix
+//	8 байт     окончание действия, секунды Unix
+//	8 байт     случайный nonce
+//
+// Теми же ключами подписываются манифесты для сканеров (SignPayload): подпись
+// ставится над строкой "SPM1.<payload>", где payload — base64url от данных.
+// Другой префикс не позволяет выдать манифест за билет и наоборот.
+//
+// Открытые ключи публикуются по идентификаторам, поэтому ключи можно менять:
+// новые билеты подписываются активным ключом, а билеты, выпущенные прежними
+// ключами, проверяются, пока эти ключи остаются в наборе.
+package ticketsign
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TokenPrefix — префикс токенов текущей версии формата
+const TokenPrefix = "SP1."
+
+// PayloadPrefix — префикс подписываемых данных, не являющихся билетом
+const PayloadPrefix = "SPM1."
+
+// Algorithm — алгоритм подписи токенов
+const Algorithm = "Ed25519"
+
+// maxKeyIDLength ограничивает идентификатор ключа, чтобы QR-код оставался компактным
+const maxKeyIDLength = 32
+
+const (
+	nonceSize  = 8
+	claimsSize = 16 + 16 + 8 + 8 + nonceSize
+)
+
+var (
+	ErrMalformedToken   = errors.New("ticket token is malformed")
+	ErrUnknownKey       = errors.New("ticket token is signed with an unknown key")
+	ErrInvalidSignature = errors.New("ticket token signature is invalid")
+	ErrNoSigningKey     = errors.New("no active ticket signing key")
+	ErrInvalidKeyID     = errors.New("ticket signing key id must be 1-32 printable ASCII characters without ':', ',' or '.'")
+)
+
+var encoding = base64.RawURLEncoding
+
+// Claims — данные, которые содержит подписанный токен
+type Claims struct {
+	KeyID     string
+	TicketID  uuid.UUID
+	ParkID    uuid.UUID
+	ValidFrom time.Time
+	ValidTo   time.Time
+	Nonce     [nonceSize]byte
+}
+
+// Keyring хранит ключи подписи билетов. Активный ключ подписывает новые
+// билеты, остальные только проверяют ранее выпущенные.
+type Keyring struct {
+	activeID string
+	private  map[string]ed25519.PrivateKey
+	public   map[string]ed25519.PublicKey
+}
+
+// NewKeyring создаёт набор ключей. activeID должен быть среди закрытых
+// ключей; retired — открытые ключи, закрытая часть которых уже выведена из
+// оборота, но выпущенные ими билеты ещё действуют.
+func NewKeyring(activeID string, private map[string]ed25519.PrivateKey, retired map[string]ed25519.PublicKey) (*Keyring, error) {
+	if _, ok := private[activeID]; !ok {
+		return nil, ErrNoSigningKey
+	}
+
+	k := &Keyring{
+		activeID: activeID,
+		private:  make(map[string]ed25519.PrivateKey, len(private)),
+		public:   make(map[string]ed25519.PublicKey, len(private)+len(retired)),
+	}
+	for id, key := range retired {
+		if err := validateKeyID(id); err != nil {
+			return nil, err
+		}
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("ticket verification key %q: invalid length", id)
+		}
+		k.public[id] = key
+	}
+	for id, key := range private {
+		if err := validateKeyID(id); err != nil {
+			return nil, err
+		}
+		if len(key) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("ticket signing key %q: invalid length", id)
+		}
+		k.private[id] = key
+		k.public[id] = key.Public().(ed25519.PublicKey)
+	}
+	return k, nil
+}
+
+// GenerateKeyring создаёт набор из одного случайного ключа. Подходит только
+// для разработки: после перезапуска выпущенные билеты перестают проверяться.
+func GenerateKeyring(keyID string) (*Keyring, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ticket signing key: %w", err)
+	}
+	return NewKeyring(keyID, map[string]ed25519.PrivateKey{keyID: key}, nil)
+}
+
+// ParseKeyring собирает набор ключей из настроек. signing и verify — списки
+// "id:ключ" через запятую, ключи в base64: в signing 32-байтовый seed или
+// 64-байтовый закрытый ключ, в verify 32-байтовый открытый ключ. Если
+// activeID пуст, активным становится последний ключ из signing.
+func ParseKeyring(signing, activeID, verify string) (*Keyring, error) {
+	private := map[string]ed25519.PrivateKey{}
+	lastID := ""
+	err := parseKeyList(signing, func(id string, raw []byte) error {
+		switch len(raw) {
+		case ed25519.SeedSize:
+			private[id] = ed25519.NewKeyFromSeed(raw)
+		case ed25519.PrivateKeySize:
+			private[id] = ed25519.PrivateKey(raw)
+		default:
+			return fmt.Errorf("ticket signing key %q: expected a %d-byte seed or %d-byte private key", id, ed25519.SeedSize, ed25519.PrivateKeySize)
+		}
+		lastID = id
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	retired := map[string]ed25519.PublicKey{}
+	err = parseKeyList(verify, func(id string, raw []byte) error {
+		if len(raw) != ed25519.PublicKeySize {
+			return fmt.Errorf("ticket verification key %q: expected a %d-byte public key", id, ed25519.PublicKeySize)
+		}
+		retired[id] = ed25519.PublicKey(raw)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if activeID == "" {
+		activeID = lastID
+	}
+	return NewKeyring(activeID, private, retired)
+}
+
+func parseKeyList(list string, add func(id string, raw []byte) error) error {
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, value, ok := strings.Cut(entry, ":")
+		if !ok {
+			return fmt.Errorf("ticket key entry %q: expected id:base64-key", entry)
+		}
+		if err := validateKeyID(id); err != nil {
+			return err
+		}
+		raw, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return fmt.Errorf("ticket key %q: %w", id, err)
+		}
+		if err := add(id, raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateKeyID(id string) error {
+	if id == "" || len(id) > maxKeyIDLength {
+		return ErrInvalidKeyID
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' || id[i] == ':' || id[i] == ',' || id[i] == '.' {
+			return ErrInvalidKeyID
+		}
+	}
+	return nil
+}
+
+// ActiveKeyID возвращает идентификатор ключа, которым подписываются новые билеты
+func (k *Keyring) ActiveKeyID() string {
+	return k.activeID
+}
+
+// Sign подписывает данные билета активным ключом. KeyID и Nonce
+// заполняются автоматически.
+func (k *Keyring) Sign(claims Claims) (string, error) {
+	key, ok := k.private[k.activeID]
+	if !ok {
+		return "", ErrNoSigningKey
+	}
+	claims.KeyID = k.activeID
+	if _, err := rand.Read(claims.Nonce[:]); err != nil {
+		return "", fmt.Errorf("failed to generate ticket nonce: %w", err)
+	}
+
+	signed := TokenPrefix + encoding.EncodeToString(encodeClaims(claims))
+	signature := ed25519.Sign(key, []byte(signed))
+	return signed + "." + encoding.EncodeToString(signature), nil
+}
+
+// Verify проверяет подпись токена и возвращает его данные. Срок действия
+// не проверяется: решение о проходе принимает вызывающий код.
+func (k *Keyring) Verify(token string) (*Claims, error) {
+	if !strings.HasPrefix(token, TokenPrefix) {
+		return nil, ErrMalformedToken
+	}
+	dot := strings.LastIndexByte(token, '.')
+	if dot < len(TokenPrefix) {
+		return nil, ErrMalformedToken
+	}
+
+	payload, err := encoding.DecodeString(token[len(TokenPrefix):dot])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	signature, err := encoding.DecodeString(token[dot+1:])
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return nil, ErrMalformedToken
+	}
+	claims, err := decodeClaims(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := k.public[claims.KeyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if !ed25519.Verify(key, []byte(token[:dot]), signature) {
+		return nil, ErrInvalidSignature
+	}
+	return claims, nil
+}
+
+// SignedPayload — данные, подписанные SignPayload
+type SignedPayload struct {
+	Payload   string `json:"payload"` // base64url от данных
+	KeyID     string `json:"keyId"`
+	Signature string `json:"signature"` // base64url подписи "SPM1.<payload>"
+}
+
+// SignPayload подписывает произвольные данные, например манифест сканера,
+// активным ключом
+func (k *Keyring) SignPayload(data []byte) (*SignedPayload, error) {
+	key, ok := k.private[k.activeID]
+	if !ok {
+		return nil, ErrNoSigningKey
+	}
+	payload := encoding.EncodeToString(data)
+	signature := ed25519.Sign(key, []byte(PayloadPrefix+payload))
+	return &SignedPayload{
+		Payload:   payload,
+		KeyID:     k.activeID,
+		Signature: encoding.EncodeToString(signature),
+	}, nil
+}
+
+// VerifyPayload проверяет подпись данных и возвращает их
+func (k *Keyring) VerifyPayload(signed SignedPayload) ([]byte, error) {
+	key, ok := k.public[signed.KeyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	signature, err := encoding.DecodeString(signed.Signature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return nil, ErrMalformedToken
+	}
+	if !ed25519.Verify(key, []byte(PayloadPrefix+signed.Payload), signature) {
+		return nil, ErrInvalidSignature
+	}
+	data, err := encoding.DecodeString(signed.Payload)
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	return data, nil
+}
+
+// PublicKey — открытый ключ в формате JWK (RFC 8037)
+type PublicKey struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	KeyID     string `json:"kid"`
+	X         string `json:"x"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Active    bool   `json:"active"`
+}
+
+// PublicKeys возвращает открытые ключи для проверки билетов, активный первым
+func (k *Keyring) PublicKeys() []PublicKey {
+	ids := make([]string, 0, len(k.public))
+	for id := range k.public {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if (ids[i] == k.activeID) != (ids[j] == k.activeID) {
+			return ids[i] == k.activeID
+		}
+		return ids[i] < ids[j]
+	})
+
+	keys := make([]PublicKey, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, PublicKey{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			KeyID:     id,
+			X:         encoding.EncodeToString(k.public[id]),
+			Use:       "sig",
+			Algorithm: "EdDSA",
+			Active:    id == k.activeID,
+		})
+	}
+	return keys
+}
+
+func encodeClaims(claims Claims) []byte {
+	var buf bytes.Buffer
+	buf.Grow(1 + len(claims.KeyID) + claimsSize)
+	buf.WriteByte(byte(len(claims.KeyID)))
+	buf.WriteString(claims.KeyID)
+	buf.Write(claims.TicketID[:])
+	buf.Write(claims.ParkID[:])
+	binary.Write(&buf, binary.BigEndian, claims.ValidFrom.Unix())
+	binary.Write(&buf, binary.BigEndian, claims.ValidTo.Unix())
+	buf.Write(claims.Nonce[:])
+	return buf.Bytes()
+}
+
+func decodeClaims(payload []byte) (*Claims, error) {
+	if len(payload) < 1 {
+		return nil, ErrMalformedToken
+	}
+	idLength := int(payload[0])
+	if idLength == 0 || idLength > maxKeyIDLength || len(payload) != 1+idLength+claimsSize {
+		return nil, ErrMalformedToken
+	}
+
+	claims := &Claims{KeyID: string(payload[1 : 1+idLength])}
+	rest := payload[1+idLength:]
+	copy(claims.TicketID[:], rest[0:16])
+	copy(claims.ParkID[:], rest[16:32])
+	claims.ValidFrom = time.Unix(int64(binary.BigEndian.Uint64(rest[32:40])), 0)
+	claims.ValidTo = time.Unix(int64(binary.BigEndian.Uint64(rest[40:48])), 0)
+	copy(claims.Nonce[:], rest[48:56])
+	return claims, nil
+}
@@ -0,0 +1,357 @@
+package ticketsign
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// testKey возвращает детерминированный ключ, чтобы тесты не зависели от случайности
+func testKey(seed byte) ed25519.PrivateKey {
+	raw := make([]byte, ed25519.SeedSize)
+	for i := range raw {
+		raw[i] = seed
+	}
+	return ed25519.NewKeyFromSeed(raw)
+}
+
+func testClaims() Claims {
+	validFrom := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	return Claims{
+		TicketID:  uuid.MustParse("6f1c2a9e-3b7d-4c1a-9e2f-0a1b2c3d4e5f"),
+		ParkID:    uuid.MustParse("0b8e7d6c-5a4f-4e3d-8c2b-1a0f9e8d7c6b"),
+		ValidFrom: validFrom,
+		ValidTo:   validFrom.Add(3 * time.Hour),
+	}
+}
+
+func mustKeyring(t *testing.T, activeID string, private map[string]ed25519.PrivateKey, retired map[string]ed25519.PublicKey) *Keyring {
+	t.Helper()
+	k, err := NewKeyring(activeID, private, retired)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	return k
+}
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	oldKey, newKey := testKey(1), testKey(2)
+	signedByOld := mustKeyring(t, "k1", map[string]ed25519.PrivateKey{"k1": oldKey}, nil)
+
+	tests := []struct {
+		name   string
+		signer *Keyring
+		// verifier проверяет токены signer
+		verifier *Keyring
+	}{
+		{
+			name:     "same keyring",
+			signer:   signedByOld,
+			verifier: signedByOld,
+		},
+		{
+			name:     "key still held after rotation",
+			signer:   signedByOld,
+			verifier: mustKeyring(t, "k2", map[string]ed25519.PrivateKey{"k1": oldKey, "k2": newKey}, nil),
+		},
+		{
+			name:   "retired key kept as public only",
+			signer: signedByOld,
+			verifier: mustKeyring(t, "k2", map[string]ed25519.PrivateKey{"k2": newKey},
+				map[string]ed25519.PublicKey{"k1": oldKey.Public().(ed25519.PublicKey)}),
+		},
+		{
+			name:     "longest key id",
+			signer:   mustKeyring(t, strings.Repeat("k", maxKeyIDLength), map[string]ed25519.PrivateKey{strings.Repeat("k", maxKeyIDLength): newKey}, nil),
+			verifier: mustKeyring(t, strings.Repeat("k", maxKeyIDLength), map[string]ed25519.PrivateKey{strings.Repeat("k", maxKeyIDLength): newKey}, nil),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := testClaims()
+			token, err := tt.signer.Sign(want)
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+			if !strings.HasPrefix(token, TokenPrefix) {
+				t.Fatalf("Sign() = %q, want prefix %q", token, TokenPrefix)
+			}
+
+			got, err := tt.verifier.Verify(token)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if got.KeyID != tt.signer.ActiveKeyID() {
+				t.Errorf("KeyID = %q, want %q", got.KeyID, tt.signer.ActiveKeyID())
+			}
+			if got.TicketID != want.TicketID || got.ParkID != want.ParkID {
+				t.Errorf("IDs = %s, %s, want %s, %s", got.TicketID, got.ParkID, want.TicketID, want.ParkID)
+			}
+			if !got.ValidFrom.Equal(want.ValidFrom) || !got.ValidTo.Equal(want.ValidTo) {
+				t.Errorf("validity = %s..%s, want %s..%s", got.ValidFrom, got.ValidTo, want.ValidFrom, want.ValidTo)
+			}
+		})
+	}
+}
+
+func TestSignUsesFreshNonce(t *testing.T) {
+	k := mustKeyring(t, "k1", map[string]ed25519.PrivateKey{"k1": testKey(1)}, nil)
+	first, err := k.Sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := k.Sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Error("two tokens for the same ticket are identical")
+	}
+}
+
+func TestVerifyRejectsTamperedTokens(t *testing.T) {
+	k := mustKeyring(t, "k1", map[string]ed25519.PrivateKey{"k1": testKey(1)}, nil)
+	token, err := k.Sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dot := strings.LastIndexByte(token, '.')
+	payload, signature := token[len(TokenPrefix):dot], token[dot+1:]
+
+	other, err := k.Sign(Claims{TicketID: uuid.New(), ParkID: uuid.New()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSignature := other[strings.LastIndexByte(other, '.')+1:]
+
+	// Другой ID билета с сохранением длины и ключа
+	raw, _ := encoding.DecodeString(payload)
+	raw[1+len("k1")] ^= 0xff
+	changedTicket := encoding.EncodeToString(raw)
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"changed ticket id", TokenPrefix + changedTicket + "." + signature, ErrInvalidSignature},
+		{"signature of another token", TokenPrefix + payload + "." + otherSignature, ErrInvalidSignature},
+		{"flipped signature byte", TokenPrefix + payload + "." + flipFirstByte(signature), ErrInvalidSignature},
+		{"wrong prefix", "SP2." + payload + "." + signature, ErrMalformedToken},
+		{"no signature", TokenPrefix + payload, ErrMalformedToken},
+		{"empty", "", ErrMalformedToken},
+		{"payload not base64", TokenPrefix + "!!!" + "." + signature, ErrMalformedToken},
+		{"signature not base64", TokenPrefix + payload + ".!!!", ErrMalformedToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := k.Verify(tt.token); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsUnknownKeys(t *testing.T) {
+	verifier := mustKeyring(t, "k1", map[string]ed25519.PrivateKey{"k1": testKey(1)}, nil)
+
+	tests := []struct {
+		name   string
+		signer *Keyring
+		want   error
+	}{
+		{
+			name:   "key id not in keyring",
+			signer: mustKeyring(t, "k9", map[string]ed25519.PrivateKey{"k9": testKey(9)}, nil),
+			want:   ErrUnknownKey,
+		},
+		{
+			// Чужой ключ под известным идентификатором
+			name:   "same key id, different key",
+			signer: mustKeyring(t, "k1", map[string]ed25519.PrivateKey{"k1": testKey(9)}, nil),
+			want:   ErrInvalidSignature,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.signer.Sign(testClaims())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := verifier.Verify(token); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsWrongLengths(t *testing.T) {
+	key := testKey(1)
+	k := mustKeyring(t, "k1", map[string]ed25519.PrivateKey{"k1": key}, nil)
+	claims := encodeClaims(Claims{KeyID: "k1", TicketID: uuid.New(), ParkID: uuid.New()})
+
+	// signed подписывает произвольный payload, как это сделал бы злоумышленник с ключом
+	signed := func(payload []byte) string {
+		unsigned := TokenPrefix + encoding.EncodeToString(payload)
+		return unsigned + "." + encoding.EncodeToString(ed25519.Sign(key, []byte(unsigned)))
+	}
+	valid := signed(claims)
+	unsigned := valid[:strings.LastIndexByte(valid, '.')]
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"payload one byte short", signed(claims[:len(claims)-1])},
+		{"payload one byte long", signed(append(append([]byte{}, claims...), 0))},
+		{"key id length over the limit", signed(append([]byte{40}, claims[1:]...))},
+		{"empty key id", signed(append([]byte{0}, claims[1+len("k1"):]...))},
+		{"empty payload", signed(nil)},
+		{"short signature", unsigned + "." + encoding.EncodeToString(make([]byte, ed25519.SignatureSize-1))},
+		{"long signature", unsigned + "." + encoding.EncodeToString(make([]byte, ed25519.SignatureSize+1))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := k.Verify(tt.token); !errors.Is(err, ErrMalformedToken) {
+				t.Errorf("Verify() error = %v, want ErrMalformedToken", err)
+			}
+		})
+	}
+	if _, err := k.Verify(valid); err != nil {
+		t.Errorf("Verify() of the well-formed token error = %v", err)
+	}
+}
+
+func TestSignPayload(t *testing.T) {
+	k := mustKeyring(t, "k1", map[string]ed25519.PrivateKey{"k1": testKey(1)}, nil)
+	data := []byte(`{"tickets":[]}`)
+
+	signed, err := k.SignPayload(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := k.VerifyPayload(*signed)
+	if err != nil || string(got) != string(data) {
+		t.Fatalf("VerifyPayload() = %q, %v, want %q", got, err, data)
+	}
+
+	tampered := *signed
+	tampered.Payload = encoding.EncodeToString([]byte(`{"tickets":[1]}`))
+	if _, err := k.VerifyPayload(tampered); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifyPayload() of changed data error = %v, want ErrInvalidSignature", err)
+	}
+
+	unknown := *signed
+	unknown.KeyID = "k9"
+	if _, err := k.VerifyPayload(unknown); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("VerifyPayload() with unknown key error = %v, want ErrUnknownKey", err)
+	}
+
+	// Подпись билета не должна подходить как подпись данных и наоборот
+	token, err := k.Sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dot := strings.LastIndexByte(token, '.')
+	asPayload := SignedPayload{Payload: token[len(TokenPrefix):dot], KeyID: "k1", Signature: token[dot+1:]}
+	if _, err := k.VerifyPayload(asPayload); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifyPayload() of a ticket token error = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestNewKeyringRejectsInvalidKeys(t *testing.T) {
+	key := testKey(1)
+	tests := []struct {
+		name     string
+		activeID string
+		private  map[string]ed25519.PrivateKey
+		retired  map[string]ed25519.PublicKey
+		want     error
+	}{
+		{"active key missing", "k2", map[string]ed25519.PrivateKey{"k1": key}, nil, ErrNoSigningKey},
+		{"key id with dot", "k.1", map[string]ed25519.PrivateKey{"k.1": key}, nil, ErrInvalidKeyID},
+		{"key id with colon", "k:1", map[string]ed25519.PrivateKey{"k:1": key}, nil, ErrInvalidKeyID},
+		{"key id with space", "k 1", map[string]ed25519.PrivateKey{"k 1": key}, nil, ErrInvalidKeyID},
+		{"key id too long", strings.Repeat("k", maxKeyIDLength+1), map[string]ed25519.PrivateKey{strings.Repeat("k", maxKeyIDLength+1): key}, nil, nil},
+		{"short private key", "k1", map[string]ed25519.PrivateKey{"k1": key[:ed25519.PrivateKeySize-1]}, nil, nil},
+		{"short public key", "k1", map[string]ed25519.PrivateKey{"k1": key}, map[string]ed25519.PublicKey{"k0": make([]byte, ed25519.PublicKeySize-1)}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyring(tt.activeID, tt.private, tt.retired)
+			if err == nil {
+				t.Fatal("NewKeyring() succeeded, want an error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("NewKeyring() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseKeyring(t *testing.T) {
+	seed := func(b byte) string {
+		return base64.StdEncoding.EncodeToString(testKey(b).Seed())
+	}
+	public := base64.StdEncoding.EncodeToString(testKey(3).Public().(ed25519.PublicKey))
+
+	tests := []struct {
+		name       string
+		signing    string
+		activeID   string
+		verify     string
+		wantActive string
+		wantKeys   int
+		wantErr    bool
+	}{
+		{name: "last signing key is active", signing: "k1:" + seed(1) + ", k2:" + seed(2), wantActive: "k2", wantKeys: 2},
+		{name: "explicit active key", signing: "k1:" + seed(1) + ",k2:" + seed(2), activeID: "k1", wantActive: "k1", wantKeys: 2},
+		{name: "full private key", signing: "k1:" + base64.StdEncoding.EncodeToString(testKey(1)), wantActive: "k1", wantKeys: 1},
+		{name: "verify-only key", signing: "k1:" + seed(1), verify: "k0:" + public, wantActive: "k1", wantKeys: 2},
+		{name: "no signing keys", wantErr: true},
+		{name: "missing id", signing: seed(1), wantErr: true},
+		{name: "not base64", signing: "k1:not-base64!", wantErr: true},
+		{name: "seed one byte short", signing: "k1:" + base64.StdEncoding.EncodeToString(make([]byte, ed25519.SeedSize-1)), wantErr: true},
+		{name: "48-byte signing key", signing: "k1:" + base64.StdEncoding.EncodeToString(make([]byte, 48)), wantErr: true},
+		{name: "verify key one byte long", signing: "k1:" + seed(1), verify: "k0:" + base64.StdEncoding.EncodeToString(make([]byte, ed25519.PublicKeySize+1)), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := ParseKeyring(tt.signing, tt.activeID, tt.verify)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ParseKeyring() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseKeyring() error = %v", err)
+			}
+			if k.ActiveKeyID() != tt.wantActive {
+				t.Errorf("ActiveKeyID() = %q, want %q", k.ActiveKeyID(), tt.wantActive)
+			}
+			keys := k.PublicKeys()
+			if len(keys) != tt.wantKeys {
+				t.Fatalf("PublicKeys() returned %d keys, want %d", len(keys), tt.wantKeys)
+			}
+			if !keys[0].Active || keys[0].KeyID != tt.wantActive {
+				t.Errorf("PublicKeys()[0] = %+v, want the active key first", keys[0])
+			}
+		})
+	}
+}
+
+// flipFirstByte портит первый байт значения в base64url
+func flipFirstByte(value string) string {
+	raw, err := encoding.DecodeString(value)
+	if err != nil || len(raw) == 0 {
+		return value
+	}
+	raw[0] ^= 0xff
+	return encoding.EncodeToString(raw)
+}
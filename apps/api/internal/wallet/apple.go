@@ -0,0 +1,269 @@
+package wallet
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"go.mozilla.org/pkcs7"
+)
+
+// DefaultAPNsURL — боевой адрес APNs; пассы Wallet используют только его
+const DefaultAPNsURL = "https://api.push.apple.com"
+
+// PKPassContentType — MIME-тип файла .pkpass
+const PKPassContentType = "application/vnd.apple.pkpass"
+
+// AppleConfig — настройки выпуска пассов Apple Wallet
+type AppleConfig struct {
+	PassTypeID       string // pass.kg.skypark.ticket
+	TeamID           string
+	OrganizationName string
+	// WebServiceURL — адрес веб-сервиса пассов; пустой, если обновления не нужны
+	WebServiceURL string
+	// Certificate и PrivateKey — сертификат Pass Type ID и его ключ в PEM
+	Certificate []byte
+	PrivateKey  []byte
+	// WWDRCertificate — промежуточный сертификат Apple WWDR в PEM
+	WWDRCertificate []byte
+	// APNsURL заменяет DefaultAPNsURL, например локальной заглушкой
+	APNsURL string
+}
+
+// AppleSigner подписывает пассы Apple Wallet и отправляет push об их изменении
+type AppleSigner struct {
+	config AppleConfig
+	cert   *x509.Certificate
+	key    crypto.Signer
+	wwdr   *x509.Certificate
+	client *http.Client
+}
+
+// NewAppleSigner проверяет сертификаты и создаёт подписывающего
+func NewAppleSigner(config AppleConfig) (*AppleSigner, error) {
+	if config.PassTypeID == "" || config.TeamID == "" {
+		return nil, fmt.Errorf("%w: pass type id and team id are required", ErrInvalidConfig)
+	}
+	cert, err := parseCertificate(config.Certificate)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parsePrivateKey(config.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	wwdr, err := parseCertificate(config.WWDRCertificate)
+	if err != nil {
+		return nil, err
+	}
+	if err := cert.CheckSignatureFrom(wwdr); err != nil {
+		return nil, fmt.Errorf("%w: pass certificate is not issued by the WWDR certificate: %v", ErrInvalidCertificate, err)
+	}
+	if config.APNsURL == "" {
+		config.APNsURL = DefaultAPNsURL
+	}
+	if config.OrganizationName == "" {
+		config.OrganizationName = "Sky Park"
+	}
+
+	// APNs принимает только HTTP/2 с клиентским сертификатом пасса
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			Certificates: []tls.Certificate{{
+				Certificate: [][]byte{cert.Raw},
+				PrivateKey:  key,
+				Leaf:        cert,
+			}},
+		},
+		ForceAttemptHTTP2: true,
+	}
+	return &AppleSigner{
+		config: config,
+		cert:   cert,
+		key:    key,
+		wwdr:   wwdr,
+		client: &http.Client{Transport: transport, Timeout: requestTimeout},
+	}, nil
+}
+
+// PassTypeID возвращает идентификатор типа пассов
+func (a *AppleSigner) PassTypeID() string {
+	return a.config.PassTypeID
+}
+
+// ApplePass — содержимое pass.json для пасса-билета (eventTicket)
+type ApplePass struct {
+	FormatVersion       int             `json:"formatVersion"`
+	PassTypeIdentifier  string          `json:"passTypeIdentifier"`
+	TeamIdentifier      string          `json:"teamIdentifier"`
+	OrganizationName    string          `json:"organizationName"`
+	SerialNumber        string          `json:"serialNumber"`
+	Description         string          `json:"description"`
+	LogoText            string          `json:"logoText,omitempty"`
+	ForegroundColor     string          `json:"foregroundColor,omitempty"`
+	BackgroundColor     string          `json:"backgroundColor,omitempty"`
+	LabelColor          string          `json:"labelColor,omitempty"`
+	RelevantDate        string          `json:"relevantDate,omitempty"`
+	ExpirationDate      string          `json:"expirationDate,omitempty"`
+	Voided              bool            `json:"voided,omitempty"`
+	WebServiceURL       string          `json:"webServiceURL,omitempty"`
+	AuthenticationToken string          `json:"authenticationToken,omitempty"`
+	Barcodes            []AppleBarcode  `json:"barcodes,omitempty"`
+	Locations           []AppleLocation `json:"locations,omitempty"`
+	EventTicket         ApplePassFields `json:"eventTicket"`
+}
+
+// AppleBarcode — штрихкод пасса
+type AppleBarcode struct {
+	Format          string `json:"format"` // PKBarcodeFormatQR
+	Message         string `json:"message"`
+	MessageEncoding string `json:"messageEncoding"`
+	AltText         string `json:"altText,omitempty"`
+}
+
+// AppleLocation — место, рядом с которым пасс показывается на экране блокировки
+type AppleLocation struct {
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+	RelevantText string  `json:"relevantText,omitempty"`
+}
+
+// ApplePassFields — поля лицевой и оборотной сторон пасса
+type ApplePassFields struct {
+	HeaderFields    []AppleField `json:"headerFields,omitempty"`
+	PrimaryFields   []AppleField `json:"primaryFields,omitempty"`
+	SecondaryFields []AppleField `json:"secondaryFields,omitempty"`
+	AuxiliaryFields []AppleField `json:"auxiliaryFields,omitempty"`
+	BackFields      []AppleField `json:"backFields,omitempty"`
+}
+
+// AppleField — поле пасса
+type AppleField struct {
+	Key           string `json:"key"`
+	Label         string `json:"label,omitempty"`
+	Value         string `json:"value"`
+	DateStyle     string `json:"dateStyle,omitempty"`
+	TimeStyle     string `json:"timeStyle,omitempty"`
+	ChangeMessage string `json:"changeMessage,omitempty"`
+}
+
+// Package собирает подписанный .pkpass. Идентификаторы команды и типа
+// пассов, организация и адрес веб-сервиса берутся из настроек; files —
+// картинки пасса (icon.png обязательна).
+func (a *AppleSigner) Package(pass ApplePass, files map[string][]byte) ([]byte, error) {
+	if _, ok := files["icon.png"]; !ok {
+		return nil, fmt.Errorf("%w: icon.png is required", ErrInvalidConfig)
+	}
+	pass.FormatVersion = 1
+	pass.PassTypeIdentifier = a.config.PassTypeID
+	pass.TeamIdentifier = a.config.TeamID
+	pass.OrganizationName = a.config.OrganizationName
+	if a.config.WebServiceURL == "" {
+		pass.AuthenticationToken = ""
+	} else {
+		pass.WebServiceURL = a.config.WebServiceURL
+	}
+
+	passJSON, err := json.Marshal(pass)
+	if err != nil {
+		return nil, err
+	}
+	contents := map[string][]byte{"pass.json": passJSON}
+	for name, data := range files {
+		contents[name] = data
+	}
+
+	manifest := make(map[string]string, len(contents))
+	for name, data := range contents {
+		sum := sha1.Sum(data)
+		manifest[name] = hex.EncodeToString(sum[:])
+	}
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := a.sign(manifestJSON)
+	if err != nil {
+		return nil, err
+	}
+	contents["manifest.json"] = manifestJSON
+	contents["signature"] = signature
+
+	names := make([]string, 0, len(contents))
+	for name := range contents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := archive.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(contents[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// sign ставит отсоединённую подпись PKCS#7 над манифестом
+func (a *AppleSigner) sign(manifest []byte) ([]byte, error) {
+	signed, err := pkcs7.NewSignedData(manifest)
+	if err != nil {
+		return nil, err
+	}
+	signed.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+	if err := signed.AddSignerChain(a.cert, a.key, []*x509.Certificate{a.wwdr}, pkcs7.SignerInfoConfig{}); err != nil {
+		return nil, fmt.Errorf("failed to sign pass manifest: %w", err)
+	}
+	signed.Detach()
+	return signed.Finish()
+}
+
+// Push сообщает устройству с токеном pushToken, что пассы изменились.
+// Содержимое push пустое: устройство само запрашивает изменённые пассы.
+func (a *AppleSigner) Push(ctx context.Context, pushToken string) error {
+	url := strings.TrimRight(a.config.APNsURL, "/") + "/3/device/" + pushToken
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader("{}"))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("apns-topic", a.config.PassTypeID)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach APNs: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusGone:
+		return ErrPushTokenGone
+	case http.StatusBadRequest:
+		if strings.Contains(string(body), "BadDeviceToken") {
+			return ErrPushTokenGone
+		}
+	}
+	return httpError("APNs", resp, body)
+}
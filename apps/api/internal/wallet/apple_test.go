@@ -0,0 +1,201 @@
+package wallet
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	"go.mozilla.org/pkcs7"
+)
+
+// testCertificate выпускает сертификат template, подписанный parent и
+// parentKey; без parent сертификат самоподписанный
+func testCertificate(t *testing.T, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate() error = %v", err)
+	}
+	return cert, key
+}
+
+func certificatePEM(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+func ecKeyPEM(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error = %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func TestApplePackageSignsManifest(t *testing.T) {
+	now := time.Now()
+	wwdr, wwdrKey := testCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test WWDR"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}, nil, nil)
+	passCert, passKey := testCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Pass Type ID: pass.kg.skypark.test"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}, wwdr, wwdrKey)
+
+	signer, err := NewAppleSigner(AppleConfig{
+		PassTypeID:      "pass.kg.skypark.test",
+		TeamID:          "TEAM123456",
+		Certificate:     certificatePEM(passCert),
+		PrivateKey:      ecKeyPEM(t, passKey),
+		WWDRCertificate: certificatePEM(wwdr),
+	})
+	if err != nil {
+		t.Fatalf("NewAppleSigner() error = %v", err)
+	}
+
+	icon := []byte("\x89PNG icon")
+	pkpass, err := signer.Package(ApplePass{
+		SerialNumber: "TK-24-7F3K9Q",
+		Description:  "Билет Sky Park",
+		Barcodes:     []AppleBarcode{{Format: "PKBarcodeFormatQR", Message: "SP1.test", MessageEncoding: "iso-8859-1"}},
+	}, map[string][]byte{"icon.png": icon})
+	if err != nil {
+		t.Fatalf("Package() error = %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(pkpass), int64(len(pkpass)))
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+	entries := map[string][]byte{}
+	for _, file := range archive.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatalf("Open(%s) error = %v", file.Name, err)
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("ReadAll(%s) error = %v", file.Name, err)
+		}
+		entries[file.Name] = data
+	}
+	for _, name := range []string{"pass.json", "icon.png", "manifest.json", "signature"} {
+		if _, ok := entries[name]; !ok {
+			t.Fatalf("pkpass has no %s", name)
+		}
+	}
+
+	// В манифесте — SHA-1 каждого файла архива, кроме самого манифеста и подписи
+	var manifest map[string]string
+	if err := json.Unmarshal(entries["manifest.json"], &manifest); err != nil {
+		t.Fatalf("manifest.json is not JSON: %v", err)
+	}
+	if len(manifest) != len(entries)-2 {
+		t.Errorf("manifest lists %d files, want %d", len(manifest), len(entries)-2)
+	}
+	for name, data := range entries {
+		if name == "manifest.json" || name == "signature" {
+			continue
+		}
+		sum := sha1.Sum(data)
+		if got, want := manifest[name], hex.EncodeToString(sum[:]); got != want {
+			t.Errorf("manifest[%q] = %q, want %q", name, got, want)
+		}
+	}
+
+	var pass ApplePass
+	if err := json.Unmarshal(entries["pass.json"], &pass); err != nil {
+		t.Fatalf("pass.json is not JSON: %v", err)
+	}
+	if pass.PassTypeIdentifier != "pass.kg.skypark.test" || pass.TeamIdentifier != "TEAM123456" || pass.FormatVersion != 1 {
+		t.Errorf("pass.json identifiers = %q, %q, format %d", pass.PassTypeIdentifier, pass.TeamIdentifier, pass.FormatVersion)
+	}
+
+	// Подпись отсоединённая: манифест подставляется как подписанное содержимое
+	p7, err := pkcs7.Parse(entries["signature"])
+	if err != nil {
+		t.Fatalf("pkcs7.Parse() error = %v", err)
+	}
+	p7.Content = entries["manifest.json"]
+	roots := x509.NewCertPool()
+	roots.AddCert(wwdr)
+	if err := p7.VerifyWithChain(roots); err != nil {
+		t.Errorf("VerifyWithChain() error = %v", err)
+	}
+	if signer := p7.GetOnlySigner(); signer == nil || !signer.Equal(passCert) {
+		t.Errorf("signature is not made with the pass certificate")
+	}
+
+	p7.Content = append([]byte(nil), entries["manifest.json"]...)
+	p7.Content[len(p7.Content)-2] ^= 1
+	if err := p7.Verify(); err == nil {
+		t.Errorf("Verify() of a changed manifest succeeded")
+	}
+}
+
+func TestNewAppleSignerRejectsForeignCertificate(t *testing.T) {
+	now := time.Now()
+	ca := func(name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+		return testCertificate(t, &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: name},
+			NotBefore:             now.Add(-time.Hour),
+			NotAfter:              now.Add(time.Hour),
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign,
+		}, nil, nil)
+	}
+	wwdr, _ := ca("Test WWDR")
+	other, otherKey := ca("Other CA")
+	passCert, passKey := testCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Pass Type ID: pass.kg.skypark.test"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+	}, other, otherKey)
+
+	_, err := NewAppleSigner(AppleConfig{
+		PassTypeID:      "pass.kg.skypark.test",
+		TeamID:          "TEAM123456",
+		Certificate:     certificatePEM(passCert),
+		PrivateKey:      ecKeyPEM(t, passKey),
+		WWDRCertificate: certificatePEM(wwdr),
+	})
+	if err == nil {
+		t.Fatal("NewAppleSigner() accepted a pass certificate not issued by the WWDR certificate")
+	}
+}
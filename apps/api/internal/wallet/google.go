@@ -0,0 +1,303 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// DefaultGoogleAPIURL — адрес Google Wallet API
+	DefaultGoogleAPIURL = "https://walletobjects.googleapis.com/walletobjects/v1"
+	// DefaultGoogleTokenURL — адрес выдачи токенов OAuth сервисным аккаунтам
+	DefaultGoogleTokenURL = "https://oauth2.googleapis.com/token"
+	// googleSaveURL — ссылка «Добавить в Google Кошелёк», к ней дописывается JWT
+	googleSaveURL = "https://pay.google.com/gp/v/save/"
+	googleScope   = "https://www.googleapis.com/auth/wallet_object.issuer"
+)
+
+// GoogleConfig — настройки выпуска пассов Google Wallet
+type GoogleConfig struct {
+	IssuerID string
+	// ServiceAccountKey — JSON-ключ сервисного аккаунта Google Cloud
+	ServiceAccountKey []byte
+	// Origins — сайты, на которых показывается кнопка сохранения
+	Origins []string
+	// APIURL и TokenURL заменяют адреса Google, например локальными заглушками
+	APIURL   string
+	TokenURL string
+}
+
+// GoogleIssuer выпускает пассы Google Wallet от имени эмитента
+type GoogleIssuer struct {
+	config GoogleConfig
+	email  string
+	key    *rsa.PrivateKey
+	client *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	tokenExpiry time.Time
+}
+
+// NewGoogleIssuer разбирает ключ сервисного аккаунта и создаёт эмитента
+func NewGoogleIssuer(config GoogleConfig) (*GoogleIssuer, error) {
+	if config.IssuerID == "" {
+		return nil, fmt.Errorf("%w: issuer id is required", ErrInvalidConfig)
+	}
+	var account struct {
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(config.ServiceAccountKey, &account); err != nil {
+		return nil, fmt.Errorf("%w: service account key is not valid JSON: %v", ErrInvalidConfig, err)
+	}
+	if account.ClientEmail == "" {
+		return nil, fmt.Errorf("%w: service account key has no client_email", ErrInvalidConfig)
+	}
+	signer, err := parsePrivateKey([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+	key, ok := signer.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrInvalidPrivateKey
+	}
+	if config.APIURL == "" {
+		config.APIURL = DefaultGoogleAPIURL
+	}
+	if config.TokenURL == "" {
+		config.TokenURL = account.TokenURI
+	}
+	if config.TokenURL == "" {
+		config.TokenURL = DefaultGoogleTokenURL
+	}
+	return &GoogleIssuer{
+		config: config,
+		email:  account.ClientEmail,
+		key:    key,
+		client: &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+// ResourceID возвращает идентификатор класса или объекта эмитента. Google
+// допускает в суффиксе только буквы, цифры, '.', '_' и '-'.
+func (g *GoogleIssuer) ResourceID(suffix string) string {
+	return g.config.IssuerID + "." + suffix
+}
+
+// LocalizedString — строка с переводами
+type LocalizedString struct {
+	DefaultValue     TranslatedString   `json:"defaultValue"`
+	TranslatedValues []TranslatedString `json:"translatedValues,omitempty"`
+}
+
+// TranslatedString — строка на одном языке
+type TranslatedString struct {
+	Language string `json:"language"`
+	Value    string `json:"value"`
+}
+
+// EventTicketClass — класс пассов: общие для билетов парка сведения
+type EventTicketClass struct {
+	ID           string           `json:"id"`
+	IssuerName   string           `json:"issuerName"`
+	EventName    LocalizedString  `json:"eventName"`
+	ReviewStatus string           `json:"reviewStatus"`
+	Venue        *EventVenue      `json:"venue,omitempty"`
+	Logo         *Image           `json:"logo,omitempty"`
+	HexColor     string           `json:"hexBackgroundColor,omitempty"`
+	Locations    []LatLongPoint   `json:"locations,omitempty"`
+	Homepage     *Uri             `json:"homepageUri,omitempty"`
+	Message      *LocalizedString `json:"finePrint,omitempty"`
+}
+
+// EventVenue — место проведения
+type EventVenue struct {
+	Name    LocalizedString `json:"name"`
+	Address LocalizedString `json:"address"`
+}
+
+// Image — картинка по ссылке
+type Image struct {
+	SourceURI Uri `json:"sourceUri"`
+}
+
+// Uri — ссылка
+type Uri struct {
+	URI         string `json:"uri"`
+	Description string `json:"description,omitempty"`
+}
+
+// LatLongPoint — координаты
+type LatLongPoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// EventTicketObject — пасс одного билета
+type EventTicketObject struct {
+	ID                string        `json:"id"`
+	ClassID           string        `json:"classId"`
+	State             string        `json:"state"` // ACTIVE, COMPLETED, EXPIRED, INACTIVE
+	TicketHolderName  string        `json:"ticketHolderName,omitempty"`
+	TicketNumber      string        `json:"ticketNumber,omitempty"`
+	Barcode           *Barcode      `json:"barcode,omitempty"`
+	ValidTimeInterval *TimeInterval `json:"validTimeInterval,omitempty"`
+	TextModules       []TextModule  `json:"textModulesData,omitempty"`
+}
+
+// Barcode — штрихкод пасса
+type Barcode struct {
+	Type          string `json:"type"` // QR_CODE
+	Value         string `json:"value"`
+	AlternateText string `json:"alternateText,omitempty"`
+}
+
+// TimeInterval — срок действия пасса
+type TimeInterval struct {
+	Start DateTime `json:"start"`
+	End   DateTime `json:"end"`
+}
+
+// DateTime — момент времени в формате ISO 8601 со смещением
+type DateTime struct {
+	Date string `json:"date"`
+}
+
+// NewDateTime записывает момент времени для Google Wallet
+func NewDateTime(t time.Time) DateTime {
+	return DateTime{Date: t.Format(time.RFC3339)}
+}
+
+// TextModule — дополнительный текст на пассе
+type TextModule struct {
+	ID     string `json:"id"`
+	Header string `json:"header"`
+	Body   string `json:"body"`
+}
+
+// SaveURL возвращает ссылку «Добавить в Google Кошелёк» для объектов и их
+// классов. Google создаёт класс и объекты при сохранении, если их ещё нет.
+func (g *GoogleIssuer) SaveURL(classes []EventTicketClass, objects []EventTicketObject) (string, error) {
+	origins := g.config.Origins
+	if origins == nil {
+		origins = []string{}
+	}
+	claims := jwt.MapClaims{
+		"iss":     g.email,
+		"aud":     "google",
+		"typ":     "savetowallet",
+		"iat":     time.Now().Unix(),
+		"origins": origins,
+		"payload": map[string]interface{}{
+			"eventTicketClasses": classes,
+			"eventTicketObjects": objects,
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(g.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign Google Wallet pass: %w", err)
+	}
+	return googleSaveURL + token, nil
+}
+
+// UpdateObject заменяет сохранённый объект пасса, после чего Google
+// обновляет его на устройствах. Если объект ещё не сохранён, возвращает
+// ErrObjectNotFound.
+func (g *GoogleIssuer) UpdateObject(ctx context.Context, object EventTicketObject) error {
+	token, err := g.token(ctx)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	endpoint := strings.TrimRight(g.config.APIURL, "/") + "/eventTicketObject/" + url.PathEscape(object.ID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Google Wallet API: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return ErrObjectNotFound
+	}
+	return httpError("Google Wallet API", resp, respBody)
+}
+
+// token возвращает токен OAuth сервисного аккаунта, получая новый за минуту
+// до истечения прежнего
+func (g *GoogleIssuer) token(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.accessToken != "" && time.Now().Add(time.Minute).Before(g.tokenExpiry) {
+		return g.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   g.email,
+		"scope": googleScope,
+		"aud":   g.config.TokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(g.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign Google token request: %w", err)
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach Google OAuth: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK {
+		return "", httpError("Google OAuth", resp, body)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil || result.AccessToken == "" {
+		return "", fmt.Errorf("Google OAuth returned no access token")
+	}
+	g.accessToken = result.AccessToken
+	g.tokenExpiry = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	return g.accessToken, nil
+}
@@ -0,0 +1,84 @@
+package wallet
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testServiceAccount возвращает JSON-ключ сервисного аккаунта с новым ключом RSA
+func testServiceAccount(t *testing.T) ([]byte, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey() error = %v", err)
+	}
+	account, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "wallet@skypark-test.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    "http://127.0.0.1/token",
+	})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	return account, key
+}
+
+func TestGoogleSaveURLIsSignedByServiceAccount(t *testing.T) {
+	account, key := testServiceAccount(t)
+	issuer, err := NewGoogleIssuer(GoogleConfig{
+		IssuerID:          "3388000000012345678",
+		ServiceAccountKey: account,
+		Origins:           []string{"https://skypark.kg"},
+	})
+	if err != nil {
+		t.Fatalf("NewGoogleIssuer() error = %v", err)
+	}
+
+	objectID := issuer.ResourceID("TK-24-7F3K9Q")
+	link, err := issuer.SaveURL(
+		[]EventTicketClass{{ID: issuer.ResourceID("park")}},
+		[]EventTicketObject{{ID: objectID, ClassID: issuer.ResourceID("park"), State: "ACTIVE"}},
+	)
+	if err != nil {
+		t.Fatalf("SaveURL() error = %v", err)
+	}
+	if !strings.HasPrefix(link, googleSaveURL) {
+		t.Fatalf("SaveURL() = %q, want prefix %q", link, googleSaveURL)
+	}
+
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(strings.TrimPrefix(link, googleSaveURL), claims, func(token *jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	}, jwt.WithValidMethods([]string{"RS256"}), jwt.WithAudience("google"))
+	if err != nil || !token.Valid {
+		t.Fatalf("save link JWT does not verify: %v", err)
+	}
+	if claims["iss"] != "wallet@skypark-test.iam.gserviceaccount.com" || claims["typ"] != "savetowallet" {
+		t.Errorf("claims iss = %v, typ = %v", claims["iss"], claims["typ"])
+	}
+	payload, _ := json.Marshal(claims["payload"])
+	if !strings.Contains(string(payload), `"id":"`+objectID+`"`) {
+		t.Errorf("payload %s does not contain object %s", payload, objectID)
+	}
+
+	// Ключ другого аккаунта подпись не проходит
+	_, other := testServiceAccount(t)
+	_, err = jwt.Parse(strings.TrimPrefix(link, googleSaveURL), func(token *jwt.Token) (interface{}, error) {
+		return &other.PublicKey, nil
+	})
+	if err == nil {
+		t.Error("save link JWT verifies with another key")
+	}
+}
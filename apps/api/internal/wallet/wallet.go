@@ -0,0 +1,90 @@
+// Package wallet собирает пассы билетов для Apple Wallet и Google Wallet и
+// сообщает кошелькам об изменениях.
+//
+// Apple Wallet получает файл .pkpass: zip-архив с pass.json, картинками,
+// manifest.json (SHA-1 каждого файла) и signature — отсоединённой подписью
+// PKCS#7 манифеста сертификатом Pass Type ID вместе с промежуточным
+// сертификатом Apple WWDR. Об изменениях пасса устройству сообщает пустой
+// push через APNs, после чего оно само запрашивает новую версию у
+// веб-сервиса пассов.
+//
+// Google Wallet получает объект eventTicketObject: сохраняется он по ссылке
+// с JWT, подписанным ключом сервисного аккаунта (RS256), а обновляется
+// запросом к Google Wallet API с токеном OAuth того же аккаунта.
+//
+// Сертификаты и ключи передаются в PEM или JSON, а адреса APNs и Google API
+// настраиваются, поэтому подпись и обновления можно проверять без сети на
+// самоподписанных сертификатах и локальных заглушках.
+package wallet
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrInvalidCertificate = errors.New("wallet certificate is not a valid PEM certificate")
+	ErrInvalidPrivateKey  = errors.New("wallet private key is not a valid PEM RSA or ECDSA key")
+	ErrInvalidConfig      = errors.New("wallet configuration is incomplete")
+	// ErrPushTokenGone — устройство удалило пасс, регистрацию можно забыть
+	ErrPushTokenGone = errors.New("wallet push token is no longer valid")
+	// ErrObjectNotFound — пасс Google Wallet ещё не сохранён пользователем
+	ErrObjectNotFound = errors.New("wallet object not found")
+)
+
+// requestTimeout ограничивает запросы к APNs и Google API
+const requestTimeout = 15 * time.Second
+
+// parseCertificate разбирает сертификат X.509 в PEM
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, ErrInvalidCertificate
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCertificate, err)
+	}
+	return cert, nil
+}
+
+// parsePrivateKey разбирает закрытый ключ RSA или ECDSA в PEM (PKCS#1,
+// PKCS#8 или SEC 1)
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPrivateKey, err)
+	}
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return key, nil
+	case *ecdsa.PrivateKey:
+		return key, nil
+	}
+	return nil, ErrInvalidPrivateKey
+}
+
+// httpError описывает неуспешный ответ внешнего сервиса
+func httpError(service string, resp *http.Response, body []byte) error {
+	if len(body) > 512 {
+		body = body[:512]
+	}
+	return fmt.Errorf("%s responded %d: %s", service, resp.StatusCode, body)
+}
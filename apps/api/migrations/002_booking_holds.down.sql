@@ -0,0 +1,56 @@
+-- Remove capacity holds for bookings
+
+DROP FUNCTION IF EXISTS check_park_availability(UUID, DATE, INTEGER, VARCHAR);
+
+-- Restore the availability check that ignores holds and time slots
+CREATE OR REPLACE FUNCTION check_park_availability(
+    park_uuid UUID,
+    visit_date DATE,
+    guest_count INTEGER
+)
+RETURNS JSON AS $$
+DECLARE
+    result JSON;
+    park_capacity JSONB;
+    current_bookings INTEGER;
+    available_spots INTEGER;
+BEGIN
+    SELECT capacity INTO park_capacity
+    FROM parks
+    WHERE id = park_uuid AND status = 'active' AND deleted_at IS NULL;
+
+    IF park_capacity IS NULL THEN
+        RETURN '{"available": false, "reason": "Park not found or inactive"}'::JSON;
+    END IF;
+
+    SELECT COALESCE(SUM(total_guests), 0) INTO current_bookings
+    FROM bookings
+    WHERE
+        park_id = park_uuid
+        AND visit_date = check_park_availability.visit_date
+        AND status IN ('confirmed', 'checked_in')
+        AND deleted_at IS NULL;
+
+    available_spots := (park_capacity->>'total')::INTEGER - current_bookings;
+
+    SELECT row_to_json(availability) INTO result
+    FROM (
+        SELECT
+            (available_spots >= guest_count) as available,
+            available_spots,
+            current_bookings,
+            (park_capacity->>'total')::INTEGER as total_capacity,
+            CASE
+                WHEN available_spots >= guest_count THEN 'Available'
+                ELSE 'Not enough capacity'
+            END as reason
+    ) availability;
+
+    RETURN result;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS idx_bookings_hold_expires_at;
+DROP INDEX IF EXISTS idx_bookings_park_slot;
+
+ALTER TABLE bookings DROP COLUMN IF EXISTS hold_expires_at;
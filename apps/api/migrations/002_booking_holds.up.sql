@@ -0,0 +1,69 @@
+-- Capacity holds for bookings awaiting payment
+-- A pending_payment booking holds its guests' spots until hold_expires_at
+
+ALTER TABLE bookings ADD COLUMN hold_expires_at TIMESTAMP WITH TIME ZONE;
+
+-- Index for capacity checks per park/date/slot
+CREATE INDEX idx_bookings_park_slot ON bookings(park_id, visit_date, time_slot)
+    WHERE deleted_at IS NULL AND status IN ('pending_payment', 'confirmed', 'checked_in');
+
+-- Index for releasing expired holds
+CREATE INDEX idx_bookings_hold_expires_at ON bookings(hold_expires_at)
+    WHERE status = 'pending_payment' AND hold_expires_at IS NOT NULL;
+
+-- Availability check that takes active holds into account
+DROP FUNCTION IF EXISTS check_park_availability(UUID, DATE, INTEGER);
+
+CREATE OR REPLACE FUNCTION check_park_availability(
+    park_uuid UUID,
+    visit_date DATE,
+    guest_count INTEGER,
+    slot VARCHAR(5) DEFAULT NULL
+)
+RETURNS JSON AS $$
+DECLARE
+    result JSON;
+    park_capacity JSONB;
+    current_bookings INTEGER;
+    available_spots INTEGER;
+BEGIN
+    SELECT capacity INTO park_capacity
+    FROM parks
+    WHERE id = park_uuid AND status = 'active' AND deleted_at IS NULL;
+
+    IF park_capacity IS NULL THEN
+        RETURN '{"available": false, "reason": "Park not found or inactive"}'::JSON;
+    END IF;
+
+    SELECT COALESCE(SUM(total_guests), 0) INTO current_bookings
+    FROM bookings
+    WHERE
+        park_id = park_uuid
+        AND visit_date = check_park_availability.visit_date
+        AND (slot IS NULL OR time_slot = slot)
+        AND (
+            status IN ('confirmed', 'checked_in')
+            OR (status = 'pending_payment' AND hold_expires_at > CURRENT_TIMESTAMP)
+        )
+        AND deleted_at IS NULL;
+
+    available_spots := (park_capacity->>'total')::INTEGER - current_bookings;
+
+    SELECT row_to_json(availability) INTO result
+    FROM (
+        SELECT
+            (available_spots >= guest_count) as available,
+            available_spots,
+            current_bookings,
+            (park_capacity->>'total')::INTEGER as total_capacity,
+            CASE
+                WHEN available_spots >= guest_count THEN 'Available'
+                ELSE 'Not enough capacity'
+            END as reason
+    ) availability;
+
+    RETURN result;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON COLUMN bookings.hold_expires_at IS 'Until when a pending_payment booking holds its spots';
@@ -0,0 +1,3 @@
+-- Remove per-park booking settings
+
+ALTER TABLE parks DROP COLUMN IF EXISTS settings;
@@ -0,0 +1,5 @@
+-- Per-park booking settings (price modifiers for time slots, etc.)
+
+ALTER TABLE parks ADD COLUMN settings JSONB NOT NULL DEFAULT '{}';
+
+COMMENT ON COLUMN parks.settings IS 'Per-park booking rules such as time slot price modifiers';
@@ -0,0 +1,7 @@
+-- Remove human-readable booking and ticket numbers
+
+DROP INDEX IF EXISTS idx_tickets_ticket_number;
+DROP INDEX IF EXISTS idx_bookings_booking_number;
+
+ALTER TABLE tickets DROP COLUMN IF EXISTS ticket_number;
+ALTER TABLE bookings DROP COLUMN IF EXISTS booking_number;
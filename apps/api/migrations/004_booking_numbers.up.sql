@@ -0,0 +1,12 @@
+-- Human-readable booking and ticket numbers (e.g. SP-24-7F3K9Q)
+-- Numbers are allocated by the API; rows created before this migration
+-- are backfilled on startup, so the columns stay nullable
+
+ALTER TABLE bookings ADD COLUMN booking_number VARCHAR(16);
+ALTER TABLE tickets ADD COLUMN ticket_number VARCHAR(16);
+
+CREATE UNIQUE INDEX idx_bookings_booking_number ON bookings(booking_number);
+CREATE UNIQUE INDEX idx_tickets_ticket_number ON tickets(ticket_number);
+
+COMMENT ON COLUMN bookings.booking_number IS 'Short booking reference with a check symbol, read out over the phone';
+COMMENT ON COLUMN tickets.ticket_number IS 'Short ticket reference with a check symbol';
@@ -0,0 +1,7 @@
+-- Remove the booking sweeper journal
+
+DROP INDEX IF EXISTS idx_bookings_draft_updated_at;
+
+ALTER TABLE bookings ADD CONSTRAINT check_visit_date_future CHECK (visit_date >= CURRENT_DATE) NOT VALID;
+
+DROP TABLE IF EXISTS booking_sweeper_runs;
@@ -0,0 +1,27 @@
+-- Journal of the background booking sweeper
+-- Each run marks no-shows and expires stale drafts and unpaid bookings
+
+CREATE TABLE booking_sweeper_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    instance VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('completed', 'failed')),
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    no_shows INTEGER NOT NULL DEFAULT 0,
+    expired_drafts INTEGER NOT NULL DEFAULT 0,
+    expired_pending INTEGER NOT NULL DEFAULT 0,
+    error TEXT
+);
+
+CREATE INDEX idx_booking_sweeper_runs_started_at ON booking_sweeper_runs(started_at DESC);
+
+-- The API rejects past visit dates on creation. As a row check this also
+-- failed every later update of a booking whose date has passed, such as
+-- marking a no-show or expiring a stale booking.
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS check_visit_date_future;
+
+-- Index for finding stale drafts
+CREATE INDEX idx_bookings_draft_updated_at ON bookings(updated_at)
+    WHERE status = 'draft' AND deleted_at IS NULL;
+
+COMMENT ON TABLE booking_sweeper_runs IS 'Runs of the no-show and stale booking sweeper';
@@ -0,0 +1,3 @@
+-- Remove the waitlist for sold-out time slots
+
+DROP TABLE IF EXISTS waitlist_entries;
@@ -0,0 +1,38 @@
+-- Waitlist for sold-out time slots
+-- Offered entries hold their spots until offer_expires_at, like payment holds
+
+CREATE TABLE waitlist_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    park_id UUID NOT NULL REFERENCES parks(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'waiting'
+        CHECK (status IN ('waiting', 'offered', 'claimed', 'expired', 'cancelled')),
+    visit_date DATE NOT NULL,
+    time_slot VARCHAR(5) NOT NULL,
+    guest_count INTEGER NOT NULL CHECK (guest_count > 0),
+    guests JSONB NOT NULL DEFAULT '[]',
+    contact_info JSONB NOT NULL,
+    offered_at TIMESTAMP WITH TIME ZONE,
+    offer_expires_at TIMESTAMP WITH TIME ZONE,
+    -- Set in the same transaction that creates the booking
+    booking_id UUID REFERENCES bookings(id) ON DELETE SET NULL DEFERRABLE INITIALLY DEFERRED,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Queue order per slot
+CREATE INDEX idx_waitlist_entries_slot ON waitlist_entries(park_id, visit_date, time_slot, created_at)
+    WHERE status IN ('waiting', 'offered') AND deleted_at IS NULL;
+CREATE INDEX idx_waitlist_entries_user_id ON waitlist_entries(user_id);
+CREATE INDEX idx_waitlist_entries_offer_expires_at ON waitlist_entries(offer_expires_at)
+    WHERE status = 'offered';
+
+-- One active entry per user and slot
+CREATE UNIQUE INDEX idx_waitlist_entries_user_slot ON waitlist_entries(user_id, park_id, visit_date, time_slot)
+    WHERE status IN ('waiting', 'offered') AND deleted_at IS NULL;
+
+CREATE TRIGGER update_waitlist_entries_updated_at BEFORE UPDATE ON waitlist_entries
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE waitlist_entries IS 'Waitlist for sold-out park time slots';
@@ -0,0 +1,5 @@
+-- Remove group bookings
+-- PostgreSQL cannot drop enum values, so 'bank_transfer' stays in payment_method
+
+DROP INDEX IF EXISTS idx_bookings_group_approval;
+ALTER TABLE bookings DROP COLUMN IF EXISTS booking_type;
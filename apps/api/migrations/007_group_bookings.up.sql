@@ -0,0 +1,12 @@
+-- Group bookings for schools and kindergartens, paid by bank transfer invoice
+
+ALTER TYPE payment_method ADD VALUE IF NOT EXISTS 'bank_transfer';
+
+ALTER TABLE bookings ADD COLUMN booking_type VARCHAR(20) NOT NULL DEFAULT 'standard'
+    CHECK (booking_type IN ('standard', 'group'));
+
+-- Admin queue of group bookings awaiting approval
+CREATE INDEX idx_bookings_group_approval ON bookings(created_at)
+    WHERE booking_type = 'group' AND status = 'draft' AND metadata->>'approvalStatus' = 'pending';
+
+COMMENT ON COLUMN bookings.booking_type IS 'standard or group (organization pays by invoice)';
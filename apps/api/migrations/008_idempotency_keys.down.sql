@@ -0,0 +1,3 @@
+-- Remove idempotency keys
+
+DROP TABLE IF EXISTS idempotency_keys;
@@ -0,0 +1,10 @@
+-- Remove season passes
+-- PostgreSQL cannot drop enum values, so 'pass' stays in ticket_type
+
+DROP INDEX IF EXISTS idx_bookings_items;
+
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS bookings_booking_type_check;
+ALTER TABLE bookings ADD CONSTRAINT bookings_booking_type_check
+    CHECK (booking_type IN ('standard', 'group'));
+
+DROP TABLE IF EXISTS pass_products;
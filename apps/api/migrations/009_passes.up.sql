@@ -0,0 +1,37 @@
+-- Season passes and unlimited tickets
+-- A pass is bought as a booking of type 'pass' that holds no capacity; the
+-- issued ticket is activated on confirmation. Visits booked with a pass are
+-- priced at zero and count against the ticket's usage_count on check-in.
+
+ALTER TYPE ticket_type ADD VALUE IF NOT EXISTS 'pass';
+
+CREATE TABLE pass_products (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    park_id UUID NOT NULL REFERENCES parks(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    ticket_type ticket_type NOT NULL,
+    -- Number of visits for 'pass'; 'unlimited' allows one visit per day
+    visits INTEGER NOT NULL DEFAULT 0 CHECK (visits >= 0),
+    validity_days INTEGER NOT NULL CHECK (validity_days > 0),
+    price DECIMAL(10,2) NOT NULL CHECK (price >= 0),
+    currency VARCHAR(3) NOT NULL DEFAULT 'KGS',
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_pass_products_park_id ON pass_products(park_id) WHERE is_active AND deleted_at IS NULL;
+
+CREATE TRIGGER update_pass_products_updated_at BEFORE UPDATE ON pass_products
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS bookings_booking_type_check;
+ALTER TABLE bookings ADD CONSTRAINT bookings_booking_type_check
+    CHECK (booking_type IN ('standard', 'group', 'pass'));
+
+-- Finding visits booked with a given pass
+CREATE INDEX idx_bookings_items ON bookings USING GIN(items jsonb_path_ops);
+
+COMMENT ON TABLE pass_products IS 'Per-park catalog of multi-visit passes';
@@ -0,0 +1,12 @@
+-- Remove party packages
+
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS bookings_booking_type_check;
+ALTER TABLE bookings ADD CONSTRAINT bookings_booking_type_check
+    CHECK (booking_type IN ('standard', 'group', 'pass'));
+
+ALTER TABLE bookings DROP COLUMN IF EXISTS payment_schedule;
+ALTER TABLE bookings DROP COLUMN IF EXISTS party;
+
+DROP TABLE IF EXISTS party_room_reservations;
+DROP TABLE IF EXISTS party_packages;
+DROP TABLE IF EXISTS party_rooms;
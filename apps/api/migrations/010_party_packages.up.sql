@@ -0,0 +1,78 @@
+-- Birthday party and private-room packages
+-- A party is a booking of type 'party' that also occupies a party room for
+-- the package duration. It is paid by a deposit and a balance recorded by
+-- staff; the schedule is stored on the booking.
+
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+CREATE TABLE party_rooms (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    park_id UUID NOT NULL REFERENCES parks(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    max_guests INTEGER NOT NULL CHECK (max_guests > 0),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_party_rooms_park_id ON party_rooms(park_id) WHERE deleted_at IS NULL;
+
+CREATE TABLE party_packages (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    park_id UUID NOT NULL REFERENCES parks(id) ON DELETE CASCADE,
+    room_id UUID NOT NULL REFERENCES party_rooms(id),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    duration_minutes INTEGER NOT NULL CHECK (duration_minutes > 0),
+    price DECIMAL(10,2) NOT NULL CHECK (price >= 0),
+    included_guests INTEGER NOT NULL CHECK (included_guests > 0),
+    extra_guest_price DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (extra_guest_price >= 0),
+    add_ons JSONB NOT NULL DEFAULT '[]',
+    deposit_percent DECIMAL(5,2) NOT NULL DEFAULT 30 CHECK (deposit_percent BETWEEN 0 AND 100),
+    -- The balance is due this many hours before the party starts
+    balance_due_hours INTEGER NOT NULL DEFAULT 0 CHECK (balance_due_hours >= 0),
+    currency VARCHAR(3) NOT NULL DEFAULT 'KGS',
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_party_packages_park_id ON party_packages(park_id) WHERE is_active AND deleted_at IS NULL;
+
+CREATE TABLE party_room_reservations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    room_id UUID NOT NULL REFERENCES party_rooms(id),
+    booking_id UUID NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    released_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT check_party_reservation_range CHECK (ends_at > starts_at),
+    -- A room can never be double-booked, even if two requests race past the
+    -- application check
+    CONSTRAINT party_room_reservations_no_overlap EXCLUDE USING gist (
+        room_id WITH =,
+        tstzrange(starts_at, ends_at, '[)') WITH &&
+    ) WHERE (released_at IS NULL)
+);
+
+CREATE INDEX idx_party_room_reservations_booking_id ON party_room_reservations(booking_id);
+
+CREATE TRIGGER update_party_rooms_updated_at BEFORE UPDATE ON party_rooms
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_party_packages_updated_at BEFORE UPDATE ON party_packages
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE bookings ADD COLUMN party JSONB;
+ALTER TABLE bookings ADD COLUMN payment_schedule JSONB;
+
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS bookings_booking_type_check;
+ALTER TABLE bookings ADD CONSTRAINT bookings_booking_type_check
+    CHECK (booking_type IN ('standard', 'group', 'pass', 'party'));
+
+COMMENT ON TABLE party_packages IS 'Per-park birthday party and private-room packages';
+COMMENT ON TABLE party_room_reservations IS 'Party room occupancy; active reservations of a room never overlap';
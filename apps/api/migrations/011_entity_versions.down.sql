@@ -0,0 +1,14 @@
+-- Remove entity versions
+
+DROP TRIGGER IF EXISTS increment_payments_version ON payments;
+DROP TRIGGER IF EXISTS increment_tickets_version ON tickets;
+DROP TRIGGER IF EXISTS increment_bookings_version ON bookings;
+DROP TRIGGER IF EXISTS increment_parks_version ON parks;
+
+DROP FUNCTION IF EXISTS increment_park_version();
+DROP FUNCTION IF EXISTS increment_version();
+
+ALTER TABLE payments DROP COLUMN IF EXISTS version;
+ALTER TABLE tickets DROP COLUMN IF EXISTS version;
+ALTER TABLE bookings DROP COLUMN IF EXISTS version;
+ALTER TABLE parks DROP COLUMN IF EXISTS version;
@@ -0,0 +1,40 @@
+-- Optimistic concurrency control
+-- Parks, bookings, tickets and payments get a version that the database
+-- increments on every update, whichever code path issues it. The API exposes
+-- it as an ETag and rejects changes sent with a stale If-Match. Background
+-- refreshes of the park capacity snapshot do not count as changes.
+
+ALTER TABLE parks ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE bookings ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE tickets ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE payments ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+
+CREATE OR REPLACE FUNCTION increment_version()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.version = OLD.version + 1;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+-- The occupancy snapshot in parks.capacity (reserved, current, available,
+-- lastUpdated) is recalculated every minute and is not an edit of the park
+CREATE OR REPLACE FUNCTION increment_park_version()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF to_jsonb(NEW) - ARRAY['capacity', 'updated_at', 'version'] IS DISTINCT FROM to_jsonb(OLD) - ARRAY['capacity', 'updated_at', 'version']
+        OR NEW.capacity - ARRAY['reserved', 'current', 'available', 'lastUpdated'] IS DISTINCT FROM OLD.capacity - ARRAY['reserved', 'current', 'available', 'lastUpdated'] THEN
+        NEW.version = OLD.version + 1;
+    END IF;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER increment_parks_version BEFORE UPDATE ON parks
+    FOR EACH ROW EXECUTE FUNCTION increment_park_version();
+CREATE TRIGGER increment_bookings_version BEFORE UPDATE ON bookings
+    FOR EACH ROW EXECUTE FUNCTION increment_version();
+CREATE TRIGGER increment_tickets_version BEFORE UPDATE ON tickets
+    FOR EACH ROW EXECUTE FUNCTION increment_version();
+CREATE TRIGGER increment_payments_version BEFORE UPDATE ON payments
+    FOR EACH ROW EXECUTE FUNCTION increment_version();
@@ -0,0 +1,4 @@
+-- Remove gate scan log
+
+DROP INDEX IF EXISTS idx_tickets_park_updated_at;
+DROP TABLE IF EXISTS ticket_scans;
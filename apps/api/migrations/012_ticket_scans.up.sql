@@ -0,0 +1,46 @@
+-- Gate scans for offline scanner sync
+-- Every admitted entry, whether checked online or by a scanner without a
+-- connection, is logged here. Offline scans arrive late and out of order, so
+-- the outcome of all scans of a ticket is recomputed on upload: scans are
+-- ordered by (scanned_at, device_id, scan_id) and entries beyond the ticket's
+-- allowance are flagged as conflicts for staff to review.
+
+CREATE TABLE ticket_scans (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    -- NULL when the QR code could not be matched to a ticket
+    ticket_id UUID REFERENCES tickets(id) ON DELETE SET NULL,
+    park_id UUID NOT NULL REFERENCES parks(id) ON DELETE CASCADE,
+    device_id VARCHAR(100) NOT NULL,
+    -- Identifier assigned by the device; re-uploading a batch is a no-op
+    scan_id VARCHAR(100) NOT NULL,
+    source VARCHAR(10) NOT NULL CHECK (source IN ('online', 'offline')),
+    qr_data TEXT NOT NULL,
+    scanned_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    scanned_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    location VARCHAR(255),
+    -- What the device decided at the gate
+    device_admitted BOOLEAN NOT NULL,
+    device_reason VARCHAR(30),
+    outcome VARCHAR(20) NOT NULL CHECK (outcome IN ('accepted', 'conflict', 'refused')),
+    reason VARCHAR(30),
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    resolution_note TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX idx_ticket_scans_device_scan ON ticket_scans(device_id, scan_id);
+CREATE INDEX idx_ticket_scans_ticket_order ON ticket_scans(ticket_id, scanned_at, device_id, scan_id);
+-- Open conflicts per park for the staff review queue
+CREATE INDEX idx_ticket_scans_open_conflicts ON ticket_scans(park_id, scanned_at)
+    WHERE outcome = 'conflict' AND resolved_at IS NULL;
+
+CREATE TRIGGER update_ticket_scans_updated_at BEFORE UPDATE ON ticket_scans
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Delta manifests list the park's tickets changed since the scanner's cursor
+CREATE INDEX idx_tickets_park_updated_at ON tickets(park_id, updated_at);
+
+COMMENT ON TABLE ticket_scans IS 'Online and offline gate scans with conflict flags';
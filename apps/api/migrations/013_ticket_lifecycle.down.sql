@@ -0,0 +1,7 @@
+-- Remove ticket lifecycle sweep counters
+
+DROP INDEX IF EXISTS idx_tickets_status_valid_to;
+
+ALTER TABLE booking_sweeper_runs DROP COLUMN IF EXISTS voided_tickets;
+ALTER TABLE booking_sweeper_runs DROP COLUMN IF EXISTS expired_tickets;
+ALTER TABLE booking_sweeper_runs DROP COLUMN IF EXISTS activated_tickets;
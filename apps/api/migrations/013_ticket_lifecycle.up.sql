@@ -0,0 +1,10 @@
+-- Ticket lifecycle
+-- The booking sweeper also expires tickets past their validity and brings
+-- ticket statuses in line with their bookings; each run records how many
+-- tickets it changed.
+
+ALTER TABLE booking_sweeper_runs ADD COLUMN activated_tickets INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE booking_sweeper_runs ADD COLUMN expired_tickets INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE booking_sweeper_runs ADD COLUMN voided_tickets INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_tickets_status_valid_to ON tickets(status, valid_to) WHERE deleted_at IS NULL;
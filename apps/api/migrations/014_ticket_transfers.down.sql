@@ -0,0 +1,3 @@
+-- Remove ticket transfers
+
+DROP TABLE IF EXISTS ticket_transfers;
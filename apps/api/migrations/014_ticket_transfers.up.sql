@@ -0,0 +1,33 @@
+-- Ticket transfers
+-- A ticket owner can hand an unused ticket to another person. The recipient
+-- confirms with a code sent to their phone by SMS; only its hash is stored.
+-- On acceptance the ticket moves to the recipient and gets a new QR code.
+-- Parks can switch transfers off in parks.settings->'ticketTransfer'.
+
+CREATE TABLE ticket_transfers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    ticket_id UUID NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+    park_id UUID NOT NULL REFERENCES parks(id) ON DELETE CASCADE,
+    from_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    recipient_phone VARCHAR(20) NOT NULL,
+    recipient_name VARCHAR(255),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'cancelled')),
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    to_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    accepted_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- At most one transfer of a ticket awaits confirmation
+CREATE UNIQUE INDEX idx_ticket_transfers_pending_ticket ON ticket_transfers(ticket_id)
+    WHERE status = 'pending';
+CREATE INDEX idx_ticket_transfers_recipient ON ticket_transfers(recipient_phone, status);
+
+CREATE TRIGGER update_ticket_transfers_updated_at BEFORE UPDATE ON ticket_transfers
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE ticket_transfers IS 'Ticket hand-overs to another person confirmed by SMS code';
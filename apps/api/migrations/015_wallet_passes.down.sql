@@ -0,0 +1,3 @@
+-- Remove wallet pass registrations
+
+DROP TABLE IF EXISTS wallet_registrations;
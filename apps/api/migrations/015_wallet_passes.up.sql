@@ -0,0 +1,32 @@
+-- Wallet passes
+-- Tickets can be added to Apple Wallet and Google Wallet. A registration is
+-- kept for every pass on a device (Apple) or saved object (Google) so the
+-- pass can be updated when its ticket changes: once tickets.version moves
+-- past pushed_version, the wallet is notified and pushed_version catches up.
+
+CREATE TABLE wallet_registrations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    ticket_id UUID NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    platform VARCHAR(10) NOT NULL CHECK (platform IN ('apple', 'google')),
+    serial_number VARCHAR(255) NOT NULL,
+    device_library_id VARCHAR(255),
+    push_token VARCHAR(255),
+    pushed_version BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    CHECK (platform <> 'apple' OR (device_library_id IS NOT NULL AND push_token IS NOT NULL))
+);
+
+-- An Apple device registers a pass once; a Google object is saved once
+CREATE UNIQUE INDEX idx_wallet_registrations_apple_device ON wallet_registrations(device_library_id, serial_number)
+    WHERE platform = 'apple' AND deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_wallet_registrations_google_object ON wallet_registrations(serial_number)
+    WHERE platform = 'google' AND deleted_at IS NULL;
+CREATE INDEX idx_wallet_registrations_ticket ON wallet_registrations(ticket_id) WHERE deleted_at IS NULL;
+
+CREATE TRIGGER update_wallet_registrations_updated_at BEFORE UPDATE ON wallet_registrations
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE wallet_registrations IS 'Ticket passes in Apple Wallet and Google Wallet that receive updates';
@@ -7,6 +7,7 @@
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 CREATE EXTENSION IF NOT EXISTS "pg_trgm";
 CREATE EXTENSION IF NOT EXISTS "pgcrypto";
+CREATE EXTENSION IF NOT EXISTS "btree_gist"; -- исключение пересечений броней комнат для праздников
 
 -- ===============================================
 -- СОЗДАНИЕ ENUM ТИПОВ
@@ -34,7 +35,7 @@ CREATE TYPE amenity_type AS ENUM ('parking', 'wifi', 'restaurant', 'cafe', 'shop
 CREATE TYPE ticket_status AS ENUM ('pending', 'active', 'used', 'expired', 'cancelled', 'refunded');
 
 -- Типы билетов
-CREATE TYPE ticket_type AS ENUM ('single', 'group', 'family', 'vip', 'unlimited');
+CREATE TYPE ticket_type AS ENUM ('single', 'group', 'family', 'vip', 'unlimited', 'pass');
 
 -- Возрастные категории
 CREATE TYPE age_category AS ENUM ('baby', 'child', 'teen', 'adult', 'senior');
@@ -49,7 +50,7 @@ CREATE TYPE payment_status AS ENUM ('pending', 'processing', 'completed', 'faile
 CREATE TYPE booking_source AS ENUM ('web', 'mobile', 'admin', 'partner', 'walk_in');
 
 -- Методы платежей (для Кыргызстана)
-CREATE TYPE payment_method AS ENUM ('elqr', 'elcart', 'mbank', 'odengi', 'bank_card', 'cash', 'loyalty_points', 'wallet');
+CREATE TYPE payment_method AS ENUM ('elqr', 'elcart', 'mbank', 'odengi', 'bank_card', 'cash', 'loyalty_points', 'wallet', 'bank_transfer');
 
 -- Провайдеры платежей
 CREATE TYPE payment_provider AS ENUM ('elqr', 'elcart', 'mbank', 'odengi', 'visa', 'mastercard', 'internal');
@@ -193,7 +194,9 @@ CREATE TABLE parks (
     
     -- Системные поля
     is_featured BOOLEAN NOT NULL DEFAULT FALSE,
+    settings JSONB NOT NULL DEFAULT '{}', -- правила бронирования: наценки слотов, политика отмены, группы, передача билетов
     metadata JSONB DEFAULT '{}',
+    version BIGINT NOT NULL DEFAULT 1, -- увеличивается триггером при каждом изменении
     
     -- Временные метки
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
@@ -220,6 +223,8 @@ CREATE TABLE bookings (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     park_id UUID NOT NULL REFERENCES parks(id) ON DELETE CASCADE,
+    booking_number VARCHAR(16), -- короткий номер вида SP-24-7F3K9Q
+    booking_type VARCHAR(20) NOT NULL DEFAULT 'standard' CHECK (booking_type IN ('standard', 'group', 'pass', 'party')), -- group: школы и детские сады, оплата по счёту; pass: покупка абонемента; party: праздник в отдельной комнате
     
     -- Статусы
     status booking_status NOT NULL DEFAULT 'draft',
@@ -248,6 +253,10 @@ CREATE TABLE bookings (
     loyalty_points_earned INTEGER NOT NULL DEFAULT 0 CHECK (loyalty_points_earned >= 0),
     promo_code VARCHAR(50),
     
+    -- Праздник: пакет, комната и график оплаты (предоплата и остаток)
+    party JSONB,
+    payment_schedule JSONB,
+    
     -- Контактная информация (в формате JSONB)
     contact_info JSONB NOT NULL,
     
@@ -263,6 +272,7 @@ CREATE TABLE bookings (
     completed_at TIMESTAMP WITH TIME ZONE,
     cancelled_at TIMESTAMP WITH TIME ZONE,
     refunded_at TIMESTAMP WITH TIME ZONE,
+    hold_expires_at TIMESTAMP WITH TIME ZONE, -- до какого момента удерживаются места при оплате
     
     -- Информация об отмене
     cancellation_reason TEXT,
@@ -272,6 +282,7 @@ CREATE TABLE bookings (
     
     -- Системные поля
     metadata JSONB DEFAULT '{}',
+    version BIGINT NOT NULL DEFAULT 1, -- увеличивается триггером при каждом изменении
     
     -- Временные метки
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
@@ -292,11 +303,29 @@ CREATE INDEX idx_bookings_created_at ON bookings(created_at);
 CREATE INDEX idx_bookings_deleted_at ON bookings(deleted_at) WHERE deleted_at IS NOT NULL;
 CREATE INDEX idx_bookings_metadata ON bookings USING GIN(metadata);
 CREATE INDEX idx_bookings_contact_info ON bookings USING GIN(contact_info);
+CREATE INDEX idx_bookings_items ON bookings USING GIN(items jsonb_path_ops); -- визиты по абонементу
+
+-- Номер выдаёт API и повторяет вставку при совпадении, поэтому имя индекса важно
+CREATE UNIQUE INDEX idx_bookings_booking_number ON bookings(booking_number);
 
 -- Композитные индексы для улучшения производительности
 CREATE INDEX idx_bookings_user_status ON bookings(user_id, status);
 CREATE INDEX idx_bookings_park_visit_date ON bookings(park_id, visit_date);
 CREATE INDEX idx_bookings_park_status ON bookings(park_id, status);
+CREATE INDEX idx_bookings_park_slot ON bookings(park_id, visit_date, time_slot)
+    WHERE deleted_at IS NULL AND status IN ('pending_payment', 'confirmed', 'checked_in');
+
+-- Снятие просроченных удержаний мест
+CREATE INDEX idx_bookings_hold_expires_at ON bookings(hold_expires_at)
+    WHERE status = 'pending_payment' AND hold_expires_at IS NOT NULL;
+
+-- Уборка устаревших черновиков
+CREATE INDEX idx_bookings_draft_updated_at ON bookings(updated_at)
+    WHERE status = 'draft' AND deleted_at IS NULL;
+
+-- Очередь групповых бронирований на согласование
+CREATE INDEX idx_bookings_group_approval ON bookings(created_at)
+    WHERE booking_type = 'group' AND status = 'draft' AND metadata->>'approvalStatus' = 'pending';
 
 -- ===============================================
 -- ТАБЛИЦА БИЛЕТОВ
@@ -306,6 +335,7 @@ CREATE TABLE tickets (
     booking_id UUID NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
     park_id UUID NOT NULL REFERENCES parks(id) ON DELETE CASCADE,
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    ticket_number VARCHAR(16), -- короткий номер вида TK-24-7F3K9Q
     
     -- Типы и категории
     type ticket_type NOT NULL,
@@ -342,6 +372,7 @@ CREATE TABLE tickets (
     
     -- Системные поля
     metadata JSONB DEFAULT '{}',
+    version BIGINT NOT NULL DEFAULT 1, -- увеличивается триггером при каждом изменении
     
     -- Временные метки
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
@@ -363,6 +394,9 @@ CREATE INDEX idx_tickets_created_at ON tickets(created_at);
 CREATE INDEX idx_tickets_deleted_at ON tickets(deleted_at) WHERE deleted_at IS NOT NULL;
 CREATE INDEX idx_tickets_qr_code ON tickets USING GIN(qr_code);
 CREATE INDEX idx_tickets_metadata ON tickets USING GIN(metadata);
+CREATE INDEX idx_tickets_park_updated_at ON tickets(park_id, updated_at); -- изменения для офлайн-сканеров
+CREATE INDEX idx_tickets_status_valid_to ON tickets(status, valid_to) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_tickets_ticket_number ON tickets(ticket_number);
 
 -- Уникальный индекс для QR кода
 CREATE UNIQUE INDEX idx_tickets_qr_code_unique ON tickets((qr_code->>'code')) WHERE deleted_at IS NULL;
@@ -412,6 +446,7 @@ CREATE TABLE payments (
     
     -- Системные поля
     metadata JSONB DEFAULT '{}',
+    version BIGINT NOT NULL DEFAULT 1, -- увеличивается триггером при каждом изменении
     
     -- Временные метки
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
@@ -432,6 +467,251 @@ CREATE INDEX idx_payments_metadata ON payments USING GIN(metadata);
 -- Уникальный индекс для внешнего ID транзакции
 CREATE UNIQUE INDEX idx_payments_provider_transaction_id ON payments((details->>'providerTransactionId')) WHERE details->>'providerTransactionId' IS NOT NULL;
 
+-- ===============================================
+-- ЛИСТ ОЖИДАНИЯ
+-- ===============================================
+-- Предложенная запись держит места до offer_expires_at, как удержание при оплате
+CREATE TABLE waitlist_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    park_id UUID NOT NULL REFERENCES parks(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'waiting'
+        CHECK (status IN ('waiting', 'offered', 'claimed', 'expired', 'cancelled')),
+    visit_date DATE NOT NULL,
+    time_slot VARCHAR(5) NOT NULL,
+    guest_count INTEGER NOT NULL CHECK (guest_count > 0),
+    guests JSONB NOT NULL DEFAULT '[]',
+    contact_info JSONB NOT NULL,
+    offered_at TIMESTAMP WITH TIME ZONE,
+    offer_expires_at TIMESTAMP WITH TIME ZONE,
+    -- Заполняется в той же транзакции, что создаёт бронирование
+    booking_id UUID REFERENCES bookings(id) ON DELETE SET NULL DEFERRABLE INITIALLY DEFERRED,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Очередь по слоту
+CREATE INDEX idx_waitlist_entries_slot ON waitlist_entries(park_id, visit_date, time_slot, created_at)
+    WHERE status IN ('waiting', 'offered') AND deleted_at IS NULL;
+CREATE INDEX idx_waitlist_entries_user_id ON waitlist_entries(user_id);
+CREATE INDEX idx_waitlist_entries_offer_expires_at ON waitlist_entries(offer_expires_at)
+    WHERE status = 'offered';
+
+-- Одна активная запись на пользователя и слот
+CREATE UNIQUE INDEX idx_waitlist_entries_user_slot ON waitlist_entries(user_id, park_id, visit_date, time_slot)
+    WHERE status IN ('waiting', 'offered') AND deleted_at IS NULL;
+
+-- ===============================================
+-- АБОНЕМЕНТЫ
+-- ===============================================
+-- Абонемент покупается как бронирование типа 'pass', визиты по нему бесплатны
+CREATE TABLE pass_products (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    park_id UUID NOT NULL REFERENCES parks(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    ticket_type ticket_type NOT NULL,
+    -- Число визитов для 'pass'; 'unlimited' — один визит в день
+    visits INTEGER NOT NULL DEFAULT 0 CHECK (visits >= 0),
+    validity_days INTEGER NOT NULL CHECK (validity_days > 0),
+    price DECIMAL(10,2) NOT NULL CHECK (price >= 0),
+    currency VARCHAR(3) NOT NULL DEFAULT 'KGS',
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_pass_products_park_id ON pass_products(park_id) WHERE is_active AND deleted_at IS NULL;
+
+-- ===============================================
+-- ПРАЗДНИКИ И КОМНАТЫ
+-- ===============================================
+CREATE TABLE party_rooms (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    park_id UUID NOT NULL REFERENCES parks(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    max_guests INTEGER NOT NULL CHECK (max_guests > 0),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_party_rooms_park_id ON party_rooms(park_id) WHERE deleted_at IS NULL;
+
+CREATE TABLE party_packages (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    park_id UUID NOT NULL REFERENCES parks(id) ON DELETE CASCADE,
+    room_id UUID NOT NULL REFERENCES party_rooms(id),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    duration_minutes INTEGER NOT NULL CHECK (duration_minutes > 0),
+    price DECIMAL(10,2) NOT NULL CHECK (price >= 0),
+    included_guests INTEGER NOT NULL CHECK (included_guests > 0),
+    extra_guest_price DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (extra_guest_price >= 0),
+    add_ons JSONB NOT NULL DEFAULT '[]',
+    deposit_percent DECIMAL(5,2) NOT NULL DEFAULT 30 CHECK (deposit_percent BETWEEN 0 AND 100),
+    -- Остаток оплачивается за столько часов до начала праздника
+    balance_due_hours INTEGER NOT NULL DEFAULT 0 CHECK (balance_due_hours >= 0),
+    currency VARCHAR(3) NOT NULL DEFAULT 'KGS',
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_party_packages_park_id ON party_packages(park_id) WHERE is_active AND deleted_at IS NULL;
+
+CREATE TABLE party_room_reservations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    room_id UUID NOT NULL REFERENCES party_rooms(id),
+    booking_id UUID NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    released_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT check_party_reservation_range CHECK (ends_at > starts_at),
+    -- Комната не бронируется дважды, даже если два запроса прошли проверку API одновременно
+    CONSTRAINT party_room_reservations_no_overlap EXCLUDE USING gist (
+        room_id WITH =,
+        tstzrange(starts_at, ends_at, '[)') WITH &&
+    ) WHERE (released_at IS NULL)
+);
+
+CREATE INDEX idx_party_room_reservations_booking_id ON party_room_reservations(booking_id);
+
+-- ===============================================
+-- ПРОХОДЫ ЧЕРЕЗ ТУРНИКЕТ
+-- ===============================================
+-- Проходы, в том числе с офлайн-сканеров; проходы сверх допустимого числа
+-- помечаются как конфликты для персонала
+CREATE TABLE ticket_scans (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    -- NULL, если QR-код не удалось сопоставить с билетом
+    ticket_id UUID REFERENCES tickets(id) ON DELETE SET NULL,
+    park_id UUID NOT NULL REFERENCES parks(id) ON DELETE CASCADE,
+    device_id VARCHAR(100) NOT NULL,
+    -- Идентификатор от устройства; повторная выгрузка пакета ничего не меняет
+    scan_id VARCHAR(100) NOT NULL,
+    source VARCHAR(10) NOT NULL CHECK (source IN ('online', 'offline')),
+    qr_data TEXT NOT NULL,
+    scanned_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    scanned_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    location VARCHAR(255),
+    -- Решение устройства у турникета
+    device_admitted BOOLEAN NOT NULL,
+    device_reason VARCHAR(30),
+    outcome VARCHAR(20) NOT NULL CHECK (outcome IN ('accepted', 'conflict', 'refused')),
+    reason VARCHAR(30),
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    resolution_note TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX idx_ticket_scans_device_scan ON ticket_scans(device_id, scan_id);
+CREATE INDEX idx_ticket_scans_ticket_order ON ticket_scans(ticket_id, scanned_at, device_id, scan_id);
+-- Открытые конфликты парка для разбора персоналом
+CREATE INDEX idx_ticket_scans_open_conflicts ON ticket_scans(park_id, scanned_at)
+    WHERE outcome = 'conflict' AND resolved_at IS NULL;
+
+-- ===============================================
+-- ПЕРЕДАЧА БИЛЕТОВ
+-- ===============================================
+-- Передача билета другому человеку, подтверждаемая кодом из SMS (хранится только хеш)
+CREATE TABLE ticket_transfers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    ticket_id UUID NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+    park_id UUID NOT NULL REFERENCES parks(id) ON DELETE CASCADE,
+    from_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    recipient_phone VARCHAR(20) NOT NULL,
+    recipient_name VARCHAR(255),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'cancelled')),
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    to_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    accepted_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Не больше одной ожидающей передачи на билет
+CREATE UNIQUE INDEX idx_ticket_transfers_pending_ticket ON ticket_transfers(ticket_id)
+    WHERE status = 'pending';
+CREATE INDEX idx_ticket_transfers_recipient ON ticket_transfers(recipient_phone, status);
+
+-- ===============================================
+-- КОШЕЛЬКИ
+-- ===============================================
+-- Билеты в Apple Wallet и Google Wallet, которым отправляются обновления
+CREATE TABLE wallet_registrations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    ticket_id UUID NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    platform VARCHAR(10) NOT NULL CHECK (platform IN ('apple', 'google')),
+    serial_number VARCHAR(255) NOT NULL,
+    device_library_id VARCHAR(255),
+    push_token VARCHAR(255),
+    pushed_version BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    CHECK (platform <> 'apple' OR (device_library_id IS NOT NULL AND push_token IS NOT NULL))
+);
+
+CREATE UNIQUE INDEX idx_wallet_registrations_apple_device ON wallet_registrations(device_library_id, serial_number)
+    WHERE platform = 'apple' AND deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_wallet_registrations_google_object ON wallet_registrations(serial_number)
+    WHERE platform = 'google' AND deleted_at IS NULL;
+CREATE INDEX idx_wallet_registrations_ticket ON wallet_registrations(ticket_id) WHERE deleted_at IS NULL;
+
+-- ===============================================
+-- СЛУЖЕБНЫЕ ТАБЛИЦЫ API
+-- ===============================================
+-- Журнал фоновой уборки бронирований и билетов
+CREATE TABLE booking_sweeper_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    instance VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('completed', 'failed')),
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    no_shows INTEGER NOT NULL DEFAULT 0,
+    expired_drafts INTEGER NOT NULL DEFAULT 0,
+    expired_pending INTEGER NOT NULL DEFAULT 0,
+    activated_tickets INTEGER NOT NULL DEFAULT 0,
+    expired_tickets INTEGER NOT NULL DEFAULT 0,
+    voided_tickets INTEGER NOT NULL DEFAULT 0,
+    error TEXT
+);
+
+CREATE INDEX idx_booking_sweeper_runs_started_at ON booking_sweeper_runs(started_at DESC);
+
+-- Сохранённые ответы для повторов запросов с Idempotency-Key
+CREATE TABLE idempotency_keys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    scope VARCHAR(64) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    fingerprint VARCHAR(64) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'processing'
+        CHECK (status IN ('processing', 'completed')),
+    response_status INTEGER,
+    response_content_type VARCHAR(255),
+    response_body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_idempotency_keys_scope_key ON idempotency_keys(scope, key);
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
 -- ===============================================
 -- ФУНКЦИИ И ТРИГГЕРЫ
 -- ===============================================
@@ -445,12 +725,47 @@ BEGIN
 END;
 $$ language 'plpgsql';
 
+-- Функция увеличения версии строки для оптимистичных блокировок
+CREATE OR REPLACE FUNCTION increment_version()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.version = OLD.version + 1;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+-- Снимок занятости (reserved, current, available, lastUpdated) пересчитывается
+-- фоном каждую минуту и не является правкой парка, поэтому версию не меняет
+CREATE OR REPLACE FUNCTION increment_park_version()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF to_jsonb(NEW) - ARRAY['capacity', 'updated_at', 'version'] IS DISTINCT FROM to_jsonb(OLD) - ARRAY['capacity', 'updated_at', 'version']
+        OR NEW.capacity - ARRAY['reserved', 'current', 'available', 'lastUpdated'] IS DISTINCT FROM OLD.capacity - ARRAY['reserved', 'current', 'available', 'lastUpdated'] THEN
+        NEW.version = OLD.version + 1;
+    END IF;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
 -- Триггеры для обновления updated_at
 CREATE TRIGGER update_users_updated_at BEFORE UPDATE ON users FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_parks_updated_at BEFORE UPDATE ON parks FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_bookings_updated_at BEFORE UPDATE ON bookings FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_tickets_updated_at BEFORE UPDATE ON tickets FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_payments_updated_at BEFORE UPDATE ON payments FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_waitlist_entries_updated_at BEFORE UPDATE ON waitlist_entries FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_pass_products_updated_at BEFORE UPDATE ON pass_products FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_party_rooms_updated_at BEFORE UPDATE ON party_rooms FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_party_packages_updated_at BEFORE UPDATE ON party_packages FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_ticket_scans_updated_at BEFORE UPDATE ON ticket_scans FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_ticket_transfers_updated_at BEFORE UPDATE ON ticket_transfers FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_wallet_registrations_updated_at BEFORE UPDATE ON wallet_registrations FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Триггеры версий
+CREATE TRIGGER increment_parks_version BEFORE UPDATE ON parks FOR EACH ROW EXECUTE FUNCTION increment_park_version();
+CREATE TRIGGER increment_bookings_version BEFORE UPDATE ON bookings FOR EACH ROW EXECUTE FUNCTION increment_version();
+CREATE TRIGGER increment_tickets_version BEFORE UPDATE ON tickets FOR EACH ROW EXECUTE FUNCTION increment_version();
+CREATE TRIGGER increment_payments_version BEFORE UPDATE ON payments FOR EACH ROW EXECUTE FUNCTION increment_version();
 
 -- Функция для вычисления уровня лояльности
 CREATE OR REPLACE FUNCTION calculate_loyalty_tier(total_spent_amount DECIMAL, total_visits_count INTEGER)
@@ -574,6 +889,19 @@ ALTER TABLE bookings ENABLE ROW LEVEL SECURITY;
 ALTER TABLE tickets ENABLE ROW LEVEL SECURITY;
 ALTER TABLE payments ENABLE ROW LEVEL SECURITY;
 
+-- Остальные таблицы читает и пишет только API; без политик они закрыты
+-- для клиентских ролей Supabase
+ALTER TABLE waitlist_entries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE pass_products ENABLE ROW LEVEL SECURITY;
+ALTER TABLE party_rooms ENABLE ROW LEVEL SECURITY;
+ALTER TABLE party_packages ENABLE ROW LEVEL SECURITY;
+ALTER TABLE party_room_reservations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE ticket_scans ENABLE ROW LEVEL SECURITY;
+ALTER TABLE ticket_transfers ENABLE ROW LEVEL SECURITY;
+ALTER TABLE wallet_registrations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE booking_sweeper_runs ENABLE ROW LEVEL SECURITY;
+ALTER TABLE idempotency_keys ENABLE ROW LEVEL SECURITY;
+
 -- Политики для таблицы users
 CREATE POLICY "Пользователи могут видеть свои данные" ON users
     FOR SELECT USING (auth.uid() = id);
@@ -700,7 +1028,8 @@ $$ LANGUAGE plpgsql;
 CREATE OR REPLACE FUNCTION check_park_availability(
     park_uuid UUID,
     visit_date DATE,
-    guest_count INTEGER
+    guest_count INTEGER,
+    slot VARCHAR(5) DEFAULT NULL
 )
 RETURNS JSON AS $$
 DECLARE
@@ -718,13 +1047,17 @@ BEGIN
         RETURN '{"available": false, "reason": "Park not found or inactive"}'::JSON;
     END IF;
     
-    -- Считаем текущие бронирования на эту дату
+    -- Считаем текущие бронирования и активные удержания мест на эту дату
     SELECT COALESCE(SUM(total_guests), 0) INTO current_bookings
     FROM bookings 
     WHERE 
         park_id = park_uuid 
         AND visit_date = check_park_availability.visit_date
-        AND status IN ('confirmed', 'checked_in')
+        AND (slot IS NULL OR time_slot = slot)
+        AND (
+            status IN ('confirmed', 'checked_in')
+            OR (status = 'pending_payment' AND hold_expires_at > CURRENT_TIMESTAMP)
+        )
         AND deleted_at IS NULL;
     
     -- Вычисляем доступные места